@@ -0,0 +1,67 @@
+//go:build cloudsql
+
+// The Cloud SQL connector pulls in the Google Cloud client libraries, gRPC
+// and OpenTelemetry, so it is only compiled into binaries built with
+// -tags cloudsql.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/cloudsqlconn"
+	"cloud.google.com/go/cloudsqlconn/postgres/pgxv4"
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/store"
+)
+
+const cloudSQLDriver = "cloudsql-postgres"
+
+func init() {
+	connProviders["cloudsql"] = cloudSQLProvider
+}
+
+var (
+	cloudSQLOnce sync.Once
+	cloudSQLErr  error
+)
+
+// registerCloudSQL registers the connector's database/sql driver. A driver
+// name can only be registered once per process, so the dialer options of the
+// first call apply to every connection, and only that call gets the cleanup
+// that closes the dialer.
+func registerCloudSQL(dbCfg config.Database) (func() error, error) {
+	var cleanup func() error
+	cloudSQLOnce.Do(func() {
+		var opts []cloudsqlconn.Option
+		if dbCfg.IAMAuth {
+			opts = append(opts, cloudsqlconn.WithIAMAuthN())
+		}
+		if dbCfg.PrivateIP {
+			opts = append(opts, cloudsqlconn.WithDefaultDialOptions(cloudsqlconn.WithPrivateIP()))
+		}
+		cleanup, cloudSQLErr = pgxv4.RegisterDriver(cloudSQLDriver, opts...)
+	})
+	return cleanup, cloudSQLErr
+}
+
+// cloudSQLProvider dials DB_INSTANCE through the Cloud SQL connector, which
+// handles TLS and, with DB_IAM_AUTH, logs in with the service account's
+// credentials instead of a password.
+func cloudSQLProvider(dbCfg config.Database) (store.Target, func() error, error) {
+	cleanup, err := registerCloudSQL(dbCfg)
+	if err != nil {
+		return store.Target{}, nil, fmt.Errorf("failed to set up the Cloud SQL connector: %w", err)
+	}
+
+	dsn := pqKeywordDSN(map[string]string{
+		"host":     dbCfg.Instance,
+		"user":     dbCfg.User,
+		"password": dbCfg.Password,
+		"dbname":   dbCfg.Name,
+		// The connector encrypts the connection itself.
+		"sslmode": "disable",
+	})
+	return store.Target{Dialect: store.Postgres, Driver: cloudSQLDriver, DSN: dsn}, cleanup, nil
+}
@@ -1,45 +1,195 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"strings"
 	"time"
 
-	"github.com/artyultra/tanglr/internal/database"
-	_ "github.com/lib/pq"
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/metrics"
+	"github.com/artyultra/tanglr/internal/store"
 )
 
-func setupDBConn() (*database.Queries, *sql.DB, func(), error) {
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		return nil, nil, nil, fmt.Errorf("missing DATABASE_URL environment variable")
+// maxConnectBackoff caps the wait between connection attempts at startup.
+const maxConnectBackoff = 30 * time.Second
+
+// connProvider turns the database config into the driver and DSN to open. The
+// returned cleanup, when not nil, runs after the pool is closed.
+type connProvider func(dbCfg config.Database) (store.Target, func() error, error)
+
+// connProviders is keyed by DB_PROVIDER. The Cloud SQL connector registers
+// itself from db_cloudsql.go when built with -tags cloudsql.
+var connProviders = map[string]connProvider{
+	"dsn":  dsnProvider,
+	"unix": unixSocketProvider,
+}
+
+// dsnProvider opens DATABASE_URL with the driver its scheme selects.
+func dsnProvider(dbCfg config.Database) (store.Target, func() error, error) {
+	target, err := store.ParseURL(dbCfg.URL)
+	return target, nil, err
+}
+
+// unixSocketProvider connects to Postgres through the socket in
+// DB_SOCKET_DIR, such as /var/run/postgresql or the /cloudsql/<instance>
+// directory the Cloud SQL Auth Proxy and Cloud Run mount.
+func unixSocketProvider(dbCfg config.Database) (store.Target, func() error, error) {
+	dsn := pqKeywordDSN(map[string]string{
+		"host":     dbCfg.SocketDir,
+		"user":     dbCfg.User,
+		"password": dbCfg.Password,
+		"dbname":   dbCfg.Name,
+		"sslmode":  "disable",
+	})
+	return store.Target{Dialect: store.Postgres, Driver: "postgres", DSN: dsn}, nil, nil
+}
+
+// pqKeywordDSN renders a key=value connection string, quoting every value
+// so spaces and quotes in passwords survive.
+func pqKeywordDSN(params map[string]string) string {
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	var parts []string
+	for _, key := range []string{"host", "user", "password", "dbname", "sslmode"} {
+		if value := params[key]; value != "" {
+			parts = append(parts, fmt.Sprintf("%s='%s'", key, quote.Replace(value)))
+		}
 	}
+	return strings.Join(parts, " ")
+}
 
-	db, err := sql.Open("postgres", databaseURL)
+func setupDBConn(ctx context.Context, dbCfg config.Database) (store.Repository, *sql.DB, store.Target, func(), error) {
+	provider, ok := connProviders[dbCfg.Provider]
+	if !ok {
+		return nil, nil, store.Target{}, nil, fmt.Errorf("database provider %q is not available in this build; the Cloud SQL connector needs -tags cloudsql", dbCfg.Provider)
+	}
+	target, closeProvider, err := provider(dbCfg)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to open database connection: %v", err)
+		return nil, nil, target, nil, err
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
-	err = db.Ping()
+	db, err := sql.Open(target.Driver, target.DSN)
 	if err != nil {
-		db.Close()
-		return nil, nil, nil, fmt.Errorf("failed to ping database: %v", err)
+		return nil, nil, target, nil, fmt.Errorf("failed to open database connection: %v", err)
 	}
 
-	dbQueries := database.New(db)
-	log.Println("Connected to PostgreSQL database")
+	if target.Driver == "sqlite3" {
+		// SQLite allows one writer at a time, and an in-memory database
+		// exists only as long as its connection, so keep exactly one open.
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxLifetime(0)
+	} else {
+		db.SetMaxOpenConns(dbCfg.MaxOpenConns)
+		db.SetMaxIdleConns(dbCfg.MaxIdleConns)
+		db.SetConnMaxLifetime(dbCfg.ConnMaxLifetime)
+		db.SetConnMaxIdleTime(dbCfg.ConnMaxIdleTime)
+	}
 
 	cleanup := func() {
 		if err := db.Close(); err != nil {
-			log.Printf("failed to close database connection: %v", err)
+			slog.Error("failed to close database connection", "error", err)
+		}
+		if closeProvider != nil {
+			if err := closeProvider(); err != nil {
+				slog.Error("failed to close database provider", "error", err)
+			}
 		}
 	}
 
-	return dbQueries, db, cleanup, nil
+	err = pingWithRetry(ctx, db, dbCfg.ConnectAttempts, dbCfg.ConnectBackoff)
+	if err != nil {
+		cleanup()
+		return nil, nil, target, nil, fmt.Errorf("failed to ping database: %v", err)
+	}
+
+	if err := metrics.RegisterDB(db, "primary"); err != nil {
+		slog.Warn("failed to register database metrics", "error", err)
+	}
+
+	repo := store.New(target.Dialect, db)
+	slog.Info("connected to database", "provider", dbCfg.Provider, "dialect", target.Dialect, "driver", target.Driver)
+
+	return repo, db, target, cleanup, nil
+}
+
+// setupReplicas opens the read replicas in DB_REPLICA_URLS. A replica that is
+// down at startup doesn't stop the server; it starts out of rotation and the
+// health check brings it back once it answers.
+func setupReplicas(ctx context.Context, dbCfg config.Database, primary store.Target) (*store.ReplicaSet, func(), error) {
+	if len(dbCfg.ReplicaURLs) == 0 {
+		return nil, func() {}, nil
+	}
+
+	var dbs []*sql.DB
+	closeAll := func() {
+		for _, db := range dbs {
+			if err := db.Close(); err != nil {
+				slog.Error("failed to close replica connection", "error", err)
+			}
+		}
+	}
+
+	for i, rawURL := range dbCfg.ReplicaURLs {
+		target, err := store.ParseURL(rawURL)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("replica %d: %v", i+1, err)
+		}
+		if target.Dialect != primary.Dialect {
+			closeAll()
+			return nil, nil, fmt.Errorf("replica %d is %s but the primary is %s", i+1, target.Dialect, primary.Dialect)
+		}
+		if target.InMemory {
+			closeAll()
+			return nil, nil, fmt.Errorf("replica %d: an in-memory database can't replicate the primary", i+1)
+		}
+
+		db, err := sql.Open(target.Driver, target.DSN)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to open replica %d: %v", i+1, err)
+		}
+		db.SetMaxOpenConns(dbCfg.MaxOpenConns)
+		db.SetMaxIdleConns(dbCfg.MaxIdleConns)
+		db.SetConnMaxLifetime(dbCfg.ConnMaxLifetime)
+		db.SetConnMaxIdleTime(dbCfg.ConnMaxIdleTime)
+		dbs = append(dbs, db)
+
+		if err := metrics.RegisterDB(db, fmt.Sprintf("replica-%d", i+1)); err != nil {
+			slog.Warn("failed to register replica metrics", "error", err)
+		}
+	}
+
+	replicas := store.NewReplicaSet(primary.Dialect, dbs)
+	replicas.CheckHealth(ctx)
+	healthy, total := replicas.Healthy()
+	slog.Info("connected to read replicas", "healthy", healthy, "total", total)
+
+	return replicas, closeAll, nil
+}
+
+// pingWithRetry pings db up to attempts times, doubling the wait between
+// tries from backoff up to maxConnectBackoff, so the server survives a
+// database that starts after it.
+func pingWithRetry(ctx context.Context, db *sql.DB, attempts int, backoff time.Duration) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err = db.PingContext(pingCtx)
+		cancel()
+		if err == nil || attempt >= attempts {
+			return err
+		}
+
+		slog.Warn("database not reachable, retrying", "attempt", attempt, "of", attempts, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
 }
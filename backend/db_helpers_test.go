@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/store"
+)
+
+// flakyDriver refuses the first N connections to each DSN, where the DSN is
+// "N/name", standing in for a database that is still starting up.
+type flakyDriver struct {
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+var flaky = &flakyDriver{attempts: map[string]int{}}
+
+func init() {
+	sql.Register("flaky", flaky)
+}
+
+func (d *flakyDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.attempts[dsn]++
+
+	var failures int
+	fmt.Sscanf(dsn, "%d/", &failures)
+	if d.attempts[dsn] <= failures {
+		return nil, errors.New("connection refused")
+	}
+	return flakyConn{}, nil
+}
+
+func (d *flakyDriver) attemptsFor(dsn string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.attempts[dsn]
+}
+
+type flakyConn struct{}
+
+func (flakyConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (flakyConn) Close() error                        { return nil }
+func (flakyConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func openFlaky(t *testing.T, failures int) (*sql.DB, string) {
+	t.Helper()
+	dsn := fmt.Sprintf("%d/%s", failures, t.Name())
+	db, err := sql.Open("flaky", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, dsn
+}
+
+func TestPingWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		attempts  int
+		wantErr   bool
+		wantTries int
+	}{
+		{name: "up straight away", failures: 0, attempts: 3, wantTries: 1},
+		{name: "up on the last attempt", failures: 2, attempts: 3, wantTries: 3},
+		{name: "never up", failures: 5, attempts: 3, wantErr: true, wantTries: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dsn := openFlaky(t, tt.failures)
+			err := pingWithRetry(context.Background(), db, tt.attempts, time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pingWithRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := flaky.attemptsFor(dsn); got != tt.wantTries {
+				t.Errorf("connected %d times, want %d", got, tt.wantTries)
+			}
+		})
+	}
+}
+
+func TestPingWithRetryStopsOnCancel(t *testing.T) {
+	db, _ := openFlaky(t, 100)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := pingWithRetry(ctx, db, 100, time.Hour)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("pingWithRetry() = %v, want the context's error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("pingWithRetry() kept waiting for %v after the context ended", elapsed)
+	}
+}
+
+func TestPQKeywordDSN(t *testing.T) {
+	got := pqKeywordDSN(map[string]string{
+		"host":     "/cloudsql/proj:region:db",
+		"user":     "tanglr",
+		"password": `it's a "p\ss" word`,
+		"dbname":   "tanglr",
+	})
+	want := `host='/cloudsql/proj:region:db' user='tanglr' password='it\'s a "p\\ss" word' dbname='tanglr'`
+	if got != want {
+		t.Errorf("pqKeywordDSN() = %s, want %s", got, want)
+	}
+}
+
+func TestUnixSocketProvider(t *testing.T) {
+	target, cleanup, err := unixSocketProvider(config.Database{
+		SocketDir: "/var/run/postgresql",
+		User:      "tanglr",
+		Name:      "tanglr",
+	})
+	if err != nil || cleanup != nil {
+		t.Fatalf("unixSocketProvider() error = %v, cleanup set = %v", err, cleanup != nil)
+	}
+	if target.Dialect != store.Postgres || target.Driver != "postgres" ||
+		!strings.Contains(target.DSN, "host='/var/run/postgresql'") || strings.Contains(target.DSN, "password") {
+		t.Errorf("unixSocketProvider() = %+v", target)
+	}
+}
+
+func TestSetupDBConn(t *testing.T) {
+	dbCfg := config.Default().Database
+	dbCfg.URL = "sqlite:" + filepath.Join(t.TempDir(), "tanglr.db")
+
+	repo, db, target, cleanup, err := setupDBConn(context.Background(), dbCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if repo == nil || target.Dialect != store.SQLite {
+		t.Errorf("setupDBConn() = %v, %+v", repo, target)
+	}
+	// SQLite gets a single connection whatever the pool settings say.
+	if got := db.Stats().MaxOpenConnections; got != 1 {
+		t.Errorf("MaxOpenConnections = %d, want 1", got)
+	}
+
+	if _, ok := connProviders["cloudsql"]; ok {
+		return
+	}
+	dbCfg.Provider = "cloudsql"
+	if _, _, _, _, err := setupDBConn(context.Background(), dbCfg); err == nil || !strings.Contains(err.Error(), "-tags cloudsql") {
+		t.Errorf("setupDBConn() with cloudsql in a default build = %v", err)
+	}
+}
+
+func TestSetupReplicasRejectsMismatches(t *testing.T) {
+	primary := store.Target{Dialect: store.Postgres}
+	for _, replica := range []string{"sqlite:replica.db", "mysql://replica"} {
+		dbCfg := config.Default().Database
+		dbCfg.ReplicaURLs = []string{replica}
+		if _, _, err := setupReplicas(context.Background(), dbCfg, primary); err == nil {
+			t.Errorf("setupReplicas(%s) with a Postgres primary = nil, want an error", replica)
+		}
+	}
+
+	dbCfg := config.Default().Database
+	dbCfg.ReplicaURLs = []string{"sqlite::memory:"}
+	if _, _, err := setupReplicas(context.Background(), dbCfg, store.Target{Dialect: store.SQLite}); err == nil {
+		t.Error("setupReplicas() accepted an in-memory replica")
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type UserRole struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	Role      auth.Role `json:"role"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+func (cfg *Config) HandlerGetUsersByRole(w http.ResponseWriter, r *http.Request) {
+	role, err := auth.ParseRole(r.URL.Query().Get("role"))
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid role", err)
+		return
+	}
+
+	dbUsers, err := cfg.DB.ListUsersByRole(r.Context(), string(role))
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get users", err)
+		return
+	}
+
+	users := []UserRole{}
+	for _, user := range dbUsers {
+		users = append(users, UserRole{
+			ID:        user.ID,
+			Username:  user.Username,
+			Role:      auth.Role(user.Role),
+			CreatedAt: user.CreatedAt,
+		})
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, users)
+}
+
+func (cfg *Config) HandlerPutUserRole(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	username := chi.URLParam(r, "username")
+	if username == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing username", nil)
+		return
+	}
+
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", nil)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	role, err := auth.ParseRole(params.Role)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid role", err)
+		return
+	}
+
+	target, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't update role", err)
+		return
+	}
+
+	// Stops the last admin from locking everyone out by demoting themselves.
+	// The token's username may be from before a rename, so compare IDs.
+	if target.UserID.String() == claims.Subject {
+		helpers.RespondWithError(w, http.StatusBadRequest, "You can't change your own role", nil)
+		return
+	}
+
+	user, err := cfg.DB.UpdateUserRole(r.Context(), database.UpdateUserRoleParams{
+		Username: username,
+		Role:     string(role),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't update role", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, UserRole{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     auth.Role(user.Role),
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/artyultra/tanglr/internal/auth"
+)
+
+func putUserRole(t *testing.T, cfg *Config, admin testUser, username, role string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := withToken(newRequest(t, http.MethodPut, "/", map[string]string{"role": role}), admin.Token)
+	rec := httptest.NewRecorder()
+	cfg.RequireRole(auth.RoleAdmin)(http.HandlerFunc(cfg.HandlerPutUserRole)).ServeHTTP(rec, withURLParams(r, "username", username))
+	return rec
+}
+
+func TestPutUserRole(t *testing.T) {
+	cfg := newTestConfig(t)
+	admin := createTestUserWithRole(t, cfg, "admin", auth.RoleAdmin)
+	alice := createTestUser(t, cfg, "alice")
+
+	rec := putUserRole(t, cfg, admin, alice.Username, "moderator")
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[UserRole](t, rec); got.Role != auth.RoleModerator || got.ID != alice.ID {
+		t.Errorf("response = %+v, want alice as moderator", got)
+	}
+
+	// Alice's existing token reaches the moderator routes straight away.
+	rec = serveModerator(cfg, cfg.HandlerGetReports, withToken(newRequest(t, http.MethodGet, "/", nil), alice.Token))
+	expectStatus(t, rec, http.StatusOK)
+
+	r := withToken(newRequest(t, http.MethodGet, "/?role=moderator", nil), admin.Token)
+	rec = httptest.NewRecorder()
+	cfg.RequireRole(auth.RoleAdmin)(http.HandlerFunc(cfg.HandlerGetUsersByRole)).ServeHTTP(rec, r)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[[]UserRole](t, rec); len(got) != 1 || got[0].Username != alice.Username {
+		t.Errorf("moderators = %+v, want just alice", got)
+	}
+
+	expectStatus(t, putUserRole(t, cfg, admin, alice.Username, "superuser"), http.StatusBadRequest)
+	expectStatus(t, putUserRole(t, cfg, admin, "nobody", "moderator"), http.StatusNotFound)
+	expectStatus(t, putUserRole(t, cfg, admin, admin.Username, "user"), http.StatusBadRequest)
+	expectStatus(t, putUserRole(t, cfg, alice, admin.Username, "user"), http.StatusForbidden)
+}
+
+// A rename leaves the old username in the admin's token; the guard must
+// still recognise them.
+func TestAdminCannotDemoteSelfAfterRename(t *testing.T) {
+	cfg := newTestConfig(t)
+	admin := createTestUserWithRole(t, cfg, "admin", auth.RoleAdmin)
+
+	expectStatus(t, renameUser(t, cfg, admin, "admin_2"), http.StatusOK)
+	expectStatus(t, putUserRole(t, cfg, admin, "admin_2", "user"), http.StatusBadRequest)
+}
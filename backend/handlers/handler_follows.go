@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type FollowResponse struct {
+	Username string `json:"username"`
+	Status   string `json:"status"`
+}
+
+// HandlerFollowUser follows {username}. Private accounts get a pending request
+// they have to accept; anyone else is followed straight away. Users on either
+// side of a block look nonexistent, as they do on their profile.
+func (cfg *Config) HandlerFollowUser(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	if username == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing username", nil)
+		return
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	target, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: username,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	if target.UserID == userID {
+		helpers.RespondWithError(w, http.StatusBadRequest, "You can't follow yourself", nil)
+		return
+	}
+
+	relationship, err := cfg.getRelationship(r.Context(), userID, target.UserID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get relationship", err)
+		return
+	}
+	if relationship.Following || relationship.Pending {
+		helpers.RespondWithError(w, http.StatusConflict, "You already follow this user", nil)
+		return
+	}
+
+	status := "accepted"
+	if target.PrivateMode.Bool {
+		status = "pending"
+	}
+
+	// The insert itself refuses blocked pairs, so a block made since the
+	// lookup above still wins.
+	follow, err := cfg.DB.InitiateFollowRequest(r.Context(), database.InitiateFollowRequestParams{
+		InitiatorID: userID,
+		TargetID:    target.UserID,
+		Status:      status,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+
+	cfg.invalidateSuggestions(userID, target.UserID)
+
+	helpers.RespondWithJSON(w, http.StatusCreated, FollowResponse{
+		Username: target.Username,
+		Status:   follow.Status,
+	})
+}
+
+// HandlerUnfollowUser stops following {username}, or withdraws a follow
+// request that hasn't been answered yet.
+func (cfg *Config) HandlerUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	userID, target, ok := cfg.followTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		InitiatorID: userID,
+		TargetID:    target,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't unfollow user", err)
+		return
+	}
+
+	err = cfg.DB.RejectFollowRequest(r.Context(), database.RejectFollowRequestParams{
+		InitiatorID: userID,
+		TargetID:    target,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't unfollow user", err)
+		return
+	}
+
+	cfg.invalidateSuggestions(userID, target)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandlerAcceptFollower accepts a pending follow request from {username}.
+func (cfg *Config) HandlerAcceptFollower(w http.ResponseWriter, r *http.Request) {
+	userID, follower, ok := cfg.followTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.AcceptFollowRequest(r.Context(), database.AcceptFollowRequestParams{
+		InitiatorID: follower,
+		TargetID:    userID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't accept follow request", err)
+		return
+	}
+
+	cfg.invalidateSuggestions(userID, follower)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandlerRemoveFollower rejects a pending follow request from {username}, or
+// removes them from the user's followers.
+func (cfg *Config) HandlerRemoveFollower(w http.ResponseWriter, r *http.Request) {
+	userID, follower, ok := cfg.followTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.RejectFollowRequest(r.Context(), database.RejectFollowRequestParams{
+		InitiatorID: follower,
+		TargetID:    userID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't remove follower", err)
+		return
+	}
+
+	err = cfg.DB.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		InitiatorID: follower,
+		TargetID:    userID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't remove follower", err)
+		return
+	}
+
+	cfg.invalidateSuggestions(userID, follower)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// followTarget authenticates the request and resolves the {username} path
+// parameter without a viewer, so follows can always be undone whatever
+// blocks are in place. It writes the error response itself and returns false
+// when the request should stop.
+func (cfg *Config) followTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	username := chi.URLParam(r, "username")
+	if username == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing username", nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	target, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return uuid.Nil, uuid.Nil, false
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, target.UserID, true
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func followUser(t *testing.T, cfg *Config, follower, target testUser, want int) FollowResponse {
+	t.Helper()
+	r := withURLParams(withToken(newRequest(t, http.MethodPost, "/", nil), follower.Token), "username", target.Username)
+	rec := serve(cfg.HandlerFollowUser, r)
+	expectStatus(t, rec, want)
+	if want != http.StatusCreated {
+		return FollowResponse{}
+	}
+	return decodeResponse[FollowResponse](t, rec)
+}
+
+func setPrivateMode(t *testing.T, cfg *Config, user testUser) {
+	t.Helper()
+	_, err := cfg.DBConn.Exec("UPDATE user_preferences SET private_mode = true WHERE user_id = ?", user.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func relationship(t *testing.T, cfg *Config, viewer, target testUser) Relationship {
+	t.Helper()
+	rel, err := cfg.getRelationship(t.Context(), viewer.ID, target.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rel
+}
+
+func TestFollowPublicAccount(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	if got := followUser(t, cfg, alice, bob, http.StatusCreated); got.Status != "accepted" {
+		t.Errorf("status = %q, want accepted", got.Status)
+	}
+	if !relationship(t, cfg, alice, bob).Following {
+		t.Error("not following after follow")
+	}
+
+	followUser(t, cfg, alice, bob, http.StatusConflict)
+	followUser(t, cfg, alice, alice, http.StatusBadRequest)
+
+	r := withURLParams(withToken(newRequest(t, http.MethodDelete, "/", nil), alice.Token), "username", bob.Username)
+	expectStatus(t, serve(cfg.HandlerUnfollowUser, r), http.StatusNoContent)
+	if relationship(t, cfg, alice, bob).Following {
+		t.Error("still following after unfollow")
+	}
+}
+
+func TestFollowPrivateAccount(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	setPrivateMode(t, cfg, bob)
+
+	if got := followUser(t, cfg, alice, bob, http.StatusCreated); got.Status != "pending" {
+		t.Errorf("status = %q, want pending", got.Status)
+	}
+	if rel := relationship(t, cfg, alice, bob); !rel.Pending || rel.Following {
+		t.Errorf("relationship = %+v, want pending", rel)
+	}
+
+	r := withURLParams(withToken(newRequest(t, http.MethodPost, "/", nil), bob.Token), "username", alice.Username)
+	expectStatus(t, serve(cfg.HandlerAcceptFollower, r), http.StatusNoContent)
+	if !relationship(t, cfg, alice, bob).Following {
+		t.Error("not following after the request was accepted")
+	}
+
+	r = withURLParams(withToken(newRequest(t, http.MethodDelete, "/", nil), bob.Token), "username", alice.Username)
+	expectStatus(t, serve(cfg.HandlerRemoveFollower, r), http.StatusNoContent)
+	if relationship(t, cfg, alice, bob).Following {
+		t.Error("still following after being removed")
+	}
+}
+
+func TestWithdrawFollowRequest(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	setPrivateMode(t, cfg, bob)
+
+	followUser(t, cfg, alice, bob, http.StatusCreated)
+
+	r := withURLParams(withToken(newRequest(t, http.MethodDelete, "/", nil), alice.Token), "username", bob.Username)
+	expectStatus(t, serve(cfg.HandlerUnfollowUser, r), http.StatusNoContent)
+	if rel := relationship(t, cfg, alice, bob); rel.Pending {
+		t.Error("request still pending after withdrawing it")
+	}
+
+	// Accepting a withdrawn request does nothing.
+	r = withURLParams(withToken(newRequest(t, http.MethodPost, "/", nil), bob.Token), "username", alice.Username)
+	expectStatus(t, serve(cfg.HandlerAcceptFollower, r), http.StatusNoContent)
+	if relationship(t, cfg, alice, bob).Following {
+		t.Error("accepting a withdrawn request created a follow")
+	}
+}
+
+func TestFollowRequiresToken(t *testing.T) {
+	cfg := newTestConfig(t)
+	bob := createTestUser(t, cfg, "bob")
+
+	r := withURLParams(newRequest(t, http.MethodPost, "/", nil), "username", bob.Username)
+	expectStatus(t, serve(cfg.HandlerFollowUser, r), http.StatusUnauthorized)
+}
@@ -1,123 +1,162 @@
 package handlers
 
-//
-// import (
-// 	"log"
-// 	"net/http"
-// 	"time"
-//
-// 	"github.com/artyultra/tanglr/handlers/helpers"
-// 	"github.com/artyultra/tanglr/internal/auth"
-// 	"github.com/artyultra/tanglr/internal/database"
-// 	"github.com/go-chi/chi"
-// 	"github.com/google/uuid"
-// )
-//
-// type Friends struct {
-// 	UserID      uuid.UUID `json:"user_id"`
-// 	FriendID    uuid.UUID `json:"friend_id"`
-// 	Status      string    `json:"status"`
-// 	InitiatorID uuid.UUID `json:"initiator_id"`
-// 	CreatedAt   time.Time `json:"created_at"`
-// 	UpdatedAt   time.Time `json:"updated_at"`
-// }
-//
-// type FriendUser struct {
-// 	UserID          uuid.UUID `json:"user_id"`
-// 	FriendID        uuid.UUID `json:"friend_id"`
-// 	Status          string    `json:"status"`
-// 	InitiatorID     uuid.UUID `json:"initiator_id"`
-// 	CreatedAt       time.Time `json:"created_at"`
-// 	UpdatedAt       time.Time `json:"updated_at"`
-// 	FriendUsername  string    `json:"friend_username"`
-// 	FriendAvatarURL string    `json:"friend_avatar_url"`
-// }
-//
-// func helperFriends(cfg *Config, w http.ResponseWriter, r *http.Request) database.GetUserByUsernameRow {
-// 	username := chi.URLParam(r, "username")
-// 	if username == "" {
-// 		helpers.RespondWithError(w, http.StatusBadRequest, "Missing username", nil)
-// 		return database.GetUserByUsernameRow{}
-// 	}
-//
-// 	jwtToken, err := auth.GetBearerToken(r.Header)
-// 	if err != nil {
-// 		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
-// 		return database.GetUserByUsernameRow{}
-// 	}
-//
-// 	_, err = auth.ValidateJWT(jwtToken, cfg.jwtSecret)
-// 	if err != nil {
-// 		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
-// 		return database.GetUserByUsernameRow{}
-// 	}
-//
-// 	DBUser, err := cfg.DB.GetUserByUsername(r.Context(), username)
-//
-// 	return DBUser
-// }
-//
-// func (cfg *Config) HandlerGetNonFriends(w http.ResponseWriter, r *http.Request) {
-// 	dbUser := helperFriends(cfg, w, r)
-//
-// 	dbNonFriends, err := cfg.DB.GetNonFriendUsers(r.Context(), dbUser.UserID)
-// 	if err != nil {
-// 		helpers.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error", err)
-// 		return
-// 	}
-//
-// 	nonFriends := []User{}
-// 	for _, user := range dbNonFriends {
-// 		nonFriends = append(nonFriends, User{
-// 			ID:        user.UserID,
-// 			Username:  user.Username,
-// 			Email:     user.Email,
-// 			CreatedAt: user.UserCreatedAt,
-// 			UpdatedAt: user.UserUpdatedAt,
-// 			AvatarURL: user.AvatarUrl.String,
-// 		})
-// 	}
-//
-// 	helpers.RespondWithJSON(w, http.StatusOK, nonFriends)
-// }
-//
-// func (cfg *Config) HandlerGetFriends(w http.ResponseWriter, r *http.Request) {
-// 	dbUser := helperFriends(cfg, w, r)
-//
-// 	dbFriends, err := cfg.DB.GetFriendsList(r.Context(), dbUser.ID)
-// 	if err != nil {
-// 		helpers.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error", err)
-// 		return
-// 	}
-//
-// 	log.Println(dbFriends)
-//
-// 	friends := []FriendUser{}
-// 	for _, friend := range dbFriends {
-// 		userID := dbUser.UserID
-// 		friendID := friend.UserID
-// 		if friend.UserID == dbUser.UserID {
-// 			userID = friend.FriendID
-// 			friendID = friend.UserID
-// 		}
-//
-// 		avatarURL := ""
-// 		if friend.FriendAvatarUrl.Valid {
-// 			avatarURL = friend.FriendAvatarUrl.String
-// 		}
-//
-// 		friends = append(friends, FriendUser{
-// 			UserID:          userID,
-// 			FriendID:        friendID,
-// 			Status:          friend.Status,
-// 			InitiatorID:     friend.InitiatorID,
-// 			CreatedAt:       friend.CreatedAt,
-// 			UpdatedAt:       friend.UpdatedAt,
-// 			FriendUsername:  friend.FriendUsername,
-// 			FriendAvatarURL: avatarURL,
-// 		})
-// 	}
-//
-// 	helpers.RespondWithJSON(w, http.StatusOK, friends)
-//
-// }
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// Friends are users who follow each other with both follows accepted.
+type Friend struct {
+	ID           uuid.UUID  `json:"id"`
+	Username     string     `json:"username"`
+	AvatarURL    string     `json:"avatar_url"`
+	FriendsSince *time.Time `json:"friends_since,omitempty"`
+}
+
+// Relationship describes how the viewer is connected to another user.
+type Relationship struct {
+	Following  bool `json:"following"`
+	FollowedBy bool `json:"followed_by"`
+	Pending    bool `json:"pending"`
+	IsMutual   bool `json:"is_mutual"`
+	Blocked    bool `json:"blocked"`
+}
+
+func (cfg *Config) getRelationship(ctx context.Context, viewerID, targetID uuid.UUID) (Relationship, error) {
+	row, err := cfg.DB.GetRelationship(ctx, database.GetRelationshipParams{
+		ViewerID: viewerID,
+		TargetID: targetID,
+	})
+	if err != nil {
+		return Relationship{}, err
+	}
+
+	rel := Relationship{
+		Following:  row.FollowingStatus.String == "accepted",
+		FollowedBy: row.FollowedByStatus.String == "accepted",
+		Pending:    row.FollowingStatus.String == "pending",
+		Blocked:    row.Blocking,
+	}
+	rel.IsMutual = rel.Following && rel.FollowedBy
+	return rel, nil
+}
+
+// friendsTarget authenticates the request and resolves the {username} path
+// parameter as seen by the viewer. Private accounts only list their friends to
+// followers. It writes the error response itself and returns false when the
+// request should stop.
+func (cfg *Config) friendsTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, database.GetUserByUsernameRow, bool) {
+	username := chi.URLParam(r, "username")
+	if username == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing username", nil)
+		return uuid.Nil, database.GetUserByUsernameRow{}, false
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return uuid.Nil, database.GetUserByUsernameRow{}, false
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return uuid.Nil, database.GetUserByUsernameRow{}, false
+	}
+
+	viewerID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return uuid.Nil, database.GetUserByUsernameRow{}, false
+	}
+
+	target, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: username,
+		ViewerID: uuid.NullUUID{UUID: viewerID, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return uuid.Nil, database.GetUserByUsernameRow{}, false
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return uuid.Nil, database.GetUserByUsernameRow{}, false
+	}
+
+	if target.UserID != viewerID && target.PrivateMode.Bool {
+		relationship, err := cfg.getRelationship(r.Context(), viewerID, target.UserID)
+		if err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get relationship", err)
+			return uuid.Nil, database.GetUserByUsernameRow{}, false
+		}
+		if !canViewProfile(target.PrivateMode.Bool, relationship) {
+			helpers.RespondWithError(w, http.StatusForbidden, "This account is private", nil)
+			return uuid.Nil, database.GetUserByUsernameRow{}, false
+		}
+	}
+
+	return viewerID, target, true
+}
+
+func (cfg *Config) HandlerGetFriends(w http.ResponseWriter, r *http.Request) {
+	viewerID, target, ok := cfg.friendsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	dbFriends, err := cfg.DB.GetFriendsList(r.Context(), database.GetFriendsListParams{
+		UserID:   target.UserID,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get friends", err)
+		return
+	}
+
+	friends := []Friend{}
+	for _, friend := range dbFriends {
+		friends = append(friends, Friend{
+			ID:           friend.ID,
+			Username:     friend.Username,
+			AvatarURL:    friend.AvatarUrl.String,
+			FriendsSince: &friend.FriendsSince,
+		})
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, friends)
+}
+
+func (cfg *Config) HandlerGetMutualFriends(w http.ResponseWriter, r *http.Request) {
+	viewerID, target, ok := cfg.friendsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	dbFriends, err := cfg.DB.GetMutualFriends(r.Context(), database.GetMutualFriendsParams{
+		ViewerID: viewerID,
+		TargetID: target.UserID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get mutual friends", err)
+		return
+	}
+
+	friends := []Friend{}
+	for _, friend := range dbFriends {
+		friends = append(friends, Friend{
+			ID:        friend.ID,
+			Username:  friend.Username,
+			AvatarURL: friend.AvatarUrl.String,
+		})
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, friends)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func befriend(t *testing.T, cfg *Config, a, b testUser) {
+	t.Helper()
+	followUser(t, cfg, a, b, http.StatusCreated)
+	followUser(t, cfg, b, a, http.StatusCreated)
+}
+
+func getFriends(t *testing.T, cfg *Config, h http.HandlerFunc, viewer testUser, username string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := withURLParams(withToken(newRequest(t, http.MethodGet, "/", nil), viewer.Token), "username", username)
+	return serve(h, r)
+}
+
+func friendNames(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	expectStatus(t, rec, http.StatusOK)
+	names := []string{}
+	for _, friend := range decodeResponse[[]Friend](t, rec) {
+		names = append(names, friend.Username)
+	}
+	return names
+}
+
+func TestFriendsAreMutualFollows(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	carol := createTestUser(t, cfg, "carol")
+	dave := createTestUser(t, cfg, "dave")
+	erin := createTestUser(t, cfg, "erin")
+
+	befriend(t, cfg, alice, bob)
+	befriend(t, cfg, alice, carol)
+	befriend(t, cfg, dave, carol)
+	followUser(t, cfg, alice, erin, http.StatusCreated)
+
+	if got := friendNames(t, getFriends(t, cfg, cfg.HandlerGetFriends, dave, alice.Username)); !slices.Equal(got, []string{"bob", "carol"}) {
+		t.Errorf("alice's friends = %v, want [bob carol]", got)
+	}
+
+	if got := friendNames(t, getFriends(t, cfg, cfg.HandlerGetMutualFriends, dave, alice.Username)); !slices.Equal(got, []string{"carol"}) {
+		t.Errorf("mutual friends of dave and alice = %v, want [carol]", got)
+	}
+
+	// The viewer never sees users on the other side of a block.
+	blockUser(t, cfg, bob, dave)
+	if got := friendNames(t, getFriends(t, cfg, cfg.HandlerGetFriends, dave, alice.Username)); !slices.Equal(got, []string{"carol"}) {
+		t.Errorf("alice's friends seen by dave = %v, want [carol]", got)
+	}
+
+	// Unfollowing ends the friendship.
+	r := withURLParams(withToken(newRequest(t, http.MethodDelete, "/", nil), carol.Token), "username", alice.Username)
+	expectStatus(t, serve(cfg.HandlerUnfollowUser, r), http.StatusNoContent)
+	if got := friendNames(t, getFriends(t, cfg, cfg.HandlerGetFriends, alice, alice.Username)); !slices.Equal(got, []string{"bob"}) {
+		t.Errorf("alice's friends = %v, want [bob]", got)
+	}
+}
+
+func TestFriendsOfPrivateAccount(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	carol := createTestUser(t, cfg, "carol")
+	befriend(t, cfg, alice, bob)
+	setPrivateMode(t, cfg, alice)
+
+	expectStatus(t, getFriends(t, cfg, cfg.HandlerGetFriends, carol, alice.Username), http.StatusForbidden)
+	expectStatus(t, getFriends(t, cfg, cfg.HandlerGetMutualFriends, carol, alice.Username), http.StatusForbidden)
+
+	// Followers and the owner can look.
+	if got := friendNames(t, getFriends(t, cfg, cfg.HandlerGetFriends, bob, alice.Username)); !slices.Equal(got, []string{"bob"}) {
+		t.Errorf("alice's friends seen by bob = %v, want [bob]", got)
+	}
+	if got := friendNames(t, getFriends(t, cfg, cfg.HandlerGetFriends, alice, alice.Username)); !slices.Equal(got, []string{"bob"}) {
+		t.Errorf("alice's own friends = %v, want [bob]", got)
+	}
+
+	expectStatus(t, getFriends(t, cfg, cfg.HandlerGetFriends, carol, "nobody"), http.StatusNotFound)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/buildinfo"
+	"github.com/artyultra/tanglr/internal/logging"
+	"github.com/artyultra/tanglr/sql/schema"
+)
+
+type ReadinessResponse struct {
+	Status          string            `json:"status"`
+	Checks          map[string]string `json:"checks"`
+	SchemaVersion   int64             `json:"schema_version"`
+	ExpectedVersion int64             `json:"expected_schema_version"`
+}
+
+type VersionResponse struct {
+	buildinfo.Info
+	SchemaVersion   int64 `json:"schema_version"`
+	ExpectedVersion int64 `json:"expected_schema_version"`
+}
+
+// SetDraining marks the server as shutting down so /readyz starts failing and
+// the load balancer stops sending new requests.
+func (cfg *Config) SetDraining() {
+	cfg.draining.Store(true)
+}
+
+// appliedSchemaVersion returns the current goose migration version. It follows
+// goose's own rule: walk the version table newest first, skipping versions
+// whose latest entry is a rollback.
+func appliedSchemaVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version_id, is_applied FROM goose_db_version ORDER BY id DESC`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	rolledBack := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var applied bool
+		if err := rows.Scan(&version, &applied); err != nil {
+			return 0, err
+		}
+		if rolledBack[version] {
+			continue
+		}
+		if applied {
+			return version, nil
+		}
+		rolledBack[version] = true
+	}
+	return 0, rows.Err()
+}
+
+// HandlerHealthz reports that the process is up. It deliberately checks
+// nothing else so a slow database doesn't get the process restarted.
+func (cfg *Config) HandlerHealthz(w http.ResponseWriter, r *http.Request) {
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// HandlerReadyz reports whether the instance should get traffic: it isn't
+// shutting down, the database answers and its schema is up to date.
+func (cfg *Config) HandlerReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	resp := ReadinessResponse{
+		Status: "ready",
+		Checks: map[string]string{},
+	}
+	fail := func(check, msg string) {
+		resp.Status = "unavailable"
+		resp.Checks[check] = msg
+	}
+	// The endpoint is public, so errors are logged rather than returned.
+	logger := logging.FromContext(r.Context())
+	failErr := func(check string, err error) {
+		logger.Error("readiness check failed", "check", check, "error", err)
+		fail(check, "unavailable")
+	}
+
+	if cfg.draining.Load() {
+		fail("shutdown", "draining")
+	} else {
+		resp.Checks["shutdown"] = "ok"
+	}
+
+	if err := cfg.DBConn.PingContext(ctx); err != nil {
+		failErr("database", err)
+	} else {
+		resp.Checks["database"] = "ok"
+	}
+
+	// Replicas are informational: reads fall back to the primary without
+	// them, so they never make the instance unready.
+	if cfg.replicas != nil {
+		healthy, total := cfg.replicas.Healthy()
+		resp.Checks["replicas"] = fmt.Sprintf("%d/%d healthy", healthy, total)
+	}
+
+	expected, err := schema.LatestVersion()
+	if err != nil {
+		failErr("migrations", err)
+	}
+	resp.ExpectedVersion = expected
+
+	applied, err := appliedSchemaVersion(ctx, cfg.DBConn)
+	switch {
+	case err != nil:
+		failErr("migrations", err)
+	case applied < expected:
+		fail("migrations", "database schema is behind")
+	default:
+		resp.Checks["migrations"] = "ok"
+	}
+	resp.SchemaVersion = applied
+
+	status := http.StatusOK
+	if resp.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	helpers.RespondWithJSON(w, status, resp)
+}
+
+func (cfg *Config) HandlerVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	resp := VersionResponse{
+		Info: buildinfo.Get(),
+	}
+
+	// The schema versions are best effort; /readyz is the place to learn
+	// whether they are acceptable.
+	resp.ExpectedVersion, _ = schema.LatestVersion()
+	resp.SchemaVersion, _ = appliedSchemaVersion(ctx, cfg.DBConn)
+
+	helpers.RespondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/artyultra/tanglr/sql/schema"
+)
+
+func readyz(t *testing.T, cfg *Config) (int, ReadinessResponse) {
+	t.Helper()
+	rec := serve(cfg.HandlerReadyz, newRequest(t, http.MethodGet, "/readyz", nil))
+	return rec.Code, decodeResponse[ReadinessResponse](t, rec)
+}
+
+func TestReadyz(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	status, resp := readyz(t, cfg)
+	expected, err := schema.LatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK || resp.Status != "ready" || resp.SchemaVersion != expected {
+		t.Fatalf("readyz = %d %+v, want ready at schema %d", status, resp, expected)
+	}
+
+	cfg.SetDraining()
+	status, resp = readyz(t, cfg)
+	if status != http.StatusServiceUnavailable || resp.Checks["shutdown"] != "draining" {
+		t.Errorf("readyz while draining = %d %+v", status, resp)
+	}
+}
+
+func TestReadyzSchemaBehind(t *testing.T) {
+	cfg := newTestConfig(t)
+	_, err := cfg.DBConn.Exec("DELETE FROM goose_db_version WHERE version_id = (SELECT MAX(version_id) FROM goose_db_version)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, resp := readyz(t, cfg)
+	if status != http.StatusServiceUnavailable || resp.Checks["migrations"] != "database schema is behind" {
+		t.Errorf("readyz = %d %+v, want the migrations check to fail", status, resp)
+	}
+}
+
+func TestReadyzHidesErrors(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.DBConn.Close()
+
+	rec := serve(cfg.HandlerReadyz, newRequest(t, http.MethodGet, "/readyz", nil))
+	expectStatus(t, rec, http.StatusServiceUnavailable)
+	resp := decodeResponse[ReadinessResponse](t, rec)
+	if resp.Checks["database"] != "unavailable" || resp.Checks["migrations"] != "unavailable" {
+		t.Errorf("checks = %v, want both unavailable", resp.Checks)
+	}
+	if strings.Contains(rec.Body.String(), "closed") {
+		t.Errorf("readyz leaked the database error: %s", rec.Body.String())
+	}
+}
+
+func TestAppliedSchemaVersionSkipsRollbacks(t *testing.T) {
+	cfg := newTestConfig(t)
+	latest, err := appliedSchemaVersion(t.Context(), cfg.DBConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// goose records a rollback as a second row for the same version.
+	_, err = cfg.DBConn.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES (?, false)", latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := appliedSchemaVersion(t.Context(), cfg.DBConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != latest-1 {
+		t.Errorf("appliedSchemaVersion() = %d after rolling back %d, want %d", got, latest, latest-1)
+	}
+}
+
+func TestHealthzAndVersion(t *testing.T) {
+	cfg := newTestConfig(t)
+	expectStatus(t, serve(cfg.HandlerHealthz, newRequest(t, http.MethodGet, "/healthz", nil)), http.StatusOK)
+
+	rec := serve(cfg.HandlerVersion, newRequest(t, http.MethodGet, "/version", nil))
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[VersionResponse](t, rec); got.SchemaVersion == 0 || got.SchemaVersion != got.ExpectedVersion {
+		t.Errorf("version = %+v, want matching schema versions", got)
+	}
+}
@@ -1,52 +1,123 @@
 package handlers
 
 import (
-	"encoding/json"
-	"log"
+	"database/sql"
 	"net/http"
 	"time"
 
 	"github.com/artyultra/tanglr/handlers/helpers"
 	"github.com/artyultra/tanglr/internal/auth"
 	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/internal/logging"
+	"github.com/artyultra/tanglr/internal/metrics"
+	"github.com/google/uuid"
 )
 
 func (cfg *Config) HandlerLogin(w http.ResponseWriter, r *http.Request) {
-	log.Println("LOGIN ATTEMPT")
 	type parameters struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
 	}
 
-	type response struct {
-		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	err := decoder.Decode(&params)
+	src := cfg.loginSource(r)
+
+	user, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: params.Username,
+	})
 	if err != nil {
-		helpers.RespondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		if err != sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+			return
+		}
+		auth.CheckPasswordNoUser(params.Password)
+		cfg.recordLoginAttempt(r.Context(), src, params.Username, uuid.Nil, loginUnknownUser, false)
+		metrics.Logins.WithLabelValues("invalid_credentials").Inc()
+		respondInvalidLogin(w)
 		return
 	}
 
-	user, err := cfg.DB.GetUserByUsername(r.Context(), params.Username)
+	locked, err := cfg.isLoginLocked(r.Context(), user.UserID)
 	if err != nil {
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+
+	// The password is checked even for a locked account so the response
+	// takes as long either way. Accounts created through an identity
+	// provider have no hash to compare with, so they get the dummy compare.
+	var passwordErr error
+	if user.HashedPassword == noPassword {
+		auth.CheckPasswordNoUser(params.Password)
+		passwordErr = errNoPassword
+	} else {
+		passwordErr = auth.CheckPassword(params.Password, user.HashedPassword)
+	}
+
+	if locked {
+		cfg.recordLoginAttempt(r.Context(), src, params.Username, user.UserID, loginLocked, false)
+		metrics.Logins.WithLabelValues("locked").Inc()
+		respondInvalidLogin(w)
+		return
+	}
+
+	if passwordErr != nil {
+		if err := cfg.registerFailedLogin(r.Context(), user.UserID); err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+			return
+		}
+		cfg.recordLoginAttempt(r.Context(), src, params.Username, user.UserID, loginWrongPassword, false)
+		metrics.Logins.WithLabelValues("invalid_credentials").Inc()
+		respondInvalidLogin(w)
+		return
+	}
+
+	if isSuspended(user.SuspendedAt, user.SuspendedUntil) {
+		cfg.recordLoginAttempt(r.Context(), src, params.Username, user.UserID, loginSuspended, false)
+		metrics.Logins.WithLabelValues("suspended").Inc()
+		helpers.RespondWithJSON(w, http.StatusForbidden, suspendedResponse(user.SuspensionReason, user.SuspendedUntil))
 		return
 	}
 
-	err = auth.CheckPassword(params.Password, user.HashedPassword)
+	mfaEnabled, err := cfg.hasTOTP(r.Context(), user.UserID)
 	if err != nil {
-		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid password", err)
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
 		return
 	}
+	if mfaEnabled {
+		// The failure count isn't cleared until the second factor is in, or
+		// a stolen password would reset the lockout on every guess at the
+		// code.
+		cfg.respondMFAPending(w, r, user, src)
+		return
+	}
+
+	cfg.completeLogin(w, r, user, src)
+}
 
-	jwtExpTime := time.Hour
+type loginResponse struct {
+	User
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
 
-	tokenString, err := auth.MakeJWT(user.UserID, user.Username, cfg.jwtSecret, jwtExpTime)
+// completeLogin finishes a login once every factor has been checked: it
+// clears failed attempts, issues the access and refresh tokens and records
+// the attempt, notifying the user if it came from somewhere new.
+func (cfg *Config) completeLogin(w http.ResponseWriter, r *http.Request, user database.GetUserByUsernameRow, src loginSource) {
+	err := cfg.DB.ClearLoginLockout(r.Context(), user.UserID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+
+	jwtExpTime := cfg.tokens.AccessTTL
+
+	tokenString, err := auth.MakeJWT(user.UserID, user.Username, auth.Role(user.Role), cfg.jwtKeys, jwtExpTime)
 	if err != nil {
 		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't create token", err)
 		return
@@ -58,7 +129,7 @@ func (cfg *Config) HandlerLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	refreshTokenExpTime := time.Now().Add(time.Hour * 24 * 60)
+	refreshTokenExpTime := time.Now().Add(cfg.tokens.RefreshTTL)
 
 	refreshToken, err := cfg.DB.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
 		Token:     refreshTokenString,
@@ -70,9 +141,19 @@ func (cfg *Config) HandlerLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Println("LOGIN SUCCESS")
+	newIP, newDevice, err := cfg.checkLoginSource(r.Context(), user.UserID, src)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to check login source", "error", err)
+	}
+	cfg.recordLoginAttempt(r.Context(), src, user.Username, user.UserID, "", newIP || newDevice)
+	if newIP || newDevice {
+		cfg.notifyNewLogin(r.Context(), user, src, newIP, newDevice)
+	}
+
+	metrics.Logins.WithLabelValues("success").Inc()
+	logging.FromContext(r.Context()).Info("login succeeded", "user_id", user.UserID, "new_ip", newIP, "new_device", newDevice)
 
-	helpers.RespondWithJSON(w, http.StatusOK, response{
+	helpers.RespondWithJSON(w, http.StatusOK, loginResponse{
 		User: User{
 			ID:        user.UserID,
 			Username:  user.Username,
@@ -87,5 +168,4 @@ func (cfg *Config) HandlerLogin(w http.ResponseWriter, r *http.Request) {
 		Token:        tokenString,
 		RefreshToken: refreshToken.Token,
 	})
-
 }
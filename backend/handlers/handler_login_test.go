@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLoginSucceeds(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+
+	rec := login(t, cfg, "alice", testPassword)
+	expectStatus(t, rec, http.StatusOK)
+	got := decodeResponse[loginResponse](t, rec)
+	if got.ID != alice.ID || got.Token == "" || got.RefreshToken == "" {
+		t.Errorf("login response = %+v", got)
+	}
+}
+
+// An unknown user, a wrong password and a locked account must look the same
+// to the caller.
+func TestLoginFailuresLookAlike(t *testing.T) {
+	cfg := newTestConfig(t, withLockout)
+	createTestUser(t, cfg, "alice")
+	createTestUser(t, cfg, "locked")
+	for range 3 {
+		login(t, cfg, "locked", "wrong")
+	}
+
+	var bodies []string
+	for _, attempt := range []struct{ username, password string }{
+		{"nobody", testPassword},
+		{"alice", "wrong"},
+		{"locked", testPassword},
+	} {
+		rec := login(t, cfg, attempt.username, attempt.password)
+		expectStatus(t, rec, http.StatusUnauthorized)
+		bodies = append(bodies, rec.Body.String())
+	}
+	for _, body := range bodies[1:] {
+		if body != bodies[0] {
+			t.Errorf("login failures differ: %q vs %q", body, bodies[0])
+		}
+	}
+}
+
+func TestLoginRecordsAttempts(t *testing.T) {
+	cfg := newTestConfig(t, withLockout)
+	createTestUser(t, cfg, "alice")
+
+	login(t, cfg, "nobody", testPassword)
+	login(t, cfg, "alice", "wrong")
+	login(t, cfg, "alice", testPassword)
+
+	rows, err := cfg.DBConn.Query("SELECT username, succeeded, failure_reason FROM login_attempts ORDER BY created_at")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	want := []struct {
+		username  string
+		succeeded bool
+		reason    string
+	}{
+		{"nobody", false, loginUnknownUser},
+		{"alice", false, loginWrongPassword},
+		{"alice", true, ""},
+	}
+	i := 0
+	for ; rows.Next(); i++ {
+		var username, reason string
+		var succeeded bool
+		if err := rows.Scan(&username, &succeeded, &reason); err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(want) || username != want[i].username || succeeded != want[i].succeeded || reason != want[i].reason {
+			t.Errorf("attempt %d = %s %v %q", i+1, username, succeeded, reason)
+		}
+	}
+	if i != len(want) {
+		t.Errorf("recorded %d attempts, want %d", i, len(want))
+	}
+}
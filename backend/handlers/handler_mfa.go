@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/internal/metrics"
+	"github.com/artyultra/tanglr/internal/store"
+	"github.com/google/uuid"
+)
+
+// recoveryCodeCount is how many recovery codes a user gets when they turn on
+// two-factor authentication.
+const recoveryCodeCount = 10
+
+type TOTPEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	// QRCodePNG is the otpauth URI as a base64-encoded PNG.
+	QRCodePNG string `json:"qr_code_png"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAPendingResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// hasTOTP reports whether userID has confirmed a TOTP authenticator.
+func (cfg *Config) hasTOTP(ctx context.Context, userID uuid.UUID) (bool, error) {
+	secret, err := cfg.DB.GetTOTPSecret(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return secret.ConfirmedAt.Valid, nil
+}
+
+// respondMFAPending answers a correct password from a user with two-factor
+// authentication with a short-lived token for POST /v1/login/mfa instead of
+// access and refresh tokens.
+func (cfg *Config) respondMFAPending(w http.ResponseWriter, r *http.Request, user database.GetUserByUsernameRow, src loginSource) {
+	token, err := auth.MakeMFAPendingToken(user.UserID, cfg.jwtSecret, cfg.tokens.MFATTL)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't create token", err)
+		return
+	}
+
+	cfg.recordLoginAttempt(r.Context(), src, user.Username, user.UserID, loginMFAPending, false)
+	metrics.Logins.WithLabelValues("mfa_pending").Inc()
+
+	helpers.RespondWithJSON(w, http.StatusOK, MFAPendingResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   time.Now().Add(cfg.tokens.MFATTL).UTC(),
+	})
+}
+
+// checkSecondFactor checks a TOTP code or, if one is given instead, a
+// recovery code. Either is used up by a successful check.
+func checkSecondFactor(ctx context.Context, db store.Repository, userID uuid.UUID, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		used, err := db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashRecoveryCode(recoveryCode),
+		})
+		return used == 1, err
+	}
+
+	secret, err := db.GetTOTPSecret(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	if !secret.ConfirmedAt.Valid {
+		return false, nil
+	}
+
+	step, ok := auth.CheckTOTP(secret.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	used, err := db.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	return used == 1, err
+}
+
+// HandlerLoginMFA completes a login started with a password by checking the
+// second factor against the mfa_pending token. Wrong codes count towards the
+// account lockout like wrong passwords.
+func (cfg *Config) HandlerLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	userID, err := auth.ValidateMFAPendingToken(params.MFAToken, cfg.jwtSecret)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+		return
+	}
+
+	dbUser, err := cfg.DB.GetUserById(r.Context(), database.GetUserByIdParams{ID: userID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+	user := database.GetUserByUsernameRow(dbUser)
+	src := cfg.loginSource(r)
+
+	locked, err := cfg.isLoginLocked(r.Context(), user.UserID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+	if locked {
+		cfg.recordLoginAttempt(r.Context(), src, user.Username, user.UserID, loginLocked, false)
+		metrics.Logins.WithLabelValues("locked").Inc()
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid code", nil)
+		return
+	}
+
+	if isSuspended(user.SuspendedAt, user.SuspendedUntil) {
+		cfg.recordLoginAttempt(r.Context(), src, user.Username, user.UserID, loginSuspended, false)
+		metrics.Logins.WithLabelValues("suspended").Inc()
+		helpers.RespondWithJSON(w, http.StatusForbidden, suspendedResponse(user.SuspensionReason, user.SuspendedUntil))
+		return
+	}
+
+	ok, err := checkSecondFactor(r.Context(), cfg.DB, user.UserID, params.Code, params.RecoveryCode)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+	if !ok {
+		if err := cfg.registerFailedLogin(r.Context(), user.UserID); err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+			return
+		}
+		cfg.recordLoginAttempt(r.Context(), src, user.Username, user.UserID, loginWrongCode, false)
+		metrics.Logins.WithLabelValues("invalid_mfa").Inc()
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid code", nil)
+		return
+	}
+
+	cfg.completeLogin(w, r, user, src)
+}
+
+// HandlerEnrollTOTP starts TOTP enrollment with a fresh secret. Two-factor
+// authentication isn't on until the first code is confirmed; enrolling again
+// before then replaces the secret.
+func (cfg *Config) HandlerEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	enabled, err := cfg.hasTOTP(r.Context(), userID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't start enrollment", err)
+		return
+	}
+	if enabled {
+		helpers.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already on", nil)
+		return
+	}
+
+	enrollment, err := auth.GenerateTOTP(claims.Username)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't start enrollment", err)
+		return
+	}
+
+	err = cfg.DB.StartTOTPEnrollment(r.Context(), database.StartTOTPEnrollmentParams{
+		UserID: userID,
+		Secret: enrollment.Secret,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't start enrollment", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, TOTPEnrollmentResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+		QRCodePNG:  base64.StdEncoding.EncodeToString(enrollment.QRCode),
+	})
+}
+
+// HandlerConfirmTOTP turns on two-factor authentication once the user proves
+// their authenticator works, and hands out recovery codes. The codes are
+// only stored hashed, so this is the one time they can be shown.
+func (cfg *Config) HandlerConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	secret, err := cfg.DB.GetTOTPSecret(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "No two-factor enrollment in progress", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't confirm enrollment", err)
+		return
+	}
+	if secret.ConfirmedAt.Valid {
+		helpers.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already on", nil)
+		return
+	}
+
+	step, ok := auth.CheckTOTP(secret.Secret, params.Code, time.Now())
+	if !ok {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid code", nil)
+		return
+	}
+
+	codes, err := auth.MakeRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't confirm enrollment", err)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't confirm enrollment", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	confirmed, err := qtx.ConfirmTOTP(r.Context(), database.ConfirmTOTPParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't confirm enrollment", err)
+		return
+	}
+	if confirmed == 0 {
+		helpers.RespondWithError(w, http.StatusConflict, "Two-factor authentication is already on", nil)
+		return
+	}
+
+	err = qtx.DeleteRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't confirm enrollment", err)
+		return
+	}
+	for _, code := range codes {
+		err = qtx.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashRecoveryCode(code),
+		})
+		if err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't confirm enrollment", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't confirm enrollment", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// HandlerDisableTOTP turns two-factor authentication off. It takes a current
+// code or a recovery code, so a stolen access token alone can't do it.
+func (cfg *Config) HandlerDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't turn off two-factor authentication", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	ok, err := checkSecondFactor(r.Context(), qtx, userID, params.Code, params.RecoveryCode)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't turn off two-factor authentication", err)
+		return
+	}
+	if !ok {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid code", nil)
+		return
+	}
+
+	err = qtx.DeleteTOTPSecret(r.Context(), userID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't turn off two-factor authentication", err)
+		return
+	}
+
+	err = qtx.DeleteRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't turn off two-factor authentication", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't turn off two-factor authentication", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
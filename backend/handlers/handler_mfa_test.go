@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+)
+
+// totpCode is the code an authenticator app would show at the given time.
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := hotp.GenerateCodeCustom(secret, uint64(at.Unix()/30), hotp.ValidateOpts{
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// enableTOTP turns on two-factor authentication for user with a code from
+// enrolledAt and returns the secret and recovery codes.
+func enableTOTP(t *testing.T, cfg *Config, user testUser, enrolledAt time.Time) (string, []string) {
+	t.Helper()
+	rec := serve(cfg.HandlerEnrollTOTP, withToken(newRequest(t, http.MethodPost, "/v1/users/me/mfa/totp", nil), user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	secret := decodeResponse[TOTPEnrollmentResponse](t, rec).Secret
+
+	rec = confirmTOTP(t, cfg, user, totpCode(t, secret, enrolledAt))
+	expectStatus(t, rec, http.StatusOK)
+	return secret, decodeResponse[RecoveryCodesResponse](t, rec).RecoveryCodes
+}
+
+func confirmTOTP(t *testing.T, cfg *Config, user testUser, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := withToken(newRequest(t, http.MethodPost, "/v1/users/me/mfa/totp/confirm", map[string]string{"code": code}), user.Token)
+	return serve(cfg.HandlerConfirmTOTP, r)
+}
+
+// startMFALogin logs in with a password and returns the mfa_pending token.
+func startMFALogin(t *testing.T, cfg *Config, username string) string {
+	t.Helper()
+	rec := login(t, cfg, username, testPassword)
+	expectStatus(t, rec, http.StatusOK)
+	pending := decodeResponse[MFAPendingResponse](t, rec)
+	if !pending.MFARequired || pending.MFAToken == "" {
+		t.Fatalf("password login with TOTP on = %s, want mfa_required", rec.Body)
+	}
+	return pending.MFAToken
+}
+
+func finishMFALogin(t *testing.T, cfg *Config, body map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serve(cfg.HandlerLoginMFA, newRequest(t, http.MethodPost, "/v1/login/mfa", body))
+}
+
+func TestTOTPLogin(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	enrolled := time.Now()
+	secret, _ := enableTOTP(t, cfg, alice, enrolled)
+
+	token := startMFALogin(t, cfg, "alice")
+	rec := finishMFALogin(t, cfg, map[string]string{"mfa_token": token, "code": "nope00"})
+	expectStatus(t, rec, http.StatusUnauthorized)
+
+	rec = finishMFALogin(t, cfg, map[string]string{"mfa_token": token, "code": totpCode(t, secret, enrolled.Add(30*time.Second))})
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[loginResponse](t, rec); got.ID != alice.ID || got.Token == "" {
+		t.Errorf("MFA login response = %+v", got)
+	}
+
+	expectStatus(t, finishMFALogin(t, cfg, map[string]string{"mfa_token": "not-a-token", "code": "nope00"}), http.StatusUnauthorized)
+}
+
+func TestTOTPStepCannotBeReused(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	enrolled := time.Now()
+	secret, _ := enableTOTP(t, cfg, alice, enrolled)
+
+	// The code used to confirm enrollment is spent.
+	token := startMFALogin(t, cfg, "alice")
+	expectStatus(t, finishMFALogin(t, cfg, map[string]string{"mfa_token": token, "code": totpCode(t, secret, enrolled)}), http.StatusUnauthorized)
+
+	next := totpCode(t, secret, enrolled.Add(30*time.Second))
+	expectStatus(t, finishMFALogin(t, cfg, map[string]string{"mfa_token": token, "code": next}), http.StatusOK)
+
+	// So is every code from that step or before, even with a fresh token.
+	token = startMFALogin(t, cfg, "alice")
+	expectStatus(t, finishMFALogin(t, cfg, map[string]string{"mfa_token": token, "code": next}), http.StatusUnauthorized)
+	expectStatus(t, finishMFALogin(t, cfg, map[string]string{"mfa_token": token, "code": totpCode(t, secret, enrolled)}), http.StatusUnauthorized)
+
+	// Replays also can't turn two-factor authentication off.
+	r := withToken(newRequest(t, http.MethodDelete, "/v1/users/me/mfa/totp", map[string]string{"code": next}), alice.Token)
+	expectStatus(t, serve(cfg.HandlerDisableTOTP, r), http.StatusBadRequest)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	_, codes := enableTOTP(t, cfg, alice, time.Now())
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	// Codes are single-use and forgiving about case and spacing.
+	token := startMFALogin(t, cfg, "alice")
+	expectStatus(t, finishMFALogin(t, cfg, map[string]string{"mfa_token": token, "recovery_code": " " + codes[0] + " "}), http.StatusOK)
+	expectStatus(t, finishMFALogin(t, cfg, map[string]string{"mfa_token": token, "recovery_code": codes[0]}), http.StatusUnauthorized)
+
+	r := withToken(newRequest(t, http.MethodDelete, "/v1/users/me/mfa/totp", map[string]string{"recovery_code": codes[1]}), alice.Token)
+	expectStatus(t, serve(cfg.HandlerDisableTOTP, r), http.StatusNoContent)
+
+	// With two-factor authentication off the remaining codes are gone and a
+	// password is enough again.
+	rec := login(t, cfg, "alice", testPassword)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[loginResponse](t, rec); got.Token == "" {
+		t.Errorf("password login after turning TOTP off = %s", rec.Body)
+	}
+	var left int
+	if err := cfg.DBConn.QueryRow("SELECT count(*) FROM recovery_codes").Scan(&left); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d recovery codes left after turning TOTP off", left)
+	}
+}
+
+func TestTOTPEnrollment(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+
+	expectStatus(t, confirmTOTP(t, cfg, alice, "123456"), http.StatusNotFound)
+
+	rec := serve(cfg.HandlerEnrollTOTP, withToken(newRequest(t, http.MethodPost, "/v1/users/me/mfa/totp", nil), alice.Token))
+	expectStatus(t, rec, http.StatusOK)
+	enrollment := decodeResponse[TOTPEnrollmentResponse](t, rec)
+	if enrollment.Secret == "" || enrollment.QRCodePNG == "" {
+		t.Fatalf("enrollment = %+v", enrollment)
+	}
+
+	// An unconfirmed enrollment doesn't change how alice logs in.
+	if got := decodeResponse[loginResponse](t, login(t, cfg, "alice", testPassword)); got.Token == "" {
+		t.Error("unconfirmed TOTP asked for a second factor")
+	}
+
+	expectStatus(t, confirmTOTP(t, cfg, alice, "nope00"), http.StatusBadRequest)
+	expectStatus(t, confirmTOTP(t, cfg, alice, totpCode(t, enrollment.Secret, time.Now())), http.StatusOK)
+	expectStatus(t, confirmTOTP(t, cfg, alice, totpCode(t, enrollment.Secret, time.Now())), http.StatusConflict)
+	expectStatus(t, serve(cfg.HandlerEnrollTOTP, withToken(newRequest(t, http.MethodPost, "/v1/users/me/mfa/totp", nil), alice.Token)), http.StatusConflict)
+}
+
+func TestWrongCodesCountTowardsLockout(t *testing.T) {
+	cfg := newTestConfig(t, withLockout)
+	alice := createTestUser(t, cfg, "alice")
+	secret, _ := enableTOTP(t, cfg, alice, time.Now())
+
+	token := startMFALogin(t, cfg, "alice")
+	for range 3 {
+		expectStatus(t, finishMFALogin(t, cfg, map[string]string{"mfa_token": token, "code": "nope00"}), http.StatusUnauthorized)
+	}
+	expectLockedFor(t, cfg, alice, time.Minute)
+	code := totpCode(t, secret, time.Now().Add(30*time.Second))
+	expectStatus(t, finishMFALogin(t, cfg, map[string]string{"mfa_token": token, "code": code}), http.StatusUnauthorized)
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type ModerationAction struct {
+	ID                uuid.UUID  `json:"id"`
+	ModeratorID       *uuid.UUID `json:"moderator_id,omitempty"`
+	ModeratorUsername string     `json:"moderator_username,omitempty"`
+	ReportID          *uuid.UUID `json:"report_id,omitempty"`
+	Action            string     `json:"action"`
+	TargetUserID      *uuid.UUID `json:"target_user_id,omitempty"`
+	TargetPostID      *uuid.UUID `json:"target_post_id,omitempty"`
+	Note              string     `json:"note"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+func paginationParams(r *http.Request) (int32, int32) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return int32(limit), int32(offset)
+}
+
+func (cfg *Config) HandlerGetReports(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "open"
+	}
+
+	limit, offset := paginationParams(r)
+
+	dbReports, err := cfg.DB.ListReportsByStatus(r.Context(), database.ListReportsByStatusParams{
+		Status: status,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get reports", err)
+		return
+	}
+
+	reports := []Report{}
+	for _, dbReport := range dbReports {
+		report := reportFromDB(database.Report{
+			ID:             dbReport.ID,
+			ReporterID:     dbReport.ReporterID,
+			ReportedUserID: dbReport.ReportedUserID,
+			PostID:         dbReport.PostID,
+			Reason:         dbReport.Reason,
+			Details:        dbReport.Details,
+			Status:         dbReport.Status,
+			ClaimedBy:      dbReport.ClaimedBy,
+			ClaimedAt:      dbReport.ClaimedAt,
+			ResolvedBy:     dbReport.ResolvedBy,
+			ResolvedAt:     dbReport.ResolvedAt,
+			Resolution:     dbReport.Resolution,
+			CreatedAt:      dbReport.CreatedAt,
+			UpdatedAt:      dbReport.UpdatedAt,
+		})
+		report.ReporterUsername = dbReport.ReporterUsername
+		report.ReportedUsername = dbReport.ReportedUsername
+		report.PostBody = dbReport.PostBody.String
+		reports = append(reports, report)
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, reports)
+}
+
+func (cfg *Config) HandlerClaimReport(w http.ResponseWriter, r *http.Request) {
+	moderatorID, err := userIDFromContext(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	reportID, err := uuid.Parse(chi.URLParam(r, "reportID"))
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid report id", err)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error creating transaction", err)
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	report, err := qtx.ClaimReport(r.Context(), database.ClaimReportParams{
+		ID:        reportID,
+		ClaimedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusConflict, "Report is not open", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't claim report", err)
+		return
+	}
+
+	err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ModeratorID:  uuid.NullUUID{UUID: moderatorID, Valid: true},
+		ReportID:     uuid.NullUUID{UUID: report.ID, Valid: true},
+		Action:       "claim",
+		TargetUserID: uuid.NullUUID{UUID: report.ReportedUserID, Valid: true},
+		TargetPostID: report.PostID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't record moderation action", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error committing transaction", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, reportFromDB(report))
+}
+
+func (cfg *Config) HandlerResolveReport(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action        string `json:"action"`
+		Note          string `json:"note"`
+		DurationHours int    `json:"duration_hours"`
+	}
+
+	moderatorID, err := userIDFromContext(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	reportID, err := uuid.Parse(chi.URLParam(r, "reportID"))
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid report id", err)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	status := "resolved"
+	switch params.Action {
+	case "hide_post", "suspend_user":
+	case "dismiss":
+		status = "dismissed"
+	default:
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid moderation action", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error creating transaction", err)
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	report, err := qtx.ResolveReport(r.Context(), database.ResolveReportParams{
+		Status:     status,
+		Resolution: sql.NullString{String: params.Action, Valid: true},
+		ResolvedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+		ID:         reportID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusConflict, "Report is closed or claimed by another moderator", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't resolve report", err)
+		return
+	}
+
+	switch params.Action {
+	case "hide_post":
+		if !report.PostID.Valid {
+			helpers.RespondWithError(w, http.StatusBadRequest, "Report is not about a post", nil)
+			return
+		}
+		err = qtx.HidePost(r.Context(), report.PostID.UUID)
+		if err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't hide post", err)
+			return
+		}
+	case "suspend_user":
+		role, err := qtx.GetUserRole(r.Context(), report.ReportedUserID)
+		if err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user role", err)
+			return
+		}
+		if !outranks(r.Context(), role) {
+			helpers.RespondWithError(w, http.StatusForbidden, "You can't suspend a user whose role is the same as or above yours", nil)
+			return
+		}
+		reason := params.Note
+		if reason == "" {
+			reason = report.Reason
+		}
+		err = suspendUser(r.Context(), qtx, report.ReportedUserID, suspensionEnd(params.DurationHours), reason)
+		if err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't suspend user", err)
+			return
+		}
+	}
+
+	err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ModeratorID:  uuid.NullUUID{UUID: moderatorID, Valid: true},
+		ReportID:     uuid.NullUUID{UUID: report.ID, Valid: true},
+		Action:       params.Action,
+		TargetUserID: uuid.NullUUID{UUID: report.ReportedUserID, Valid: true},
+		TargetPostID: report.PostID,
+		Note:         params.Note,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't record moderation action", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error committing transaction", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, reportFromDB(report))
+}
+
+func (cfg *Config) HandlerGetModerationLog(w http.ResponseWriter, r *http.Request) {
+	limit, offset := paginationParams(r)
+
+	dbActions, err := cfg.DB.ListModerationActions(r.Context(), database.ListModerationActionsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get moderation log", err)
+		return
+	}
+
+	actions := []ModerationAction{}
+	for _, action := range dbActions {
+		actions = append(actions, ModerationAction{
+			ID:                action.ID,
+			ModeratorID:       nullUUIDPtr(action.ModeratorID),
+			ModeratorUsername: action.ModeratorUsername.String,
+			ReportID:          nullUUIDPtr(action.ReportID),
+			Action:            action.Action,
+			TargetUserID:      nullUUIDPtr(action.TargetUserID),
+			TargetPostID:      nullUUIDPtr(action.TargetPostID),
+			Note:              action.Note,
+			CreatedAt:         action.CreatedAt,
+		})
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, actions)
+}
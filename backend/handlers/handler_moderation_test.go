@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/google/uuid"
+)
+
+func latestPostBy(t *testing.T, cfg *Config, viewer, author testUser) Post {
+	t.Helper()
+	for _, post := range timeline(t, cfg, viewer) {
+		if post.UserID == author.ID {
+			return post
+		}
+	}
+	t.Fatalf("no post by %s on %s's timeline", author.Username, viewer.Username)
+	return Post{}
+}
+
+func createReport(t *testing.T, cfg *Config, reporter testUser, body map[string]any, want int) Report {
+	t.Helper()
+	rec := serve(cfg.HandlerCreateReport, withToken(newRequest(t, http.MethodPost, "/v1/reports", body), reporter.Token))
+	expectStatus(t, rec, want)
+	if want != http.StatusCreated {
+		return Report{}
+	}
+	return decodeResponse[Report](t, rec)
+}
+
+func reportRequest(t *testing.T, moderator testUser, reportID uuid.UUID, body any) *http.Request {
+	r := withToken(newRequest(t, http.MethodPost, "/", body), moderator.Token)
+	return withURLParams(r, "reportID", reportID.String())
+}
+
+func TestReportPostAndHideIt(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	mod := createTestUserWithRole(t, cfg, "mod", auth.RoleModerator)
+	createTestPost(t, cfg, bob, "buy my stuff")
+	post := latestPostBy(t, cfg, alice, bob)
+
+	report := createReport(t, cfg, alice, map[string]any{"post_id": post.ID, "reason": "spam"}, http.StatusCreated)
+	if report.ReportedUserID != bob.ID || report.Status != "open" {
+		t.Fatalf("report = %+v, want an open report against bob", report)
+	}
+
+	rec := serveModerator(cfg, cfg.HandlerGetReports, withToken(newRequest(t, http.MethodGet, "/", nil), mod.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if queue := decodeResponse[[]Report](t, rec); len(queue) != 1 || queue[0].ID != report.ID {
+		t.Fatalf("queue = %+v, want the one report", queue)
+	}
+
+	rec = serveModerator(cfg, cfg.HandlerClaimReport, reportRequest(t, mod, report.ID, nil))
+	expectStatus(t, rec, http.StatusOK)
+	rec = serveModerator(cfg, cfg.HandlerClaimReport, reportRequest(t, mod, report.ID, nil))
+	expectStatus(t, rec, http.StatusConflict)
+
+	rec = serveModerator(cfg, cfg.HandlerResolveReport, reportRequest(t, mod, report.ID, map[string]string{
+		"action": "hide_post",
+		"note":   "spam",
+	}))
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[Report](t, rec); got.Status != "resolved" || got.Resolution != "hide_post" {
+		t.Errorf("resolved report = %+v", got)
+	}
+
+	if postedBy(timeline(t, cfg, alice), bob) {
+		t.Error("hidden post is still on the timeline")
+	}
+
+	rec = serveModerator(cfg, cfg.HandlerGetModerationLog, withToken(newRequest(t, http.MethodGet, "/", nil), mod.Token))
+	expectStatus(t, rec, http.StatusOK)
+	actions := map[string]bool{}
+	for _, action := range decodeResponse[[]ModerationAction](t, rec) {
+		if action.ModeratorID == nil || *action.ModeratorID != mod.ID {
+			t.Errorf("action %+v not attributed to the moderator", action)
+		}
+		actions[action.Action] = true
+	}
+	if !actions["claim"] || !actions["hide_post"] {
+		t.Errorf("moderation log actions = %v, want claim and hide_post", actions)
+	}
+}
+
+func TestDismissReport(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	mod := createTestUserWithRole(t, cfg, "mod", auth.RoleModerator)
+
+	report := createReport(t, cfg, alice, map[string]any{"username": bob.Username, "reason": "other"}, http.StatusCreated)
+
+	rec := serveModerator(cfg, cfg.HandlerResolveReport, reportRequest(t, mod, report.ID, map[string]string{"action": "ban_forever"}))
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	rec = serveModerator(cfg, cfg.HandlerResolveReport, reportRequest(t, mod, report.ID, map[string]string{"action": "dismiss"}))
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[Report](t, rec); got.Status != "dismissed" {
+		t.Errorf("status = %q, want dismissed", got.Status)
+	}
+
+	// A closed report can't be resolved again.
+	rec = serveModerator(cfg, cfg.HandlerResolveReport, reportRequest(t, mod, report.ID, map[string]string{"action": "dismiss"}))
+	expectStatus(t, rec, http.StatusConflict)
+}
+
+func TestCreateReportValidation(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	createReport(t, cfg, alice, map[string]any{"username": bob.Username, "reason": "boring"}, http.StatusBadRequest)
+	createReport(t, cfg, alice, map[string]any{"reason": "spam"}, http.StatusBadRequest)
+	createReport(t, cfg, alice, map[string]any{"username": alice.Username, "reason": "spam"}, http.StatusBadRequest)
+	createReport(t, cfg, alice, map[string]any{"username": "nobody", "reason": "spam"}, http.StatusNotFound)
+	createReport(t, cfg, alice, map[string]any{"post_id": uuid.New(), "reason": "spam"}, http.StatusNotFound)
+}
+
+// Blocks hide people from each other, but not from reports.
+func TestReportAcrossBlock(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	carol := createTestUser(t, cfg, "carol")
+	createTestPost(t, cfg, bob, "something nasty")
+	post := latestPostBy(t, cfg, carol, bob)
+
+	blockUser(t, cfg, bob, alice)
+	createReport(t, cfg, alice, map[string]any{"username": bob.Username, "reason": "harassment"}, http.StatusCreated)
+	createReport(t, cfg, alice, map[string]any{"post_id": post.ID, "reason": "harassment"}, http.StatusCreated)
+
+	blockUser(t, cfg, carol, bob)
+	if got := createReport(t, cfg, carol, map[string]any{"username": bob.Username, "reason": "harassment"}, http.StatusCreated); got.ReportedUserID != bob.ID {
+		t.Errorf("report = %+v", got)
+	}
+}
+
+func TestModerationRoutesNeedModerator(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+
+	rec := serveModerator(cfg, cfg.HandlerGetReports, withToken(newRequest(t, http.MethodGet, "/", nil), alice.Token))
+	expectStatus(t, rec, http.StatusForbidden)
+}
@@ -0,0 +1,548 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/internal/metrics"
+	"github.com/artyultra/tanglr/internal/sso"
+	"github.com/artyultra/tanglr/internal/store"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// oidcLoginTTL is how long a user has to sign in at the provider and come
+// back.
+const oidcLoginTTL = 10 * time.Minute
+
+// noPassword is the users.hashed_password default. It never matches a
+// password, so accounts created through a provider can only sign in there.
+const noPassword = "unset"
+
+var (
+	errNoPassword       = errors.New("account has no password")
+	errNoVerifiedEmail  = errors.New("identity provider gave no verified email")
+	errLinkRequired     = errors.New("identity's email belongs to an account it isn't linked to")
+	errOIDCLoginExpired = errors.New("oidc login not found or expired")
+)
+
+var usernameDisallowed = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// newSSOProviders builds a client for each configured provider. Discovery
+// waits until the first login.
+func newSSOProviders(c config.OIDC) map[string]*sso.Provider {
+	client := &http.Client{Timeout: 10 * time.Second}
+	providers := make(map[string]*sso.Provider, len(c.Providers))
+	for name, p := range c.Providers {
+		providers[name] = sso.New(sso.Config{
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, client)
+	}
+	return providers
+}
+
+type OIDCProviderResponse struct {
+	Name string `json:"name"`
+}
+
+type OIDCLoginResponse struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	State            string    `json:"state"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+type LinkedIdentity struct {
+	Provider    string    `json:"provider"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+func linkedIdentityFromRow(row database.Identity) LinkedIdentity {
+	return LinkedIdentity{
+		Provider:    row.Provider,
+		Email:       row.Email,
+		CreatedAt:   row.CreatedAt,
+		LastLoginAt: row.LastLoginAt,
+	}
+}
+
+// HandlerGetOIDCProviders lists the providers users can sign in with.
+func (cfg *Config) HandlerGetOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	providers := make([]OIDCProviderResponse, 0, len(cfg.sso))
+	for name := range cfg.sso {
+		providers = append(providers, OIDCProviderResponse{Name: name})
+	}
+	slices.SortFunc(providers, func(a, b OIDCProviderResponse) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	helpers.RespondWithJSON(w, http.StatusOK, providers)
+}
+
+// HandlerBeginOIDCLogin returns the provider URL to send the browser to.
+// The provider redirects back to the frontend with a code and the state,
+// which the frontend passes to HandlerFinishOIDCLogin.
+func (cfg *Config) HandlerBeginOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	cfg.beginOIDC(w, r, uuid.NullUUID{})
+}
+
+// beginOIDC starts a provider login for HandlerBeginOIDCLogin or, when
+// userID is set, for linking an identity to that user.
+func (cfg *Config) beginOIDC(w http.ResponseWriter, r *http.Request, userID uuid.NullUUID) {
+	name := chi.URLParam(r, "provider")
+	provider, ok := cfg.sso[name]
+	if !ok {
+		helpers.RespondWithError(w, http.StatusNotFound, "Unknown identity provider", nil)
+		return
+	}
+
+	login := sso.NewLogin()
+	authURL, err := provider.AuthCodeURL(r.Context(), login)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusBadGateway, "Couldn't reach the identity provider", err)
+		return
+	}
+
+	expiresAt := time.Now().Add(oidcLoginTTL)
+	err = cfg.DB.CreateOIDCLogin(r.Context(), database.CreateOIDCLoginParams{
+		State:        login.State,
+		Provider:     name,
+		Nonce:        login.Nonce,
+		CodeVerifier: login.Verifier,
+		ExpiresAt:    expiresAt,
+		UserID:       userID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't start login", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, OIDCLoginResponse{
+		AuthorizationURL: authURL,
+		State:            login.State,
+		ExpiresAt:        expiresAt.UTC(),
+	})
+}
+
+// HandlerFinishOIDCLogin exchanges the code from the provider's redirect for
+// a verified identity and logs in the user it belongs to. An identity seen
+// for the first time gets a new account, or is linked to the account with
+// the same email if that email is verified; an unverified account has to
+// link the identity itself while signed in. Users with two-factor
+// authentication still need their code.
+func (cfg *Config) HandlerFinishOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		State string `json:"state"`
+		Code  string `json:"code"`
+	}
+
+	name := chi.URLParam(r, "provider")
+	provider, ok := cfg.sso[name]
+	if !ok {
+		helpers.RespondWithError(w, http.StatusNotFound, "Unknown identity provider", nil)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	src := cfg.loginSource(r)
+
+	pending, err := cfg.takeOIDCLogin(r.Context(), name, params.State, uuid.NullUUID{})
+	if err != nil {
+		if err == errOIDCLoginExpired {
+			helpers.RespondWithError(w, http.StatusBadRequest, "Login expired, start again", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), params.Code, sso.Login{
+		State:    params.State,
+		Nonce:    pending.Nonce,
+		Verifier: pending.CodeVerifier,
+	})
+	if err != nil {
+		if errors.Is(err, sso.ErrInvalidLogin) {
+			metrics.Logins.WithLabelValues("invalid_oidc").Inc()
+			helpers.RespondWithError(w, http.StatusUnauthorized, "Couldn't verify the sign-in with the identity provider", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusBadGateway, "Couldn't reach the identity provider", err)
+		return
+	}
+
+	userID, err := cfg.resolveIdentity(r.Context(), name, identity)
+	if err != nil {
+		if err == errNoVerifiedEmail {
+			metrics.Logins.WithLabelValues("invalid_oidc").Inc()
+			helpers.RespondWithError(w, http.StatusForbidden, "Your account with the identity provider has no verified email", err)
+			return
+		}
+		if err == errLinkRequired {
+			metrics.Logins.WithLabelValues("invalid_oidc").Inc()
+			helpers.RespondWithError(w, http.StatusConflict, "An account with this email already exists; sign in to it and link the identity provider from your settings", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+
+	dbUser, err := cfg.DB.GetUserById(r.Context(), database.GetUserByIdParams{ID: userID})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+	user := database.GetUserByUsernameRow(dbUser)
+
+	if isSuspended(user.SuspendedAt, user.SuspendedUntil) {
+		cfg.recordLoginAttempt(r.Context(), src, user.Username, user.UserID, loginSuspended, false)
+		metrics.Logins.WithLabelValues("suspended").Inc()
+		helpers.RespondWithJSON(w, http.StatusForbidden, suspendedResponse(user.SuspensionReason, user.SuspendedUntil))
+		return
+	}
+
+	mfaEnabled, err := cfg.hasTOTP(r.Context(), user.UserID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+	if mfaEnabled {
+		cfg.respondMFAPending(w, r, user, src)
+		return
+	}
+
+	cfg.completeLogin(w, r, user, src)
+}
+
+// takeOIDCLogin loads and deletes a pending login, so each state can be
+// used once. owner is the user who started it to link an identity, or unset
+// for a login; a login started for the other purpose counts as expired.
+func (cfg *Config) takeOIDCLogin(ctx context.Context, provider, state string, owner uuid.NullUUID) (database.TakeOIDCLoginRow, error) {
+	pending, err := cfg.DB.TakeOIDCLogin(ctx, database.TakeOIDCLoginParams{
+		State:    state,
+		Provider: provider,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return pending, errOIDCLoginExpired
+		}
+		return pending, err
+	}
+	if time.Now().After(pending.ExpiresAt) || pending.UserID != owner {
+		return pending, errOIDCLoginExpired
+	}
+	return pending, nil
+}
+
+// verifiedEmail is the email stored with an identity. Only addresses the
+// provider has verified are kept, so a linked identity's email can vouch
+// for the account's.
+func verifiedEmail(identity sso.Identity) string {
+	if !identity.EmailVerified {
+		return ""
+	}
+	return identity.Email
+}
+
+// hasVerifiedEmail reports whether user's email is verified. Tanglr doesn't
+// send verification mail itself; an address counts as verified once an
+// identity provider linked to the account has vouched for it.
+func hasVerifiedEmail(ctx context.Context, db store.Repository, user database.User) (bool, error) {
+	identities, err := db.ListIdentities(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, identity := range identities {
+		if identity.Email != "" && strings.EqualFold(identity.Email, user.Email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// resolveIdentity returns the user an identity belongs to, creating an
+// account the first time it is seen. An identity whose email matches an
+// existing account is only linked to it when that account's email is
+// verified; otherwise anyone could register the address first and wait for
+// its owner to sign in through the provider.
+func (cfg *Config) resolveIdentity(ctx context.Context, provider string, identity sso.Identity) (uuid.UUID, error) {
+	existing, err := cfg.DB.GetIdentity(ctx, database.GetIdentityParams{
+		Provider: provider,
+		Subject:  identity.Subject,
+	})
+	if err == nil {
+		err = cfg.DB.TouchIdentity(ctx, database.TouchIdentityParams{
+			ID:    existing.ID,
+			Email: verifiedEmail(identity),
+		})
+		return existing.UserID, err
+	}
+	if err != sql.ErrNoRows {
+		return uuid.Nil, err
+	}
+
+	// Only an address the provider has verified may claim an account.
+	if identity.Email == "" || !identity.EmailVerified {
+		return uuid.Nil, errNoVerifiedEmail
+	}
+
+	tx, err := cfg.DBConn.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	var userID uuid.UUID
+	user, err := qtx.GetUserByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		verified, err := hasVerifiedEmail(ctx, qtx, user)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if !verified {
+			return uuid.Nil, errLinkRequired
+		}
+		userID = user.ID
+	case err == sql.ErrNoRows:
+		username, err := pickUsername(ctx, qtx, identity)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		created, err := qtx.CreateUser(ctx, database.CreateUserParams{
+			Username:       username,
+			Email:          identity.Email,
+			HashedPassword: noPassword,
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+		})
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if err := qtx.CreateUserPreferences(ctx, created.ID); err != nil {
+			return uuid.Nil, err
+		}
+		userID = created.ID
+	default:
+		return uuid.Nil, err
+	}
+
+	_, err = qtx.CreateIdentity(ctx, database.CreateIdentityParams{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, tx.Commit()
+}
+
+// pickUsername finds a free username for a new account, starting from the
+// provider's preferred username or the email's local part.
+func pickUsername(ctx context.Context, db store.Repository, identity sso.Identity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = strings.Trim(usernameDisallowed.ReplaceAllString(base, "_"), "_")
+	if len(base) > 24 {
+		base = base[:24]
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	candidate := base
+	for range 10 {
+		available, err := db.IsUsernameAvailable(ctx, database.IsUsernameAvailableParams{
+			Username: candidate,
+			UserID:   uuid.Nil,
+		})
+		if err != nil {
+			return "", err
+		}
+		if available {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s_%04d", base, rand.IntN(10000))
+	}
+	return "", fmt.Errorf("no free username near %q", base)
+}
+
+// HandlerGetIdentities lists the provider accounts linked to the user.
+func (cfg *Config) HandlerGetIdentities(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListIdentities(r.Context(), userID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get linked identities", err)
+		return
+	}
+
+	identities := make([]LinkedIdentity, 0, len(rows))
+	for _, row := range rows {
+		identities = append(identities, linkedIdentityFromRow(row))
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, identities)
+}
+
+// HandlerBeginLinkIdentity starts linking a provider account to the
+// signed-in user. It answers like HandlerBeginOIDCLogin, and the provider's
+// redirect is finished with HandlerFinishLinkIdentity.
+func (cfg *Config) HandlerBeginLinkIdentity(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	cfg.beginOIDC(w, r, uuid.NullUUID{UUID: userID, Valid: true})
+}
+
+// HandlerFinishLinkIdentity links the provider account that signed in to
+// the user who started the link. The provider's email doesn't have to match
+// the user's, since the user has proved they hold both accounts.
+func (cfg *Config) HandlerFinishLinkIdentity(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		State string `json:"state"`
+		Code  string `json:"code"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	name := chi.URLParam(r, "provider")
+	provider, ok := cfg.sso[name]
+	if !ok {
+		helpers.RespondWithError(w, http.StatusNotFound, "Unknown identity provider", nil)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	pending, err := cfg.takeOIDCLogin(r.Context(), name, params.State, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		if err == errOIDCLoginExpired {
+			helpers.RespondWithError(w, http.StatusBadRequest, "Link expired, start again", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't link identity", err)
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), params.Code, sso.Login{
+		State:    params.State,
+		Nonce:    pending.Nonce,
+		Verifier: pending.CodeVerifier,
+	})
+	if err != nil {
+		if errors.Is(err, sso.ErrInvalidLogin) {
+			helpers.RespondWithError(w, http.StatusUnauthorized, "Couldn't verify the sign-in with the identity provider", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusBadGateway, "Couldn't reach the identity provider", err)
+		return
+	}
+
+	existing, err := cfg.DB.GetIdentity(r.Context(), database.GetIdentityParams{
+		Provider: name,
+		Subject:  identity.Subject,
+	})
+	if err == nil {
+		if existing.UserID != userID {
+			helpers.RespondWithError(w, http.StatusConflict, "This identity is linked to another account", nil)
+			return
+		}
+		helpers.RespondWithJSON(w, http.StatusOK, linkedIdentityFromRow(existing))
+		return
+	}
+	if err != sql.ErrNoRows {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't link identity", err)
+		return
+	}
+
+	row, err := cfg.DB.CreateIdentity(r.Context(), database.CreateIdentityParams{
+		UserID:   userID,
+		Provider: name,
+		Subject:  identity.Subject,
+		Email:    verifiedEmail(identity),
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't link identity", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusCreated, linkedIdentityFromRow(row))
+}
@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/sso"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// stubProvider is an OpenID Connect provider serving discovery, its JWKS
+// and a token endpoint. Tests sign users in by handing out codes with
+// authorize instead of going through a login page.
+type stubProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]stubGrant
+}
+
+// stubGrant is what the provider remembers about a code.
+type stubGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+	// key signs the ID token.
+	key *rsa.PrivateKey
+}
+
+// stubUser is who signs in at the provider.
+type stubUser struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+const stubClientID = "tanglr"
+
+func newStubProvider(t *testing.T) *stubProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &stubProvider{t: t, key: key, grants: map[string]stubGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "stub",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", p.handleToken)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// handleToken trades a code for an ID token, checking the PKCE verifier
+// against the challenge the code was issued for.
+func (p *stubProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	grant, ok := p.grants[r.FormValue("code")]
+	delete(p.grants, r.FormValue("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+	token.Header["kid"] = "stub"
+	idToken, err := token.SignedString(grant.key)
+	if err != nil {
+		p.t.Error(err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "stub-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// authorize signs user in at the authorization URL a begin handler gave
+// out and returns the code the provider would redirect back with. modify,
+// if given, can tamper with the grant before it's stored.
+func (p *stubProvider) authorize(authorizationURL string, user stubUser, modify ...func(*stubGrant)) string {
+	p.t.Helper()
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("client_id") != stubClientID || query.Get("code_challenge_method") != "S256" {
+		p.t.Fatalf("authorization URL %s", authorizationURL)
+	}
+
+	now := time.Now()
+	grant := stubGrant{
+		key:       p.key,
+		challenge: query.Get("code_challenge"),
+		claims: jwt.MapClaims{
+			"iss":                p.server.URL,
+			"aud":                stubClientID,
+			"sub":                user.Subject,
+			"iat":                now.Unix(),
+			"exp":                now.Add(time.Hour).Unix(),
+			"nonce":              query.Get("nonce"),
+			"email":              user.Email,
+			"email_verified":     user.EmailVerified,
+			"preferred_username": user.PreferredUsername,
+		},
+	}
+	for _, f := range modify {
+		f(&grant)
+	}
+
+	code := rand.Text()
+	p.mu.Lock()
+	p.grants[code] = grant
+	p.mu.Unlock()
+	return code
+}
+
+// withStubProvider configures p as the "stub" identity provider.
+func withStubProvider(p *stubProvider) func(*config.Config) {
+	return func(c *config.Config) {
+		c.OIDC.Providers = map[string]config.OIDCProvider{
+			"stub": {
+				Issuer:       p.server.URL,
+				ClientID:     stubClientID,
+				ClientSecret: "stub-secret",
+				RedirectURL:  "http://localhost:3000/auth/oidc/stub/callback",
+			},
+		}
+	}
+}
+
+func beginOIDCLogin(t *testing.T, cfg *Config) OIDCLoginResponse {
+	t.Helper()
+	r := withURLParams(newRequest(t, http.MethodPost, "/v1/login/oidc/stub/begin", nil), "provider", "stub")
+	rec := serve(cfg.HandlerBeginOIDCLogin, r)
+	expectStatus(t, rec, http.StatusOK)
+	return decodeResponse[OIDCLoginResponse](t, rec)
+}
+
+func finishOIDCLogin(t *testing.T, cfg *Config, state, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := withURLParams(newRequest(t, http.MethodPost, "/v1/login/oidc/stub/finish", map[string]string{
+		"state": state,
+		"code":  code,
+	}), "provider", "stub")
+	return serve(cfg.HandlerFinishOIDCLogin, r)
+}
+
+// oidcLogin signs user in through the provider from start to finish.
+func oidcLogin(t *testing.T, cfg *Config, p *stubProvider, user stubUser) *httptest.ResponseRecorder {
+	t.Helper()
+	begun := beginOIDCLogin(t, cfg)
+	return finishOIDCLogin(t, cfg, begun.State, p.authorize(begun.AuthorizationURL, user))
+}
+
+// linkIdentity links the provider account user signs in with to owner.
+func linkIdentity(t *testing.T, cfg *Config, p *stubProvider, owner testUser, user stubUser) *httptest.ResponseRecorder {
+	t.Helper()
+	r := withURLParams(withToken(newRequest(t, http.MethodPost, "/v1/users/me/identities/stub/begin", nil), owner.Token), "provider", "stub")
+	rec := serve(cfg.HandlerBeginLinkIdentity, r)
+	expectStatus(t, rec, http.StatusOK)
+	begun := decodeResponse[OIDCLoginResponse](t, rec)
+
+	r = withURLParams(withToken(newRequest(t, http.MethodPost, "/v1/users/me/identities/stub/finish", map[string]string{
+		"state": begun.State,
+		"code":  p.authorize(begun.AuthorizationURL, user),
+	}), owner.Token), "provider", "stub")
+	return serve(cfg.HandlerFinishLinkIdentity, r)
+}
+
+func getIdentities(t *testing.T, cfg *Config, user testUser) []LinkedIdentity {
+	t.Helper()
+	rec := serve(cfg.HandlerGetIdentities, withToken(newRequest(t, http.MethodGet, "/v1/users/me/identities", nil), user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	return decodeResponse[[]LinkedIdentity](t, rec)
+}
+
+var carol = stubUser{Subject: "carol-1", Email: "carol@example.com", EmailVerified: true, PreferredUsername: "carol"}
+
+func TestOIDCLoginCreatesAccount(t *testing.T) {
+	p := newStubProvider(t)
+	cfg := newTestConfig(t, withStubProvider(p))
+
+	rec := oidcLogin(t, cfg, p, carol)
+	expectStatus(t, rec, http.StatusOK)
+	first := decodeResponse[loginResponse](t, rec)
+	if first.Username != "carol" || first.Token == "" {
+		t.Fatalf("first OIDC login = %+v", first)
+	}
+
+	rec = oidcLogin(t, cfg, p, carol)
+	expectStatus(t, rec, http.StatusOK)
+	if again := decodeResponse[loginResponse](t, rec); again.ID != first.ID {
+		t.Errorf("second OIDC login signed in %s, want %s", again.ID, first.ID)
+	}
+
+	identities := getIdentities(t, cfg, testUser{Token: first.Token})
+	if len(identities) != 1 || identities[0].Provider != "stub" || identities[0].Email != carol.Email {
+		t.Errorf("identities = %+v", identities)
+	}
+
+	// The account has no password to log in with, whatever is tried.
+	expectStatus(t, login(t, cfg, "carol", noPassword), http.StatusUnauthorized)
+	expectStatus(t, login(t, cfg, "carol", ""), http.StatusUnauthorized)
+}
+
+func TestOIDCStateMismatch(t *testing.T) {
+	p := newStubProvider(t)
+	cfg := newTestConfig(t, withStubProvider(p))
+
+	begun := beginOIDCLogin(t, cfg)
+	code := p.authorize(begun.AuthorizationURL, carol)
+	expectStatus(t, finishOIDCLogin(t, cfg, "some-other-state", code), http.StatusBadRequest)
+
+	// Each state finishes one login.
+	expectStatus(t, finishOIDCLogin(t, cfg, begun.State, code), http.StatusOK)
+	code = p.authorize(begun.AuthorizationURL, carol)
+	expectStatus(t, finishOIDCLogin(t, cfg, begun.State, code), http.StatusBadRequest)
+}
+
+func TestOIDCPKCEMismatch(t *testing.T) {
+	p := newStubProvider(t)
+	cfg := newTestConfig(t, withStubProvider(p))
+
+	begun := beginOIDCLogin(t, cfg)
+	code := p.authorize(begun.AuthorizationURL, carol, func(g *stubGrant) {
+		g.challenge = "challenge-from-another-login"
+	})
+	expectStatus(t, finishOIDCLogin(t, cfg, begun.State, code), http.StatusUnauthorized)
+}
+
+func TestOIDCNonceMismatch(t *testing.T) {
+	p := newStubProvider(t)
+	cfg := newTestConfig(t, withStubProvider(p))
+
+	begun := beginOIDCLogin(t, cfg)
+	code := p.authorize(begun.AuthorizationURL, carol, func(g *stubGrant) {
+		g.claims["nonce"] = "nonce-from-another-login"
+	})
+	expectStatus(t, finishOIDCLogin(t, cfg, begun.State, code), http.StatusUnauthorized)
+}
+
+func TestOIDCWrongSignerRejected(t *testing.T) {
+	p := newStubProvider(t)
+	cfg := newTestConfig(t, withStubProvider(p))
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	begun := beginOIDCLogin(t, cfg)
+	code := p.authorize(begun.AuthorizationURL, carol, func(g *stubGrant) {
+		g.key = other
+	})
+	expectStatus(t, finishOIDCLogin(t, cfg, begun.State, code), http.StatusUnauthorized)
+}
+
+func TestOIDCUnverifiedEmail(t *testing.T) {
+	p := newStubProvider(t)
+	cfg := newTestConfig(t, withStubProvider(p))
+
+	unverified := carol
+	unverified.EmailVerified = false
+	expectStatus(t, oidcLogin(t, cfg, p, unverified), http.StatusForbidden)
+}
+
+// A provider account with the email of an existing password account must
+// not take it over unless the account's email is verified.
+func TestOIDCLoginDoesNotLinkUnverifiedAccount(t *testing.T) {
+	p := newStubProvider(t)
+	cfg := newTestConfig(t, withStubProvider(p))
+	alice := createTestUser(t, cfg, "alice")
+	aliceAtProvider := stubUser{Subject: "alice-1", Email: alice.Email, EmailVerified: true}
+
+	expectStatus(t, oidcLogin(t, cfg, p, aliceAtProvider), http.StatusConflict)
+	if got := getIdentities(t, cfg, alice); len(got) != 0 {
+		t.Fatalf("identities after a refused login = %+v", got)
+	}
+
+	// Signed in, alice can link the provider account herself.
+	rec := linkIdentity(t, cfg, p, alice, aliceAtProvider)
+	expectStatus(t, rec, http.StatusCreated)
+	if got := decodeResponse[LinkedIdentity](t, rec); got.Provider != "stub" || got.Email != alice.Email {
+		t.Errorf("linked identity = %+v", got)
+	}
+	rec = oidcLogin(t, cfg, p, aliceAtProvider)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[loginResponse](t, rec); got.ID != alice.ID {
+		t.Errorf("OIDC login after linking signed in %s, want alice", got.ID)
+	}
+
+	// Now that a provider has vouched for her email, another identity with
+	// it is linked on sight.
+	rec = oidcLogin(t, cfg, p, stubUser{Subject: "alice-2", Email: alice.Email, EmailVerified: true})
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[loginResponse](t, rec); got.ID != alice.ID {
+		t.Errorf("second identity signed in %s, want alice", got.ID)
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	p := newStubProvider(t)
+	cfg := newTestConfig(t, withStubProvider(p))
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	// An unverified provider email can still be linked, but isn't kept.
+	unverified := stubUser{Subject: "alice-1", Email: "old-alice@example.com"}
+	rec := linkIdentity(t, cfg, p, alice, unverified)
+	expectStatus(t, rec, http.StatusCreated)
+	if got := decodeResponse[LinkedIdentity](t, rec); got.Email != "" {
+		t.Errorf("linked identity kept unverified email %q", got.Email)
+	}
+
+	expectStatus(t, linkIdentity(t, cfg, p, alice, unverified), http.StatusOK)
+	expectStatus(t, linkIdentity(t, cfg, p, bob, unverified), http.StatusConflict)
+	if got := getIdentities(t, cfg, bob); len(got) != 0 {
+		t.Errorf("bob's identities = %+v", got)
+	}
+}
+
+func TestLinkStateBelongsToItsUser(t *testing.T) {
+	p := newStubProvider(t)
+	cfg := newTestConfig(t, withStubProvider(p))
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	r := withURLParams(withToken(newRequest(t, http.MethodPost, "/v1/users/me/identities/stub/begin", nil), alice.Token), "provider", "stub")
+	begun := decodeResponse[OIDCLoginResponse](t, serve(cfg.HandlerBeginLinkIdentity, r))
+	code := p.authorize(begun.AuthorizationURL, carol)
+
+	// Bob can't finish alice's link, and a link can't be finished as a
+	// login. Both use the state up.
+	r = withURLParams(withToken(newRequest(t, http.MethodPost, "/v1/users/me/identities/stub/finish", map[string]string{
+		"state": begun.State,
+		"code":  code,
+	}), bob.Token), "provider", "stub")
+	expectStatus(t, serve(cfg.HandlerFinishLinkIdentity, r), http.StatusBadRequest)
+
+	r = withURLParams(withToken(newRequest(t, http.MethodPost, "/v1/users/me/identities/stub/begin", nil), alice.Token), "provider", "stub")
+	begun = decodeResponse[OIDCLoginResponse](t, serve(cfg.HandlerBeginLinkIdentity, r))
+	expectStatus(t, finishOIDCLogin(t, cfg, begun.State, p.authorize(begun.AuthorizationURL, carol)), http.StatusBadRequest)
+}
+
+func TestOIDCUsernameCollision(t *testing.T) {
+	p := newStubProvider(t)
+	cfg := newTestConfig(t, withStubProvider(p))
+	createTestUser(t, cfg, "carol")
+
+	rec := oidcLogin(t, cfg, p, stubUser{Subject: "carol-2", Email: "carol2@example.com", EmailVerified: true, PreferredUsername: "carol"})
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[loginResponse](t, rec); !regexp.MustCompile(`^carol_\d{4}$`).MatchString(got.Username) {
+		t.Errorf("username = %q, want carol_NNNN", got.Username)
+	}
+}
+
+func TestPickUsername(t *testing.T) {
+	cfg := newTestConfig(t)
+	createTestUser(t, cfg, "taken")
+
+	tests := []struct {
+		identity sso.Identity
+		want     string
+	}{
+		{sso.Identity{Username: "dana"}, "dana"},
+		{sso.Identity{Email: "erin.smith@example.com"}, "erin_smith"},
+		{sso.Identity{Username: "  Émile-Zola "}, "mile_Zola"},
+		{sso.Identity{Username: "x"}, "user"},
+		{sso.Identity{Username: "a_very_long_preferred_username_indeed"}, "a_very_long_preferred_us"},
+	}
+	for _, tt := range tests {
+		got, err := pickUsername(context.Background(), cfg.DB, tt.identity)
+		if err != nil || got != tt.want {
+			t.Errorf("pickUsername(%+v) = %q, %v; want %q", tt.identity, got, err, tt.want)
+		}
+	}
+
+	got, err := pickUsername(context.Background(), cfg.DB, sso.Identity{Username: "taken"})
+	if err != nil || !regexp.MustCompile(`^taken_\d{4}$`).MatchString(got) {
+		t.Errorf("pickUsername(taken) = %q, %v; want taken_NNNN", got, err)
+	}
+}
@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/internal/logging"
+	"github.com/artyultra/tanglr/internal/metrics"
+	"github.com/go-chi/chi"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// PasskeyCeremonyResponse starts a registration or login. Options is passed
+// as is to navigator.credentials.create() or .get(), and SessionID is sent
+// back with the authenticator's answer.
+type PasskeyCeremonyResponse struct {
+	SessionID uuid.UUID `json:"session_id"`
+	Options   any       `json:"options"`
+}
+
+type Passkey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	BackedUp   bool       `json:"backed_up"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func passkeyFromRow(row database.WebauthnCredential) Passkey {
+	passkey := Passkey{
+		ID:         row.ID,
+		Name:       row.Name,
+		Transports: []string{},
+		BackedUp:   row.BackupState,
+		CreatedAt:  row.CreatedAt,
+	}
+	if row.Transports != "" {
+		passkey.Transports = strings.Split(row.Transports, ",")
+	}
+	if row.LastUsedAt.Valid {
+		passkey.LastUsedAt = &row.LastUsedAt.Time
+	}
+	return passkey
+}
+
+// HandlerBeginPasskeyRegistration starts registering a passkey for the
+// signed-in user. Passkeys they already have are excluded, so the same
+// authenticator can't be registered twice.
+func (cfg *Config) HandlerBeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	user, err := cfg.loadPasskeyUser(r.Context(), userID, claims.Username)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't start registration", err)
+		return
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.credentials))
+	for _, credential := range user.credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := cfg.passkeys.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't start registration", err)
+		return
+	}
+
+	sessionID, err := cfg.saveCeremony(r.Context(), ceremonyRegistration, uuid.NullUUID{UUID: userID, Valid: true}, session)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't start registration", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, PasskeyCeremonyResponse{
+		SessionID: sessionID,
+		Options:   creation,
+	})
+}
+
+// HandlerFinishPasskeyRegistration checks the authenticator's attestation
+// against the registration's challenge and stores the new passkey.
+func (cfg *Config) HandlerFinishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		SessionID  uuid.UUID                           `json:"session_id"`
+		Name       string                              `json:"name"`
+		Credential protocol.CredentialCreationResponse `json:"credential"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+	if utf8.RuneCountInString(name) > maxPasskeyNameLength {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Passkey names can be at most 64 characters", nil)
+		return
+	}
+
+	session, owner, err := cfg.takeCeremony(r.Context(), ceremonyRegistration, params.SessionID)
+	if err != nil && err != errCeremonyNotFound {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't register passkey", err)
+		return
+	}
+	if err == errCeremonyNotFound || owner.UUID != userID {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Registration expired, start again", err)
+		return
+	}
+
+	parsed, err := params.Credential.Parse()
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid passkey", err)
+		return
+	}
+
+	user, err := cfg.loadPasskeyUser(r.Context(), userID, claims.Username)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't register passkey", err)
+		return
+	}
+
+	credential, err := cfg.passkeys.CreateCredential(user, session, parsed)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid passkey", err)
+		return
+	}
+
+	// Browsers honour the exclusion list, but nothing makes a client send it.
+	for _, existing := range user.credentials {
+		if bytes.Equal(existing.ID, credential.ID) {
+			helpers.RespondWithError(w, http.StatusConflict, "This passkey is already registered", nil)
+			return
+		}
+	}
+
+	row, err := cfg.DB.CreateWebAuthnCredential(r.Context(), database.CreateWebAuthnCredentialParams{
+		UserID:          userID,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Aaguid:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		Transports:      joinTransports(credential.Transport),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            name,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't register passkey", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusCreated, passkeyFromRow(row))
+}
+
+func (cfg *Config) HandlerGetPasskeys(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListWebAuthnCredentials(r.Context(), userID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get passkeys", err)
+		return
+	}
+
+	passkeys := make([]Passkey, 0, len(rows))
+	for _, row := range rows {
+		passkeys = append(passkeys, passkeyFromRow(row))
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, passkeys)
+}
+
+func (cfg *Config) HandlerDeletePasskey(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	passkeyID, err := uuid.Parse(chi.URLParam(r, "passkeyID"))
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid passkey ID", err)
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteWebAuthnCredential(r.Context(), database.DeleteWebAuthnCredentialParams{
+		ID:     passkeyID,
+		UserID: userID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't delete passkey", err)
+		return
+	}
+	if deleted == 0 {
+		helpers.RespondWithError(w, http.StatusNotFound, "Passkey not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandlerBeginPasskeyLogin starts a passwordless login. No username is
+// needed: the browser offers whichever passkeys it holds for this site.
+func (cfg *Config) HandlerBeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	assertion, session, err := cfg.passkeys.BeginDiscoverableLogin()
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't start login", err)
+		return
+	}
+
+	sessionID, err := cfg.saveCeremony(r.Context(), ceremonyLogin, uuid.NullUUID{}, session)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't start login", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, PasskeyCeremonyResponse{
+		SessionID: sessionID,
+		Options:   assertion,
+	})
+}
+
+// HandlerFinishPasskeyLogin checks the passkey's signature and issues the
+// same tokens as a password login. A passkey needs user verification, so it
+// counts as both factors and skips TOTP, and it still works while password
+// failures have the account locked.
+func (cfg *Config) HandlerFinishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		SessionID  uuid.UUID                            `json:"session_id"`
+		Credential protocol.CredentialAssertionResponse `json:"credential"`
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	src := cfg.loginSource(r)
+
+	session, _, err := cfg.takeCeremony(r.Context(), ceremonyLogin, params.SessionID)
+	if err != nil {
+		if err == errCeremonyNotFound {
+			helpers.RespondWithError(w, http.StatusBadRequest, "Login expired, start again", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+
+	parsed, err := params.Credential.Parse()
+	if err != nil {
+		metrics.Logins.WithLabelValues("invalid_passkey").Inc()
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid passkey", err)
+		return
+	}
+
+	// The user handle names the account; remember it and any database error
+	// so they aren't lost in the library's error.
+	var user database.GetUserByUsernameRow
+	var lookupErr error
+	findUser := func(_, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		dbUser, err := cfg.DB.GetUserById(r.Context(), database.GetUserByIdParams{ID: userID})
+		if err != nil {
+			if err != sql.ErrNoRows {
+				lookupErr = err
+			}
+			return nil, err
+		}
+		user = database.GetUserByUsernameRow(dbUser)
+		passkeyUser, err := cfg.loadPasskeyUser(r.Context(), user.UserID, user.Username)
+		if err != nil {
+			lookupErr = err
+			return nil, err
+		}
+		return passkeyUser, nil
+	}
+
+	credential, err := cfg.passkeys.ValidateDiscoverableLogin(findUser, session, parsed)
+	if lookupErr != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", lookupErr)
+		return
+	}
+	if err != nil {
+		if user.UserID != uuid.Nil {
+			cfg.recordLoginAttempt(r.Context(), src, user.Username, user.UserID, loginBadPasskey, false)
+		}
+		metrics.Logins.WithLabelValues("invalid_passkey").Inc()
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid passkey", err)
+		return
+	}
+
+	// A signature counter that didn't go up means two authenticators may
+	// hold the same private key.
+	if credential.Authenticator.CloneWarning {
+		logging.FromContext(r.Context()).Warn("passkey signature counter went backwards", "user_id", user.UserID, "sign_count", parsed.Response.AuthenticatorData.Counter)
+		cfg.recordLoginAttempt(r.Context(), src, user.Username, user.UserID, loginClonedPasskey, false)
+		metrics.Logins.WithLabelValues("invalid_passkey").Inc()
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid passkey", nil)
+		return
+	}
+
+	err = cfg.DB.UpdateWebAuthnCredentialUse(r.Context(), database.UpdateWebAuthnCredentialUseParams{
+		CredentialID: credential.ID,
+		SignCount:    int64(credential.Authenticator.SignCount),
+		BackupState:  credential.Flags.BackupState,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't log in", err)
+		return
+	}
+
+	if isSuspended(user.SuspendedAt, user.SuspendedUntil) {
+		cfg.recordLoginAttempt(r.Context(), src, user.Username, user.UserID, loginSuspended, false)
+		metrics.Logins.WithLabelValues("suspended").Inc()
+		helpers.RespondWithJSON(w, http.StatusForbidden, suspendedResponse(user.SuspensionReason, user.SuspendedUntil))
+		return
+	}
+
+	cfg.completeLogin(w, r, user, src)
+}
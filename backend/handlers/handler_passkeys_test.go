@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/google/uuid"
+)
+
+const testOrigin = "http://localhost:3000"
+
+// softAuthenticator is a passkey held in memory. It answers registrations
+// and logins the way a platform authenticator would, with "none"
+// attestation and user verification.
+type softAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &softAuthenticator{t: t, key: key, credentialID: id}
+}
+
+// Authenticator data flags.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+func (a *softAuthenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte("localhost"))
+	data := append(rpIDHash[:], flags|flagUserPresent|flagUserVerified)
+	return binary.BigEndian.AppendUint32(data, a.signCount)
+}
+
+func clientData(t *testing.T, ceremony, challenge string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// create answers navigator.credentials.create() for the challenge.
+func (a *softAuthenticator) create(challenge string, userHandle []byte) map[string]any {
+	a.t.Helper()
+	a.userHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	authData := a.authenticatorData(flagAttested)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return map[string]any{
+		"id":    b64(a.credentialID),
+		"rawId": b64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64(clientData(a.t, "webauthn.create", challenge)),
+			"attestationObject": b64(attestation),
+			"transports":        []string{"internal"},
+		},
+	}
+}
+
+// get answers navigator.credentials.get() for the challenge, signing with
+// the given counter.
+func (a *softAuthenticator) get(challenge string, signCount uint32) map[string]any {
+	a.t.Helper()
+	a.signCount = signCount
+
+	authData := a.authenticatorData(0)
+	clientDataJSON := clientData(a.t, "webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return map[string]any{
+		"id":    b64(a.credentialID),
+		"rawId": b64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64(clientDataJSON),
+			"authenticatorData": b64(authData),
+			"signature":         b64(signature),
+			"userHandle":        b64(a.userHandle),
+		},
+	}
+}
+
+// ceremony is the part of a begin response the authenticator needs.
+type ceremony struct {
+	SessionID uuid.UUID `json:"session_id"`
+	Options   struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+		} `json:"publicKey"`
+	} `json:"options"`
+}
+
+func registerPasskey(t *testing.T, cfg *Config, user testUser, authenticator *softAuthenticator) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := serve(cfg.HandlerBeginPasskeyRegistration, withToken(newRequest(t, http.MethodPost, "/v1/users/me/passkeys/register/begin", nil), user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	begun := decodeResponse[ceremony](t, rec)
+
+	r := withToken(newRequest(t, http.MethodPost, "/v1/users/me/passkeys/register/finish", map[string]any{
+		"session_id": begun.SessionID,
+		"name":       "Laptop",
+		"credential": authenticator.create(begun.Options.PublicKey.Challenge, user.ID[:]),
+	}), user.Token)
+	return serve(cfg.HandlerFinishPasskeyRegistration, r)
+}
+
+func passkeyLogin(t *testing.T, cfg *Config, authenticator *softAuthenticator, signCount uint32) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := serve(cfg.HandlerBeginPasskeyLogin, newRequest(t, http.MethodPost, "/v1/login/passkey/begin", nil))
+	expectStatus(t, rec, http.StatusOK)
+	begun := decodeResponse[ceremony](t, rec)
+
+	return serve(cfg.HandlerFinishPasskeyLogin, newRequest(t, http.MethodPost, "/v1/login/passkey/finish", map[string]any{
+		"session_id": begun.SessionID,
+		"credential": authenticator.get(begun.Options.PublicKey.Challenge, signCount),
+	}))
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	authenticator := newSoftAuthenticator(t)
+
+	rec := registerPasskey(t, cfg, alice, authenticator)
+	expectStatus(t, rec, http.StatusCreated)
+	if got := decodeResponse[Passkey](t, rec); got.Name != "Laptop" || len(got.Transports) != 1 || got.Transports[0] != "internal" {
+		t.Errorf("registered passkey = %+v", got)
+	}
+
+	rec = passkeyLogin(t, cfg, authenticator, 1)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[loginResponse](t, rec); got.ID != alice.ID || got.Token == "" {
+		t.Errorf("passkey login response = %+v", got)
+	}
+
+	rec = serve(cfg.HandlerGetPasskeys, withToken(newRequest(t, http.MethodGet, "/v1/users/me/passkeys", nil), alice.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[[]Passkey](t, rec); len(got) != 1 || got[0].LastUsedAt == nil {
+		t.Errorf("passkeys after login = %+v", got)
+	}
+
+	// The same authenticator can't be registered twice.
+	expectStatus(t, registerPasskey(t, cfg, alice, authenticator), http.StatusConflict)
+}
+
+func TestPasskeyChallengeIsSingleUse(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	authenticator := newSoftAuthenticator(t)
+	expectStatus(t, registerPasskey(t, cfg, alice, authenticator), http.StatusCreated)
+
+	rec := serve(cfg.HandlerBeginPasskeyLogin, newRequest(t, http.MethodPost, "/v1/login/passkey/begin", nil))
+	begun := decodeResponse[ceremony](t, rec)
+	finish := func(challenge string, signCount uint32) *httptest.ResponseRecorder {
+		return serve(cfg.HandlerFinishPasskeyLogin, newRequest(t, http.MethodPost, "/v1/login/passkey/finish", map[string]any{
+			"session_id": begun.SessionID,
+			"credential": authenticator.get(challenge, signCount),
+		}))
+	}
+
+	expectStatus(t, finish(b64([]byte("some other challenge")), 1), http.StatusUnauthorized)
+	// A failed answer still uses the challenge up.
+	expectStatus(t, finish(begun.Options.PublicKey.Challenge, 2), http.StatusBadRequest)
+}
+
+func TestPasskeySignCountRegression(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	authenticator := newSoftAuthenticator(t)
+	expectStatus(t, registerPasskey(t, cfg, alice, authenticator), http.StatusCreated)
+
+	expectStatus(t, passkeyLogin(t, cfg, authenticator, 5), http.StatusOK)
+
+	// A counter that didn't go up means the key may have been copied.
+	expectStatus(t, passkeyLogin(t, cfg, authenticator, 5), http.StatusUnauthorized)
+	expectStatus(t, passkeyLogin(t, cfg, authenticator, 3), http.StatusUnauthorized)
+
+	var cloned int
+	err := cfg.DBConn.QueryRow("SELECT count(*) FROM login_attempts WHERE failure_reason = ?", loginClonedPasskey).Scan(&cloned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cloned != 2 {
+		t.Errorf("%d attempts recorded as cloned, want 2", cloned)
+	}
+}
+
+func TestPasskeyLoginRefusesSuspendedUser(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	authenticator := newSoftAuthenticator(t)
+	expectStatus(t, registerPasskey(t, cfg, alice, authenticator), http.StatusCreated)
+
+	_, err := cfg.DBConn.Exec("UPDATE users SET suspended_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'), suspension_reason = 'spam' WHERE id = ?", alice.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := passkeyLogin(t, cfg, authenticator, 1)
+	expectStatus(t, rec, http.StatusForbidden)
+	if got := decodeResponse[SuspendedResponse](t, rec); got.Reason != "spam" || !got.Permanent {
+		t.Errorf("suspended response = %+v", got)
+	}
+}
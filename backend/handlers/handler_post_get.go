@@ -7,18 +7,20 @@ import (
 
 	"github.com/artyultra/tanglr/handlers/helpers"
 	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 )
 
 type Post struct {
-	ID        uuid.UUID `json:"id"`
-	Body      string    `json:"body"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	UserID    uuid.UUID `json:"user_id"`
-	Username  string    `json:"username"`
-	AvatarURL string    `json:"avatar_url"`
+	ID          uuid.UUID `json:"id"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UserID      uuid.UUID `json:"user_id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
 }
 
 func (cfg *Config) HandlerGetAllUserPosts(w http.ResponseWriter, r *http.Request) {
@@ -28,34 +30,58 @@ func (cfg *Config) HandlerGetAllUserPosts(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	dbPosts, err := cfg.DB.GetPostsByUsername(r.Context(), username)
+	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get posts", err)
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
 	if err != nil {
 		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
 		return
 	}
 
-	_, err = auth.ValidateJWT(token, cfg.jwtSecret)
+	viewerID, err := uuid.Parse(claims.Subject)
 	if err != nil {
 		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
 		return
 	}
 
+	dbPosts, err := cfg.reader(viewerID).GetPostsByUsername(r.Context(), database.GetPostsByUsernameParams{
+		Username: username,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get posts", err)
+		return
+	}
+
+	// A held name belongs to nobody, so it has no posts; follow it to the
+	// account that gave it up, as the profile route does.
+	if len(dbPosts) == 0 {
+		newUsername, err := cfg.DB.GetUsernameRedirect(r.Context(), username)
+		if err == nil {
+			redirectRenamed(w, "/v1/posts/", newUsername)
+			return
+		}
+		if err != sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get posts", err)
+			return
+		}
+	}
+
 	posts := []Post{}
 	for _, post := range dbPosts {
 		posts = append(posts, Post{
-			ID:        post.ID,
-			Body:      post.Body,
-			CreatedAt: post.CreatedAt,
-			UpdatedAt: post.UpdatedAt,
-			UserID:    post.UserID,
-			Username:  post.Username,
-			AvatarURL: post.UserAvatarUrl.String,
+			ID:          post.ID,
+			Body:        post.Body,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			UserID:      post.UserID,
+			Username:    post.Username,
+			DisplayName: post.DisplayName,
+			AvatarURL:   post.UserAvatarUrl.String,
 		})
 	}
 
@@ -69,13 +95,19 @@ func (cfg *Config) HandlerGetAllPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = auth.ValidateJWT(token, cfg.jwtSecret)
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	viewerID, err := uuid.Parse(claims.Subject)
 	if err != nil {
 		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
 		return
 	}
 
-	dbPosts, err := cfg.DB.GetPosts(r.Context())
+	dbPosts, err := cfg.reader(viewerID).GetPosts(r.Context(), viewerID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			helpers.RespondWithJSON(w, http.StatusOK, []Post{})
@@ -87,13 +119,14 @@ func (cfg *Config) HandlerGetAllPosts(w http.ResponseWriter, r *http.Request) {
 	var posts []Post
 	for _, post := range dbPosts {
 		posts = append(posts, Post{
-			ID:        post.ID,
-			Body:      post.Body,
-			CreatedAt: post.CreatedAt,
-			UpdatedAt: post.UpdatedAt,
-			UserID:    post.UserID,
-			Username:  post.Username,
-			AvatarURL: post.AvatarUrl.String,
+			ID:          post.ID,
+			Body:        post.Body,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			UserID:      post.UserID,
+			Username:    post.Username,
+			DisplayName: post.DisplayName,
+			AvatarURL:   post.AvatarUrl.String,
 		})
 	}
 
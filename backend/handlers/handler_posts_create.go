@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"time"
 
@@ -21,7 +20,7 @@ func (cfg *Config) HandlerCreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	claims, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
 	if err != nil {
 		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
 		return
@@ -34,11 +33,8 @@ func (cfg *Config) HandlerCreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := paramaters{}
-	err = decoder.Decode(&params)
-	if err != nil {
-		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid request", err)
+	if !cfg.decodeJSON(w, r, &params) {
 		return
 	}
 
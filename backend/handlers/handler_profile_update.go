@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/google/uuid"
+)
+
+// Limits for the extended profile fields. The text limits are also enforced by
+// CHECK constraints on the users table; the number of links is configurable.
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxLocationLength    = 30
+	maxProfileLinkLength = 200
+)
+
+type ProfileLink struct {
+	URL      string `json:"url"`
+	Verified bool   `json:"verified"`
+}
+
+type UpdateProfileResponse struct {
+	DisplayName string        `json:"display_name"`
+	Bio         string        `json:"bio"`
+	Location    string        `json:"location"`
+	Links       []ProfileLink `json:"links"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// profileLinks converts stored links for display. Other users only see links
+// that were verified.
+func profileLinks(dbLinks []database.ProfileLink, verifiedOnly bool) []ProfileLink {
+	links := []ProfileLink{}
+	for _, link := range dbLinks {
+		if verifiedOnly && !link.VerifiedAt.Valid {
+			continue
+		}
+		links = append(links, ProfileLink{
+			URL:      link.Url,
+			Verified: link.VerifiedAt.Valid,
+		})
+	}
+	return links
+}
+
+// normalizeProfileLinks validates the submitted links and drops duplicates.
+// It returns a user-facing message when a link is rejected.
+func normalizeProfileLinks(rawLinks []string, maxLinks int) ([]string, string) {
+	if len(rawLinks) > maxLinks {
+		return nil, "Too many links"
+	}
+
+	links := []string{}
+	seen := map[string]bool{}
+	for _, raw := range rawLinks {
+		raw = strings.TrimSpace(raw)
+		if len(raw) > maxProfileLinkLength {
+			return nil, "Link is too long"
+		}
+		parsed, err := url.Parse(raw)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return nil, "Links must be http or https URLs"
+		}
+		link := parsed.String()
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+	return links, ""
+}
+
+// verifyProfileLinks checks every link for a rel="me" backlink to the user's
+// profile. Links that can't be fetched are stored unverified, as are all links
+// when verification is turned off.
+func (cfg *Config) verifyProfileLinks(ctx context.Context, username string, links []string) []bool {
+	verified := make([]bool, len(links))
+	if !cfg.features.LinkVerification {
+		return verified
+	}
+
+	profileURL := strings.TrimSuffix(cfg.publicURL, "/") + "/" + url.PathEscape(username)
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i, link := range links {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := cfg.links.Verify(ctx, link, profileURL)
+			verified[i] = err == nil && ok
+		}()
+	}
+	wg.Wait()
+
+	return verified
+}
+
+func (cfg *Config) HandlerUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		DisplayName *string   `json:"display_name"`
+		Bio         *string   `json:"bio"`
+		Location    *string   `json:"location"`
+		Links       *[]string `json:"links"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	user, err := cfg.DB.GetUserById(r.Context(), database.GetUserByIdParams{
+		ID: userID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	profile := database.UpdateUserProfileParams{
+		ID:          userID,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Location:    user.Location,
+	}
+	if params.DisplayName != nil {
+		profile.DisplayName = strings.TrimSpace(*params.DisplayName)
+	}
+	if params.Bio != nil {
+		profile.Bio = strings.TrimSpace(*params.Bio)
+	}
+	if params.Location != nil {
+		profile.Location = strings.TrimSpace(*params.Location)
+	}
+
+	if utf8.RuneCountInString(profile.DisplayName) > maxDisplayNameLength {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Display name is too long", nil)
+		return
+	}
+	if utf8.RuneCountInString(profile.Bio) > maxBioLength {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Bio is too long", nil)
+		return
+	}
+	if utf8.RuneCountInString(profile.Location) > maxLocationLength {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Location is too long", nil)
+		return
+	}
+
+	var links []string
+	var verified []bool
+	if params.Links != nil {
+		var msg string
+		links, msg = normalizeProfileLinks(*params.Links, cfg.limits.MaxProfileLinks)
+		if msg != "" {
+			helpers.RespondWithError(w, http.StatusBadRequest, msg, nil)
+			return
+		}
+		verified = cfg.verifyProfileLinks(r.Context(), user.Username, links)
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error creating transaction", err)
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	updated, err := qtx.UpdateUserProfile(r.Context(), profile)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't update profile", err)
+		return
+	}
+
+	if params.Links != nil {
+		err = qtx.DeleteProfileLinks(r.Context(), userID)
+		if err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't update links", err)
+			return
+		}
+
+		for i, link := range links {
+			verifiedAt := sql.NullTime{}
+			if verified[i] {
+				verifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			}
+			err = qtx.CreateProfileLink(r.Context(), database.CreateProfileLinkParams{
+				UserID:     userID,
+				Url:        link,
+				Position:   int32(i),
+				VerifiedAt: verifiedAt,
+			})
+			if err != nil {
+				helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't update links", err)
+				return
+			}
+		}
+	}
+
+	dbLinks, err := qtx.ListProfileLinks(r.Context(), userID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get links", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error committing transaction", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, UpdateProfileResponse{
+		DisplayName: updated.DisplayName,
+		Bio:         updated.Bio,
+		Location:    updated.Location,
+		Links:       profileLinks(dbLinks, false),
+		UpdatedAt:   updated.UpdatedAt,
+	})
+}
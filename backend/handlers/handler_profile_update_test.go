@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/artyultra/tanglr/internal/linkcheck"
+)
+
+// stubPages serves canned pages to the link verifier. Any other URL is a 404.
+type stubPages map[string]string
+
+func (p stubPages) Do(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	if body, ok := p[req.URL.String()]; ok {
+		rec.WriteString(body)
+	} else {
+		rec.WriteHeader(http.StatusNotFound)
+	}
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+func updateProfile(t *testing.T, cfg *Config, user testUser, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	r := withToken(newRequest(t, http.MethodPatch, "/v1/users/me/profile", body), user.Token)
+	return serve(cfg.HandlerUpdateProfile, r)
+}
+
+func TestUpdateProfileLinks(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.links = linkcheck.NewVerifier(stubPages{
+		"https://blog.example/":  `<a rel="me" href="http://localhost:3000/alice">me</a>`,
+		"https://other.example/": `<p>no backlink here</p>`,
+	})
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	rec := updateProfile(t, cfg, alice, map[string]any{"links": []string{
+		"https://blog.example/",
+		" https://other.example/",
+		"https://blog.example/",
+		"https://down.example/",
+	}})
+	expectStatus(t, rec, http.StatusOK)
+	want := []ProfileLink{
+		{URL: "https://blog.example/", Verified: true},
+		{URL: "https://other.example/"},
+		{URL: "https://down.example/"},
+	}
+	if got := decodeResponse[UpdateProfileResponse](t, rec).Links; !slices.Equal(got, want) {
+		t.Errorf("links = %+v, want %+v", got, want)
+	}
+
+	// Other users only see the verified link.
+	got := decodeResponse[GetUserResponse](t, getProfile(t, cfg, bob, "alice")).Links
+	if !slices.Equal(got, want[:1]) {
+		t.Errorf("links seen by bob = %+v, want %+v", got, want[:1])
+	}
+
+	expectStatus(t, updateProfile(t, cfg, alice, map[string]any{"links": []string{"ftp://files.example/"}}), http.StatusBadRequest)
+
+	// The backlink points at the old name, so a rename unverifies the link.
+	expectStatus(t, renameUser(t, cfg, alice, "alice_2"), http.StatusOK)
+	if got := decodeResponse[GetUserResponse](t, getProfile(t, cfg, bob, "alice_2")).Links; len(got) != 0 {
+		t.Errorf("links seen by bob after the rename = %+v, want none", got)
+	}
+}
@@ -1,12 +1,11 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
-	"time"
 
 	"github.com/artyultra/tanglr/handlers/helpers"
 	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/metrics"
 )
 
 type Response struct {
@@ -14,7 +13,6 @@ type Response struct {
 }
 
 func (cfg *Config) HandlerRefreshToken(w http.ResponseWriter, r *http.Request) {
-	log.Println("Refreshing token")
 	authHeader, err := auth.AuthHeaderHelper(w, r)
 	if err != nil {
 		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token AUTHHELPER", err)
@@ -23,6 +21,7 @@ func (cfg *Config) HandlerRefreshToken(w http.ResponseWriter, r *http.Request) {
 
 	refreshToken, err := cfg.DB.GetRefreshToken(r.Context(), authHeader)
 	if err != nil {
+		metrics.Refreshes.WithLabelValues("invalid").Inc()
 		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
 		return
 	}
@@ -33,12 +32,19 @@ func (cfg *Config) HandlerRefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newAccessToken, err := auth.MakeJWT(user.ID, user.Username, cfg.jwtSecret, time.Hour)
+	if isSuspended(user.SuspendedAt, user.SuspendedUntil) {
+		metrics.Refreshes.WithLabelValues("suspended").Inc()
+		helpers.RespondWithError(w, http.StatusForbidden, "Account suspended", nil)
+		return
+	}
+
+	newAccessToken, err := auth.MakeJWT(user.ID, user.Username, auth.Role(user.Role), cfg.jwtKeys, cfg.tokens.AccessTTL)
 	if err != nil {
 		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't create token", err)
 		return
 	}
 
+	metrics.Refreshes.WithLabelValues("success").Inc()
 	helpers.RespondWithJSON(w, http.StatusOK, Response{
 		AccessToken: newAccessToken,
 	})
@@ -57,6 +63,7 @@ func (cfg *Config) HandlerRevokeRefreshToken(w http.ResponseWriter, r *http.Requ
 		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't revoke token", err)
 		return
 	}
+	metrics.Revocations.WithLabelValues("logout").Inc()
 
 	helpers.RespondWithJSON(w, http.StatusNoContent, nil)
 
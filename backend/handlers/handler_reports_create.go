@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/google/uuid"
+)
+
+type Report struct {
+	ID               uuid.UUID  `json:"id"`
+	ReporterID       uuid.UUID  `json:"reporter_id"`
+	ReporterUsername string     `json:"reporter_username,omitempty"`
+	ReportedUserID   uuid.UUID  `json:"reported_user_id"`
+	ReportedUsername string     `json:"reported_username,omitempty"`
+	PostID           *uuid.UUID `json:"post_id,omitempty"`
+	PostBody         string     `json:"post_body,omitempty"`
+	Reason           string     `json:"reason"`
+	Details          string     `json:"details"`
+	Status           string     `json:"status"`
+	ClaimedBy        *uuid.UUID `json:"claimed_by,omitempty"`
+	ResolvedBy       *uuid.UUID `json:"resolved_by,omitempty"`
+	Resolution       string     `json:"resolution,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+var reportReasons = map[string]bool{
+	"spam":       true,
+	"harassment": true,
+	"hate":       true,
+	"violence":   true,
+	"nudity":     true,
+	"other":      true,
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func reportFromDB(report database.Report) Report {
+	return Report{
+		ID:             report.ID,
+		ReporterID:     report.ReporterID,
+		ReportedUserID: report.ReportedUserID,
+		PostID:         nullUUIDPtr(report.PostID),
+		Reason:         report.Reason,
+		Details:        report.Details,
+		Status:         report.Status,
+		ClaimedBy:      nullUUIDPtr(report.ClaimedBy),
+		ResolvedBy:     nullUUIDPtr(report.ResolvedBy),
+		Resolution:     report.Resolution.String,
+		CreatedAt:      report.CreatedAt,
+		UpdatedAt:      report.UpdatedAt,
+	}
+}
+
+func (cfg *Config) HandlerCreateReport(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Username string     `json:"username"`
+		PostID   *uuid.UUID `json:"post_id"`
+		Reason   string     `json:"reason"`
+		Details  string     `json:"details"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	if !reportReasons[params.Reason] {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid report reason", nil)
+		return
+	}
+
+	reportParams := database.CreateReportParams{
+		ReporterID: userID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	}
+
+	// The target is looked up without the reporter as viewer: blocks hide
+	// people from each other, but someone who blocked you, or whom you
+	// blocked, can still be reported.
+	switch {
+	case params.PostID != nil:
+		post, err := cfg.DB.GetPostById(r.Context(), database.GetPostByIdParams{
+			ID:       *params.PostID,
+			ViewerID: uuid.Nil,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				helpers.RespondWithError(w, http.StatusNotFound, "Post not found", err)
+				return
+			}
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get post", err)
+			return
+		}
+		reportParams.ReportedUserID = post.UserID
+		reportParams.PostID = uuid.NullUUID{UUID: post.ID, Valid: true}
+	case params.Username != "":
+		target, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+			Username: params.Username,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+				return
+			}
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+			return
+		}
+		reportParams.ReportedUserID = target.UserID
+	default:
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing post_id or username", nil)
+		return
+	}
+
+	if reportParams.ReportedUserID == userID {
+		helpers.RespondWithError(w, http.StatusBadRequest, "You can't report yourself", nil)
+		return
+	}
+
+	report, err := cfg.DB.CreateReport(r.Context(), reportParams)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't create report", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusCreated, reportFromDB(report))
+}
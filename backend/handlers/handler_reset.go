@@ -7,6 +7,11 @@ import (
 )
 
 func (cfg *Config) HandlerResetDatabases(w http.ResponseWriter, r *http.Request) {
+	if cfg.environment != "development" {
+		helpers.RespondWithError(w, http.StatusForbidden, "Reset is only allowed in development", nil)
+		return
+	}
+
 	err := cfg.DB.ResetUsersTable(r.Context())
 	if err != nil {
 		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset users table", err)
@@ -37,7 +42,110 @@ func (cfg *Config) HandlerResetDatabases(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	message := "reset tables: users, user_preferences, refresh_tokens, posts, follows"
+	err = cfg.DB.ResetBlocksTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset blocks table", err)
+		return
+	}
+
+	err = cfg.DB.ResetMutesTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset mutes table", err)
+		return
+	}
+
+	err = cfg.DB.ResetModerationActionsTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset moderation actions table", err)
+		return
+	}
+
+	err = cfg.DB.ResetReportsTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset reports table", err)
+		return
+	}
+
+	err = cfg.DB.ResetUsernameHistoryTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset username history table", err)
+		return
+	}
+
+	err = cfg.DB.ResetProfileLinksTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset profile links table", err)
+		return
+	}
+
+	err = cfg.DB.ResetRateLimitBucketsTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset rate limit buckets table", err)
+		return
+	}
+
+	err = cfg.DB.ResetLoginAttemptsTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset login attempts table", err)
+		return
+	}
+
+	err = cfg.DB.ResetLoginLockoutsTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset login lockouts table", err)
+		return
+	}
+
+	err = cfg.DB.ResetTOTPSecretsTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset TOTP secrets table", err)
+		return
+	}
+
+	err = cfg.DB.ResetRecoveryCodesTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset recovery codes table", err)
+		return
+	}
+
+	err = cfg.DB.ResetWebAuthnCredentialsTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset WebAuthn credentials table", err)
+		return
+	}
+
+	err = cfg.DB.ResetWebAuthnSessionsTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset WebAuthn sessions table", err)
+		return
+	}
+
+	err = cfg.DB.ResetIdentitiesTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset identities table", err)
+		return
+	}
+
+	err = cfg.DB.ResetOIDCLoginsTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset OIDC logins table", err)
+		return
+	}
+
+	err = cfg.DB.ResetSigningKeysTable(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't reset signing keys table", err)
+		return
+	}
+
+	// Start over with a fresh key rather than waiting for the next reload.
+	err = cfg.LoadSigningKeys(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't create signing key", err)
+		return
+	}
+
+	message := "reset tables: users, user_preferences, refresh_tokens, posts, follows, blocks, mutes, reports, moderation_actions, username_history, profile_links, rate_limit_buckets, login_attempts, login_lockouts, totp_secrets, recovery_codes, webauthn_credentials, webauthn_sessions, identities, oidc_logins, signing_keys"
 
 	helpers.RespondWithJSON(
 		w,
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/config"
+)
+
+func TestResetDatabases(t *testing.T) {
+	cfg := newTestConfig(t, func(c *config.Config) {
+		c.Tokens.Algorithm = auth.AlgEdDSA
+	})
+	admin := createTestUserWithRole(t, cfg, "admin", auth.RoleAdmin)
+	createTestPost(t, cfg, admin, "about to vanish")
+	before := cfg.jwtKeys.Keys(time.Now())
+
+	rec := serve(cfg.HandlerResetDatabases, newRequest(t, http.MethodPost, "/v1/reset", nil))
+	expectStatus(t, rec, http.StatusOK)
+
+	if _, err := cfg.DB.GetUserByEmail(context.Background(), admin.Email); err == nil {
+		t.Error("users survived the reset")
+	}
+
+	after := cfg.jwtKeys.Keys(time.Now())
+	if len(after) != 1 || len(before) != 1 || after[0].ID == before[0].ID {
+		t.Fatalf("signing keys before %v, after %v; want one fresh key", keyIDs(before), keyIDs(after))
+	}
+	if _, err := auth.ValidateJWT(admin.Token, cfg.jwtKeys); err == nil {
+		t.Error("token signed with a reset key still validates")
+	}
+}
+
+func TestResetDatabasesOutsideDevelopment(t *testing.T) {
+	cfg := newTestConfig(t, func(c *config.Config) {
+		c.Environment = "production"
+	})
+	createTestUser(t, cfg, "alice")
+
+	rec := serve(cfg.HandlerResetDatabases, newRequest(t, http.MethodPost, "/v1/reset", nil))
+	expectStatus(t, rec, http.StatusForbidden)
+}
+
+func keyIDs(keys []auth.SigningKey) []string {
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, key.ID)
+	}
+	return ids
+}
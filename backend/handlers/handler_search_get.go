@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/google/uuid"
+)
+
+type SearchUserResult struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	AvatarURL string    `json:"avatar_url"`
+}
+
+type SearchPostResult struct {
+	Post
+	Rank      float32 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+type SearchResponse struct {
+	Users []SearchUserResult `json:"users"`
+	Posts []SearchPostResult `json:"posts"`
+}
+
+// escapeLikePattern escapes the LIKE wildcards in a user-supplied prefix.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (cfg *Config) HandlerSearch(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	viewerID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing search query", nil)
+		return
+	}
+
+	searchType := r.URL.Query().Get("type")
+	if searchType != "" && searchType != "users" && searchType != "posts" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid search type", nil)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	limit = min(limit, cfg.limits.MaxSearchResults)
+
+	resp := SearchResponse{
+		Users: []SearchUserResult{},
+		Posts: []SearchPostResult{},
+	}
+
+	if searchType == "" || searchType == "users" {
+		dbUsers, err := cfg.DB.SearchUsers(r.Context(), database.SearchUsersParams{
+			Prefix:      escapeLikePattern(strings.TrimPrefix(query, "@")),
+			ViewerID:    viewerID,
+			ResultLimit: int32(limit),
+		})
+		if err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't search users", err)
+			return
+		}
+		for _, user := range dbUsers {
+			resp.Users = append(resp.Users, SearchUserResult{
+				ID:        user.ID,
+				Username:  user.Username,
+				AvatarURL: user.AvatarUrl.String,
+			})
+		}
+	}
+
+	if searchType == "" || searchType == "posts" {
+		dbPosts, err := cfg.DB.SearchPosts(r.Context(), database.SearchPostsParams{
+			Query:       query,
+			ViewerID:    viewerID,
+			ResultLimit: int32(limit),
+		})
+		if err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't search posts", err)
+			return
+		}
+		for _, post := range dbPosts {
+			resp.Posts = append(resp.Posts, SearchPostResult{
+				Post: Post{
+					ID:          post.ID,
+					Body:        post.Body,
+					CreatedAt:   post.CreatedAt,
+					UpdatedAt:   post.UpdatedAt,
+					UserID:      post.UserID,
+					Username:    post.Username,
+					DisplayName: post.DisplayName,
+					AvatarURL:   post.AvatarUrl.String,
+				},
+				Rank:      post.Rank,
+				Highlight: post.Highlight,
+			})
+		}
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, resp)
+}
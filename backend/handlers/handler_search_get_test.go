@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func search(t *testing.T, cfg *Config, viewer testUser, query url.Values) SearchResponse {
+	t.Helper()
+	r := withToken(newRequest(t, http.MethodGet, "/v1/search?"+query.Encode(), nil), viewer.Token)
+	rec := serve(cfg.HandlerSearch, r)
+	expectStatus(t, rec, http.StatusOK)
+	return decodeResponse[SearchResponse](t, rec)
+}
+
+func usernames(users []SearchUserResult) []string {
+	names := []string{}
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+	return names
+}
+
+func postWithBody(t *testing.T, cfg *Config, viewer testUser, body string) Post {
+	t.Helper()
+	for _, post := range timeline(t, cfg, viewer) {
+		if post.Body == body {
+			return post
+		}
+	}
+	t.Fatalf("no post %q on %s's timeline", body, viewer.Username)
+	return Post{}
+}
+
+func TestEscapeLikePattern(t *testing.T) {
+	tests := map[string]string{
+		"alice":   "alice",
+		"a_b":     `a\_b`,
+		"100%":    `100\%`,
+		`back\sl`: `back\\sl`,
+	}
+	for in, want := range tests {
+		if got := escapeLikePattern(in); got != want {
+			t.Errorf("escapeLikePattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSearchUsersByPrefix(t *testing.T) {
+	cfg := newTestConfig(t)
+	viewer := createTestUser(t, cfg, "viewer")
+	createTestUser(t, cfg, "alice")
+	createTestUser(t, cfg, "alicia_k")
+	createTestUser(t, cfg, "aliXbob")
+	createTestUser(t, cfg, "bob")
+	blocker := createTestUser(t, cfg, "alinda")
+	blockUser(t, cfg, blocker, viewer)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"ali", []string{"alice", "aliXbob", "alicia_k"}},
+		{"@ALICE", []string{"alice"}},
+		{"alicia_", []string{"alicia_k"}},
+		// "_" is literal, not a one-character wildcard.
+		{"ali_", []string{}},
+		{"zed", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := usernames(search(t, cfg, viewer, url.Values{"q": {tt.query}, "type": {"users"}}).Users)
+			if len(got) != len(tt.want) {
+				t.Fatalf("users = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("users = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchPostsRespectsVisibility(t *testing.T) {
+	cfg := newTestConfig(t)
+	viewer := createTestUser(t, cfg, "viewer")
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	carol := createTestUser(t, cfg, "carol")
+
+	createTestPost(t, cfg, alice, "Gardening tips for spring")
+	createTestPost(t, cfg, bob, "My gardening diary")
+	createTestPost(t, cfg, carol, "Secret gardening plans")
+	createTestPost(t, cfg, carol, "Hidden gardening post")
+	blockUser(t, cfg, viewer, bob)
+
+	ctx := context.Background()
+	_, err := cfg.DBConn.Exec("UPDATE posts SET visibility = 'friends' WHERE body = ?", "Secret gardening plans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hidden := postWithBody(t, cfg, alice, "Hidden gardening post")
+	if err := cfg.DB.HidePost(ctx, hidden.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	got := search(t, cfg, viewer, url.Values{"q": {"gardening"}, "type": {"posts"}}).Posts
+	if len(got) != 1 || got[0].UserID != alice.ID {
+		t.Fatalf("posts = %+v, want only alice's public post", got)
+	}
+
+	// Following carol opens up her friends-only post.
+	followUser(t, cfg, viewer, carol, http.StatusCreated)
+	got = search(t, cfg, viewer, url.Values{"q": {"gardening"}, "type": {"posts"}}).Posts
+	if len(got) != 2 {
+		t.Fatalf("posts = %+v, want alice's and carol's friends-only post", got)
+	}
+}
+
+func TestSearchValidation(t *testing.T) {
+	cfg := newTestConfig(t)
+	viewer := createTestUser(t, cfg, "viewer")
+
+	for _, target := range []string{"/v1/search", "/v1/search?q=%20", "/v1/search?q=a&type=hashtags"} {
+		r := withToken(newRequest(t, http.MethodGet, target, nil), viewer.Token)
+		expectStatus(t, serve(cfg.HandlerSearch, r), http.StatusBadRequest)
+	}
+
+	expectStatus(t, serve(cfg.HandlerSearch, newRequest(t, http.MethodGet, "/v1/search?q=a", nil)), http.StatusUnauthorized)
+}
+
+func TestSearchLimit(t *testing.T) {
+	cfg := newTestConfig(t)
+	viewer := createTestUser(t, cfg, "viewer")
+	for _, name := range []string{"sam1", "sam2", "sam3", "sam4"} {
+		createTestUser(t, cfg, name)
+	}
+	cfg.limits.MaxSearchResults = 3
+
+	if got := search(t, cfg, viewer, url.Values{"q": {"sam"}, "limit": {"2"}}).Users; len(got) != 2 {
+		t.Errorf("limit=2 returned %d users", len(got))
+	}
+	if got := search(t, cfg, viewer, url.Values{"q": {"sam"}, "limit": {"50"}}).Users; len(got) != 3 {
+		t.Errorf("limit above the maximum returned %d users, want 3", len(got))
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/google/uuid"
+)
+
+const suggestionsLimit = 20
+
+type Suggestion struct {
+	ID            uuid.UUID  `json:"id"`
+	Username      string     `json:"username"`
+	AvatarURL     string     `json:"avatar_url"`
+	MutualCount   int64      `json:"mutual_count"`
+	FollowerCount int64      `json:"follower_count"`
+	LastPostedAt  *time.Time `json:"last_posted_at,omitempty"`
+}
+
+// invalidateSuggestions drops cached suggestions for users whose follow graph
+// just changed. Call it whenever follows are created or removed. Changes that
+// only touch a user's suggestions indirectly, such as someone they follow
+// following someone new or a suggested account posting, aren't tracked; those
+// show up once the entry expires after Limits.SuggestionsCacheTTL.
+func (cfg *Config) invalidateSuggestions(userIDs ...uuid.UUID) {
+	cfg.suggestions.Delete(userIDs...)
+}
+
+func (cfg *Config) HandlerGetSuggestions(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	if suggestions, ok := cfg.suggestions.Get(userID); ok {
+		helpers.RespondWithJSON(w, http.StatusOK, suggestions)
+		return
+	}
+
+	// Taken before the query so that a follow made while it runs stops the
+	// stale result from being cached.
+	generation := cfg.suggestions.Generation()
+
+	dbSuggestions, err := cfg.DB.GetFollowSuggestions(r.Context(), database.GetFollowSuggestionsParams{
+		ViewerID:    userID,
+		ResultLimit: suggestionsLimit,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get suggestions", err)
+		return
+	}
+
+	suggestions := []Suggestion{}
+	for _, s := range dbSuggestions {
+		suggestion := Suggestion{
+			ID:            s.ID,
+			Username:      s.Username,
+			AvatarURL:     s.AvatarUrl.String,
+			MutualCount:   s.MutualCount,
+			FollowerCount: s.FollowerCount,
+		}
+		if s.LastPostedAt.Valid {
+			suggestion.LastPostedAt = &s.LastPostedAt.Time
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	cfg.suggestions.SetIfCurrent(userID, suggestions, generation)
+
+	helpers.RespondWithJSON(w, http.StatusOK, suggestions)
+}
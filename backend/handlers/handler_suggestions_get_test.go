@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func suggestionsFor(t *testing.T, cfg *Config, viewer testUser) []string {
+	t.Helper()
+	rec := serve(cfg.HandlerGetSuggestions, withToken(newRequest(t, http.MethodGet, "/v1/me/suggestions", nil), viewer.Token))
+	expectStatus(t, rec, http.StatusOK)
+	names := []string{}
+	for _, s := range decodeResponse[[]Suggestion](t, rec) {
+		names = append(names, s.Username)
+	}
+	return names
+}
+
+func TestSuggestionsRanking(t *testing.T) {
+	cfg := newTestConfig(t)
+	viewer := createTestUser(t, cfg, "viewer")
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	carol := createTestUser(t, cfg, "carol")
+	dave := createTestUser(t, cfg, "dave")
+	erin := createTestUser(t, cfg, "erin")
+	frank := createTestUser(t, cfg, "frank")
+	grace := createTestUser(t, cfg, "grace")
+	createTestUser(t, cfg, "idle")
+
+	followUser(t, cfg, viewer, alice, http.StatusCreated)
+	followUser(t, cfg, viewer, bob, http.StatusCreated)
+	followUser(t, cfg, alice, carol, http.StatusCreated)
+	followUser(t, cfg, bob, carol, http.StatusCreated)
+	followUser(t, cfg, alice, dave, http.StatusCreated)
+	followUser(t, cfg, alice, frank, http.StatusCreated)
+	followUser(t, cfg, alice, grace, http.StatusCreated)
+	createTestPost(t, cfg, erin, "new here")
+
+	blockUser(t, cfg, frank, viewer)
+	setPrivateMode(t, cfg, grace)
+	followUser(t, cfg, viewer, grace, http.StatusCreated)
+
+	// Mutual follows first, then recent posters. Followed, requested and
+	// blocked accounts are left out, as are users with nothing to go on.
+	want := []string{"carol", "dave", "erin"}
+	if got := suggestionsFor(t, cfg, viewer); !slices.Equal(got, want) {
+		t.Errorf("suggestions = %v, want %v", got, want)
+	}
+}
+
+func TestSuggestionsInvalidatedByFollows(t *testing.T) {
+	cfg := newTestConfig(t)
+	viewer := createTestUser(t, cfg, "viewer")
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	carol := createTestUser(t, cfg, "carol")
+	dave := createTestUser(t, cfg, "dave")
+
+	followUser(t, cfg, viewer, alice, http.StatusCreated)
+	followUser(t, cfg, viewer, bob, http.StatusCreated)
+	followUser(t, cfg, alice, carol, http.StatusCreated)
+	followUser(t, cfg, bob, carol, http.StatusCreated)
+	followUser(t, cfg, alice, dave, http.StatusCreated)
+
+	if got := suggestionsFor(t, cfg, viewer); !slices.Equal(got, []string{"carol", "dave"}) {
+		t.Fatalf("suggestions = %v", got)
+	}
+
+	followUser(t, cfg, viewer, carol, http.StatusCreated)
+	if got := suggestionsFor(t, cfg, viewer); !slices.Equal(got, []string{"dave"}) {
+		t.Errorf("after following carol, suggestions = %v, want [dave]", got)
+	}
+
+	r := withURLParams(withToken(newRequest(t, http.MethodDelete, "/", nil), viewer.Token), "username", carol.Username)
+	expectStatus(t, serve(cfg.HandlerUnfollowUser, r), http.StatusNoContent)
+	if got := suggestionsFor(t, cfg, viewer); !slices.Equal(got, []string{"carol", "dave"}) {
+		t.Errorf("after unfollowing carol, suggestions = %v, want [carol dave]", got)
+	}
+
+	// Accepting a request changes both users' graphs.
+	setPrivateMode(t, cfg, dave)
+	suggestionsFor(t, cfg, dave)
+	followUser(t, cfg, carol, dave, http.StatusCreated)
+	r = withURLParams(withToken(newRequest(t, http.MethodPost, "/", nil), dave.Token), "username", carol.Username)
+	expectStatus(t, serve(cfg.HandlerAcceptFollower, r), http.StatusNoContent)
+	if _, cached := cfg.suggestions.Get(dave.ID); cached {
+		t.Error("accepting a follower left the cached suggestions in place")
+	}
+
+	blockUser(t, cfg, viewer, dave)
+	if got := suggestionsFor(t, cfg, viewer); !slices.Equal(got, []string{"carol"}) {
+		t.Errorf("after blocking dave, suggestions = %v, want [carol]", got)
+	}
+}
+
+func TestSuggestionsNotCachedAfterInvalidation(t *testing.T) {
+	cfg := newTestConfig(t)
+	viewer := createTestUser(t, cfg, "viewer")
+
+	// A lookup that started before a follow mustn't write its result back.
+	generation := cfg.suggestions.Generation()
+	cfg.invalidateSuggestions(viewer.ID)
+	if cfg.suggestions.SetIfCurrent(viewer.ID, []Suggestion{}, generation) {
+		t.Error("stale suggestions were cached after an invalidation")
+	}
+	if _, cached := cfg.suggestions.Get(viewer.ID); cached {
+		t.Error("stale suggestions are in the cache")
+	}
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/internal/metrics"
+	"github.com/artyultra/tanglr/internal/store"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+type SuspendedResponse struct {
+	Error          string     `json:"error"`
+	Reason         string     `json:"reason,omitempty"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	Permanent      bool       `json:"permanent"`
+}
+
+// isSuspended reports whether a suspension is in effect. A suspension with no
+// end time is permanent.
+func isSuspended(suspendedAt, suspendedUntil sql.NullTime) bool {
+	if !suspendedAt.Valid {
+		return false
+	}
+	return !suspendedUntil.Valid || suspendedUntil.Time.After(time.Now())
+}
+
+func suspendedResponse(reason string, suspendedUntil sql.NullTime) SuspendedResponse {
+	resp := SuspendedResponse{
+		Error:     "Account suspended",
+		Reason:    reason,
+		Permanent: !suspendedUntil.Valid,
+	}
+	if suspendedUntil.Valid {
+		resp.SuspendedUntil = &suspendedUntil.Time
+	}
+	return resp
+}
+
+// roleRank orders roles by privilege. Moderators can only suspend accounts
+// ranked below their own.
+var roleRank = map[auth.Role]int{
+	auth.RoleUser:      0,
+	auth.RoleModerator: 1,
+	auth.RoleAdmin:     2,
+}
+
+// outranks reports whether the caller stored in ctx by RequireRole holds a
+// higher role than target.
+func outranks(ctx context.Context, target string) bool {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return false
+	}
+	return roleRank[claims.Role] > roleRank[auth.Role(target)]
+}
+
+// suspensionEnd converts a duration in hours into the suspended_until value.
+// Zero means the suspension is permanent.
+func suspensionEnd(durationHours int) sql.NullTime {
+	if durationHours <= 0 {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{
+		Time:  time.Now().Add(time.Duration(durationHours) * time.Hour),
+		Valid: true,
+	}
+}
+
+// suspendUser marks the account as suspended and revokes its refresh tokens so
+// no new access tokens can be issued. Run it inside the caller's transaction.
+func suspendUser(ctx context.Context, qtx store.Repository, userID uuid.UUID, until sql.NullTime, reason string) error {
+	err := qtx.SuspendUser(ctx, database.SuspendUserParams{
+		ID:               userID,
+		SuspendedUntil:   until,
+		SuspensionReason: reason,
+	})
+	if err != nil {
+		return err
+	}
+	if err := qtx.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+	metrics.Revocations.WithLabelValues("suspension").Inc()
+	return nil
+}
+
+func (cfg *Config) HandlerSuspendUser(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		DurationHours int    `json:"duration_hours"`
+		Reason        string `json:"reason"`
+	}
+
+	moderatorID, err := userIDFromContext(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	username := chi.URLParam(r, "username")
+	if username == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing username", nil)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	if params.Reason == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing suspension reason", nil)
+		return
+	}
+
+	target, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	if target.UserID == moderatorID {
+		helpers.RespondWithError(w, http.StatusBadRequest, "You can't suspend yourself", nil)
+		return
+	}
+
+	if !outranks(r.Context(), target.Role) {
+		helpers.RespondWithError(w, http.StatusForbidden, "You can't suspend a user whose role is the same as or above yours", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error creating transaction", err)
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	until := suspensionEnd(params.DurationHours)
+
+	err = suspendUser(r.Context(), qtx, target.UserID, until, params.Reason)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't suspend user", err)
+		return
+	}
+
+	err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ModeratorID:  uuid.NullUUID{UUID: moderatorID, Valid: true},
+		Action:       "suspend_user",
+		TargetUserID: uuid.NullUUID{UUID: target.UserID, Valid: true},
+		Note:         params.Reason,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't record moderation action", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error committing transaction", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, suspendedResponse(params.Reason, until))
+}
+
+func (cfg *Config) HandlerUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	moderatorID, err := userIDFromContext(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	username := chi.URLParam(r, "username")
+	if username == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing username", nil)
+		return
+	}
+
+	target, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	// Otherwise a moderator could lift a suspension an admin placed on
+	// another moderator, or on themselves.
+	if !outranks(r.Context(), target.Role) {
+		helpers.RespondWithError(w, http.StatusForbidden, "You can't lift the suspension of a user whose role is the same as or above yours", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error creating transaction", err)
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	err = qtx.UnsuspendUser(r.Context(), target.UserID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't lift suspension", err)
+		return
+	}
+
+	err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ModeratorID:  uuid.NullUUID{UUID: moderatorID, Valid: true},
+		Action:       "unsuspend_user",
+		TargetUserID: uuid.NullUUID{UUID: target.UserID, Valid: true},
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't record moderation action", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error committing transaction", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxAppealNoteLength caps the appeal note, in characters.
+const maxAppealNoteLength = 1000
+
+// HandlerCreateAppeal lets a suspended user leave a note for moderators.
+// Suspended accounts can't obtain tokens, so the request is authenticated
+// with the account's username and password instead. It guards that password
+// the same way HandlerLogin does, and an account that isn't suspended gets
+// the same answer as a wrong password so the route can't be used to test
+// credentials.
+func (cfg *Config) HandlerCreateAppeal(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Note     string `json:"note"`
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	if params.Note == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing appeal note", nil)
+		return
+	}
+	if utf8.RuneCountInString(params.Note) > maxAppealNoteLength {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Appeal note is too long", nil)
+		return
+	}
+
+	src := cfg.loginSource(r)
+
+	user, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: params.Username,
+	})
+	if err != nil {
+		if err != sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't save appeal", err)
+			return
+		}
+		auth.CheckPasswordNoUser(params.Password)
+		cfg.recordLoginAttempt(r.Context(), src, params.Username, uuid.Nil, loginUnknownUser, false)
+		respondInvalidLogin(w)
+		return
+	}
+
+	locked, err := cfg.isLoginLocked(r.Context(), user.UserID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't save appeal", err)
+		return
+	}
+
+	var passwordErr error
+	if user.HashedPassword == noPassword {
+		auth.CheckPasswordNoUser(params.Password)
+		passwordErr = errNoPassword
+	} else {
+		passwordErr = auth.CheckPassword(params.Password, user.HashedPassword)
+	}
+
+	if locked {
+		cfg.recordLoginAttempt(r.Context(), src, params.Username, user.UserID, loginLocked, false)
+		respondInvalidLogin(w)
+		return
+	}
+
+	if passwordErr != nil {
+		if err := cfg.registerFailedLogin(r.Context(), user.UserID); err != nil {
+			helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't save appeal", err)
+			return
+		}
+		cfg.recordLoginAttempt(r.Context(), src, params.Username, user.UserID, loginWrongPassword, false)
+		respondInvalidLogin(w)
+		return
+	}
+
+	if !isSuspended(user.SuspendedAt, user.SuspendedUntil) {
+		respondInvalidLogin(w)
+		return
+	}
+
+	updated, err := cfg.DB.SetAppealNote(r.Context(), database.SetAppealNoteParams{
+		ID:         user.UserID,
+		AppealNote: params.Note,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't save appeal", err)
+		return
+	}
+
+	// The suspension ended between the lookup and the update.
+	if updated == 0 {
+		respondInvalidLogin(w)
+		return
+	}
+
+	cfg.recordLoginAttempt(r.Context(), src, params.Username, user.UserID, loginSuspended, false)
+	helpers.RespondWithJSON(w, http.StatusCreated, struct{}{})
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/auth"
+)
+
+func suspendRequest(t *testing.T, moderator testUser, username string, body any) *http.Request {
+	method := http.MethodPost
+	if body == nil {
+		method = http.MethodDelete
+	}
+	r := withToken(newRequest(t, method, "/", body), moderator.Token)
+	return withURLParams(r, "username", username)
+}
+
+func TestIsSuspended(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		at    sql.NullTime
+		until sql.NullTime
+		want  bool
+	}{
+		{"never suspended", sql.NullTime{}, sql.NullTime{}, false},
+		{"permanent", sql.NullTime{Time: now, Valid: true}, sql.NullTime{}, true},
+		{"running", sql.NullTime{Time: now, Valid: true}, sql.NullTime{Time: now.Add(time.Hour), Valid: true}, true},
+		{"expired", sql.NullTime{Time: now.Add(-2 * time.Hour), Valid: true}, sql.NullTime{Time: now.Add(-time.Hour), Valid: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSuspended(tt.at, tt.until); got != tt.want {
+				t.Errorf("isSuspended = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuspendAndUnsuspend(t *testing.T) {
+	cfg := newTestConfig(t)
+	mod := createTestUserWithRole(t, cfg, "mod", auth.RoleModerator)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	createTestPost(t, cfg, alice, "hello")
+
+	rec := login(t, cfg, alice.Username, testPassword)
+	expectStatus(t, rec, http.StatusOK)
+	refreshToken := decodeResponse[loginResponse](t, rec).RefreshToken
+
+	rec = serveModerator(cfg, cfg.HandlerSuspendUser, suspendRequest(t, mod, alice.Username, map[string]any{
+		"duration_hours": 24,
+		"reason":         "spam",
+	}))
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[SuspendedResponse](t, rec); got.Permanent || got.SuspendedUntil == nil {
+		t.Errorf("suspension = %+v, want one that ends", got)
+	}
+
+	rec = login(t, cfg, alice.Username, testPassword)
+	expectStatus(t, rec, http.StatusForbidden)
+	if got := decodeResponse[SuspendedResponse](t, rec); got.Reason != "spam" {
+		t.Errorf("login refusal = %+v, want the suspension reason", got)
+	}
+
+	r := newRequest(t, http.MethodPost, "/v1/refresh-token", nil)
+	r.Header.Set("Authorization", "Bearer "+refreshToken)
+	expectStatus(t, serve(cfg.HandlerRefreshToken, r), http.StatusUnauthorized)
+
+	if postedBy(timeline(t, cfg, bob), alice) {
+		t.Error("suspended user's posts are still visible")
+	}
+
+	rec = serve(cfg.HandlerCreateAppeal, newRequest(t, http.MethodPost, "/v1/appeals", map[string]string{
+		"username": alice.Username,
+		"password": "wrong password",
+		"note":     "sorry",
+	}))
+	expectStatus(t, rec, http.StatusUnauthorized)
+	rec = serve(cfg.HandlerCreateAppeal, newRequest(t, http.MethodPost, "/v1/appeals", map[string]string{
+		"username": alice.Username,
+		"password": testPassword,
+		"note":     "sorry",
+	}))
+	expectStatus(t, rec, http.StatusCreated)
+
+	rec = serveModerator(cfg, cfg.HandlerUnsuspendUser, suspendRequest(t, mod, alice.Username, nil))
+	expectStatus(t, rec, http.StatusNoContent)
+	if rec.Body.Len() != 0 {
+		t.Errorf("204 response has a body: %q", rec.Body.String())
+	}
+
+	expectStatus(t, login(t, cfg, alice.Username, testPassword), http.StatusOK)
+	if !postedBy(timeline(t, cfg, bob), alice) {
+		t.Error("posts still hidden after the suspension was lifted")
+	}
+
+	// With no suspension in effect there is nothing to appeal, and the
+	// answer is the same as for a wrong password.
+	rec = serve(cfg.HandlerCreateAppeal, newRequest(t, http.MethodPost, "/v1/appeals", map[string]string{
+		"username": alice.Username,
+		"password": testPassword,
+		"note":     "sorry",
+	}))
+	expectStatus(t, rec, http.StatusUnauthorized)
+}
+
+func appeal(t *testing.T, cfg *Config, username, password, note string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serve(cfg.HandlerCreateAppeal, newRequest(t, http.MethodPost, "/v1/appeals", map[string]string{
+		"username": username,
+		"password": password,
+		"note":     note,
+	}))
+}
+
+func TestAppealGuardsPassword(t *testing.T) {
+	cfg := newTestConfig(t, withLockout)
+	mod := createTestUserWithRole(t, cfg, "mod", auth.RoleModerator)
+	alice := createTestUser(t, cfg, "alice")
+	createTestUser(t, cfg, "bob")
+
+	expectStatus(t, appeal(t, cfg, "nobody", testPassword, "sorry"), http.StatusUnauthorized)
+	expectStatus(t, appeal(t, cfg, "bob", testPassword, "sorry"), http.StatusUnauthorized)
+	expectStatus(t, appeal(t, cfg, "bob", "wrong", "sorry"), http.StatusUnauthorized)
+
+	rec := serveModerator(cfg, cfg.HandlerSuspendUser, suspendRequest(t, mod, alice.Username, map[string]any{"reason": "spam"}))
+	expectStatus(t, rec, http.StatusOK)
+
+	expectStatus(t, appeal(t, cfg, "alice", testPassword, strings.Repeat("x", maxAppealNoteLength+1)), http.StatusBadRequest)
+
+	// Wrong passwords count towards the same lockout as logins, and a
+	// locked account can't appeal even with the right password.
+	for range 3 {
+		expectStatus(t, appeal(t, cfg, "alice", "wrong", "sorry"), http.StatusUnauthorized)
+	}
+	expectLockedFor(t, cfg, alice, time.Minute)
+	expectStatus(t, appeal(t, cfg, "alice", testPassword, "sorry"), http.StatusUnauthorized)
+
+	expireLockout(t, cfg, alice)
+	expectStatus(t, appeal(t, cfg, "alice", testPassword, "sorry"), http.StatusCreated)
+
+	var failures int
+	err := cfg.DBConn.QueryRow("SELECT count(*) FROM login_attempts WHERE failure_reason IN ('unknown_user', 'wrong_password', 'locked')").Scan(&failures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures != 6 {
+		t.Errorf("recorded %d failed attempts, want 6", failures)
+	}
+}
+
+func TestSuspendRespectsRoles(t *testing.T) {
+	cfg := newTestConfig(t)
+	admin := createTestUserWithRole(t, cfg, "admin", auth.RoleAdmin)
+	mod := createTestUserWithRole(t, cfg, "mod", auth.RoleModerator)
+	otherMod := createTestUserWithRole(t, cfg, "othermod", auth.RoleModerator)
+	body := map[string]any{"reason": "abuse"}
+
+	tests := []struct {
+		name   string
+		caller testUser
+		target testUser
+		want   int
+	}{
+		{"moderator suspends admin", mod, admin, http.StatusForbidden},
+		{"moderator suspends moderator", mod, otherMod, http.StatusForbidden},
+		{"moderator suspends self", mod, mod, http.StatusBadRequest},
+		{"admin suspends moderator", admin, otherMod, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveModerator(cfg, cfg.HandlerSuspendUser, suspendRequest(t, tt.caller, tt.target.Username, body))
+			expectStatus(t, rec, tt.want)
+		})
+	}
+
+	// Only the admin can lift the moderator's suspension.
+	rec := serveModerator(cfg, cfg.HandlerUnsuspendUser, suspendRequest(t, mod, otherMod.Username, nil))
+	expectStatus(t, rec, http.StatusForbidden)
+	rec = serveModerator(cfg, cfg.HandlerUnsuspendUser, suspendRequest(t, admin, otherMod.Username, nil))
+	expectStatus(t, rec, http.StatusNoContent)
+
+	// Resolving a report can't be used to get around the check.
+	alice := createTestUser(t, cfg, "alice")
+	report := createReport(t, cfg, alice, map[string]any{"username": admin.Username, "reason": "other"}, http.StatusCreated)
+	rec = httptest.NewRecorder()
+	cfg.RequireRole(auth.RoleModerator)(http.HandlerFunc(cfg.HandlerResolveReport)).ServeHTTP(rec,
+		reportRequest(t, mod, report.ID, map[string]string{"action": "suspend_user"}))
+	expectStatus(t, rec, http.StatusForbidden)
+	expectStatus(t, login(t, cfg, admin.Username, testPassword), http.StatusOK)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+func (cfg *Config) HandlerBlockUser(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	if username == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing username", nil)
+		return
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	// Look the target up without a viewer so that a user who has already
+	// been blocked by the target can still block them back.
+	target, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	if target.UserID == userID {
+		helpers.RespondWithError(w, http.StatusBadRequest, "You can't block yourself", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error creating transaction", err)
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	err = qtx.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerID: userID,
+		BlockedID: target.UserID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't block user", err)
+		return
+	}
+
+	err = qtx.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+		InitiatorID: userID,
+		TargetID:    target.UserID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't remove follows", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error committing transaction", err)
+		return
+	}
+
+	cfg.invalidateSuggestions(userID, target.UserID)
+
+	helpers.RespondWithJSON(w, http.StatusCreated, struct{}{})
+}
+
+func (cfg *Config) HandlerUnblockUser(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	if username == "" {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Missing username", nil)
+		return
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	target, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	err = cfg.DB.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: target.UserID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't unblock user", err)
+		return
+	}
+
+	cfg.invalidateSuggestions(userID, target.UserID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
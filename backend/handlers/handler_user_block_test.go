@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func createTestPost(t *testing.T, cfg *Config, author testUser, body string) {
+	t.Helper()
+	r := withToken(newRequest(t, http.MethodPost, "/v1/posts", map[string]string{"body": body}), author.Token)
+	expectStatus(t, serve(cfg.HandlerCreatePost, r), http.StatusCreated)
+}
+
+func timeline(t *testing.T, cfg *Config, viewer testUser) []Post {
+	t.Helper()
+	rec := serve(cfg.HandlerGetAllPosts, withToken(newRequest(t, http.MethodGet, "/v1/posts", nil), viewer.Token))
+	expectStatus(t, rec, http.StatusOK)
+	return decodeResponse[[]Post](t, rec)
+}
+
+func postedBy(posts []Post, user testUser) bool {
+	for _, post := range posts {
+		if post.UserID == user.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func blockUser(t *testing.T, cfg *Config, blocker, blocked testUser) {
+	t.Helper()
+	r := withURLParams(withToken(newRequest(t, http.MethodPost, "/", nil), blocker.Token), "username", blocked.Username)
+	expectStatus(t, serve(cfg.HandlerBlockUser, r), http.StatusCreated)
+}
+
+func TestBlockHidesBothWays(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	createTestPost(t, cfg, alice, "hello from alice")
+	createTestPost(t, cfg, bob, "hello from bob")
+
+	blockUser(t, cfg, alice, bob)
+
+	if postedBy(timeline(t, cfg, alice), bob) {
+		t.Error("blocker still sees the blocked user's posts")
+	}
+	if postedBy(timeline(t, cfg, bob), alice) {
+		t.Error("blocked user still sees the blocker's posts")
+	}
+
+	// The blocked user can't find the blocker at all; the blocker gets a bare
+	// profile so they can unblock.
+	r := withURLParams(withToken(newRequest(t, http.MethodGet, "/", nil), bob.Token), "username", alice.Username)
+	rec := serve(cfg.HandlerGetUser, r)
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[GetUserResponse](t, rec); got.Exists {
+		t.Errorf("blocked user sees blocker's profile: %+v", got)
+	}
+
+	r = withURLParams(withToken(newRequest(t, http.MethodGet, "/", nil), alice.Token), "username", bob.Username)
+	rec = serve(cfg.HandlerGetUser, r)
+	expectStatus(t, rec, http.StatusOK)
+	got := decodeResponse[GetUserResponse](t, rec)
+	if !got.Exists || got.Relationship == nil || !got.Relationship.Blocked {
+		t.Errorf("blocker's view of blocked user = %+v, want a blocked bare profile", got)
+	}
+}
+
+func TestBlockRemovesFollows(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	followUser(t, cfg, alice, bob, http.StatusCreated)
+	followUser(t, cfg, bob, alice, http.StatusCreated)
+
+	blockUser(t, cfg, bob, alice)
+
+	rel, err := cfg.getRelationship(t.Context(), alice.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Following || rel.FollowedBy || rel.Pending {
+		t.Errorf("relationship after block = %+v, want no follows", rel)
+	}
+
+	// Neither side can follow again while the block stands.
+	followUser(t, cfg, alice, bob, http.StatusNotFound)
+	followUser(t, cfg, bob, alice, http.StatusNotFound)
+}
+
+func TestUnblockUser(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	createTestPost(t, cfg, bob, "hello from bob")
+
+	blockUser(t, cfg, alice, bob)
+
+	r := withURLParams(withToken(newRequest(t, http.MethodDelete, "/", nil), alice.Token), "username", bob.Username)
+	rec := serve(cfg.HandlerUnblockUser, r)
+	expectStatus(t, rec, http.StatusNoContent)
+	if rec.Body.Len() != 0 {
+		t.Errorf("204 response has a body: %q", rec.Body.String())
+	}
+
+	if !postedBy(timeline(t, cfg, alice), bob) {
+		t.Error("posts still hidden after unblocking")
+	}
+	followUser(t, cfg, alice, bob, http.StatusCreated)
+}
+
+func TestBlockYourself(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+
+	r := withURLParams(withToken(newRequest(t, http.MethodPost, "/", nil), alice.Token), "username", alice.Username)
+	expectStatus(t, serve(cfg.HandlerBlockUser, r), http.StatusBadRequest)
+}
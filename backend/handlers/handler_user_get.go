@@ -3,27 +3,96 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/artyultra/tanglr/handlers/helpers"
 	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 )
 
+// GetUserResponse is a profile as seen by a particular viewer. Email and
+// preferences are only filled in for the profile's owner; everyone else gets
+// the public fields, and private accounts show non-followers only enough to
+// send a follow request.
 type GetUserResponse struct {
-	ID          uuid.UUID `json:"id,omitempty"`
-	Username    string    `json:"username,omitempty"`
-	Email       string    `json:"email,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	AvatarURL   string    `json:"avatar_url,omitempty"`
-	CoverURL    string    `json:"cover_url,omitempty"`
-	DarkMode    bool      `json:"dark_mode,omitempty"`
-	PrivateMode bool      `json:"private_mode,omitempty"`
-	Followers   int64     `json:"followers"`
-	Following   int64     `json:"following"`
-	Exists      bool      `json:"exists"`
+	ID           uuid.UUID     `json:"id,omitempty"`
+	Username     string        `json:"username,omitempty"`
+	DisplayName  string        `json:"display_name,omitempty"`
+	Bio          string        `json:"bio,omitempty"`
+	Location     string        `json:"location,omitempty"`
+	Links        []ProfileLink `json:"links,omitempty"`
+	Email        string        `json:"email,omitempty"`
+	CreatedAt    *time.Time    `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time    `json:"updated_at,omitempty"`
+	AvatarURL    string        `json:"avatar_url,omitempty"`
+	CoverURL     string        `json:"cover_url,omitempty"`
+	DarkMode     bool          `json:"dark_mode,omitempty"`
+	PrivateMode  bool          `json:"private_mode,omitempty"`
+	Followers    *int64        `json:"followers,omitempty"`
+	Following    *int64        `json:"following,omitempty"`
+	Restricted   bool          `json:"restricted,omitempty"`
+	Exists       bool          `json:"exists"`
+	Relationship *Relationship `json:"relationship,omitempty"`
+}
+
+// canViewProfile reports whether the viewer may see more than the minimal
+// profile of a user. Private accounts are only open to accepted followers.
+func canViewProfile(privateMode bool, rel Relationship) bool {
+	return !privateMode || rel.Following
+}
+
+func ownProfile(dbUser database.GetUserByUsernameRow, links []database.ProfileLink) GetUserResponse {
+	return GetUserResponse{
+		ID:          dbUser.UserID,
+		Username:    dbUser.Username,
+		DisplayName: dbUser.DisplayName,
+		Bio:         dbUser.Bio,
+		Location:    dbUser.Location,
+		Links:       profileLinks(links, false),
+		Email:       dbUser.Email,
+		CreatedAt:   &dbUser.UserCreatedAt,
+		UpdatedAt:   &dbUser.UserUpdatedAt,
+		AvatarURL:   dbUser.AvatarUrl.String,
+		CoverURL:    dbUser.CoverUrl.String,
+		DarkMode:    dbUser.DarkMode.Bool,
+		PrivateMode: dbUser.PrivateMode.Bool,
+		Followers:   &dbUser.FollowerCount,
+		Following:   &dbUser.FollowingCount,
+		Exists:      true,
+	}
+}
+
+func otherProfile(dbUser database.GetUserByUsernameRow, rel Relationship, links []database.ProfileLink) GetUserResponse {
+	if !canViewProfile(dbUser.PrivateMode.Bool, rel) {
+		return GetUserResponse{
+			ID:           dbUser.UserID,
+			Username:     dbUser.Username,
+			DisplayName:  dbUser.DisplayName,
+			AvatarURL:    dbUser.AvatarUrl.String,
+			Restricted:   true,
+			Exists:       true,
+			Relationship: &rel,
+		}
+	}
+
+	return GetUserResponse{
+		ID:           dbUser.UserID,
+		Username:     dbUser.Username,
+		DisplayName:  dbUser.DisplayName,
+		Bio:          dbUser.Bio,
+		Location:     dbUser.Location,
+		Links:        profileLinks(links, true),
+		CreatedAt:    &dbUser.UserCreatedAt,
+		AvatarURL:    dbUser.AvatarUrl.String,
+		CoverURL:     dbUser.CoverUrl.String,
+		Followers:    &dbUser.FollowerCount,
+		Following:    &dbUser.FollowingCount,
+		Exists:       true,
+		Relationship: &rel,
+	}
 }
 
 func (cfg *Config) HandlerGetUser(w http.ResponseWriter, r *http.Request) {
@@ -39,37 +108,113 @@ func (cfg *Config) HandlerGetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = auth.ValidateJWT(jwtToken, cfg.jwtSecret)
+	claims, err := auth.ValidateJWT(jwtToken, cfg.jwtKeys)
 	if err != nil {
 		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
 		return
 	}
 
-	dbUser, err := cfg.DB.GetUserByUsername(r.Context(), username)
+	viewerID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	db := cfg.reader(viewerID)
+	dbUser, err := db.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: username,
+		ViewerID: uuid.NullUUID{UUID: viewerID, Valid: true},
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			helpers.RespondWithJSON(w, http.StatusOK, User{Exists: false})
+			cfg.respondHiddenUser(w, r, viewerID, username)
 			return
 		}
 		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
 		return
 	}
 
-	user := GetUserResponse{
-		ID:          dbUser.UserID,
-		Email:       dbUser.Email,
-		Username:    dbUser.Username,
-		CreatedAt:   dbUser.UserCreatedAt,
-		UpdatedAt:   dbUser.UserCreatedAt,
-		AvatarURL:   dbUser.AvatarUrl.String,
-		CoverURL:    dbUser.CoverUrl.String,
-		DarkMode:    dbUser.DarkMode.Bool,
-		PrivateMode: dbUser.PrivateMode.Bool,
-		Followers:   dbUser.FollowerCount,
-		Following:   dbUser.FollowingCount,
-		Exists:      true,
+	links, err := db.ListProfileLinks(r.Context(), dbUser.UserID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get links", err)
+		return
 	}
 
+	if dbUser.UserID == viewerID {
+		helpers.RespondWithJSON(w, http.StatusOK, ownProfile(dbUser, links))
+		return
+	}
+
+	relationship, err := cfg.getRelationship(r.Context(), viewerID, dbUser.UserID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get relationship", err)
+		return
+	}
+
+	user := otherProfile(dbUser, relationship, links)
+
 	helpers.RespondWithJSON(w, http.StatusOK, user)
 
 }
+
+// respondHiddenUser answers for a profile that the viewer can't see. Users the
+// viewer has blocked still resolve to a bare profile so they can be unblocked;
+// anything else, including users who blocked the viewer, looks nonexistent.
+func (cfg *Config) respondHiddenUser(w http.ResponseWriter, r *http.Request, viewerID uuid.UUID, username string) {
+	dbUser, err := cfg.DB.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			cfg.respondRenamedUser(w, r, username)
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	relationship, err := cfg.getRelationship(r.Context(), viewerID, dbUser.UserID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get relationship", err)
+		return
+	}
+
+	if !relationship.Blocked {
+		helpers.RespondWithJSON(w, http.StatusOK, User{Exists: false})
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, GetUserResponse{
+		Username:     dbUser.Username,
+		Exists:       true,
+		Relationship: &relationship,
+	})
+}
+
+// respondRenamedUser redirects a username that was recently given up to the
+// account's current profile. Once the hold period ends the name is free and
+// looks like any other unknown user.
+func (cfg *Config) respondRenamedUser(w http.ResponseWriter, r *http.Request, username string) {
+	newUsername, err := cfg.DB.GetUsernameRedirect(r.Context(), username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			helpers.RespondWithJSON(w, http.StatusOK, User{Exists: false})
+			return
+		}
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	redirectRenamed(w, "/v1/users/", newUsername)
+}
+
+// redirectRenamed answers with a 307 to the path under prefix for the
+// account's new username.
+func redirectRenamed(w http.ResponseWriter, prefix, newUsername string) {
+	w.Header().Set("Location", prefix+url.PathEscape(newUsername))
+	helpers.RespondWithJSON(w, http.StatusTemporaryRedirect, struct {
+		Username string `json:"username"`
+	}{
+		Username: newUsername,
+	})
+}
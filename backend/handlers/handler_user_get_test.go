@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func getProfile(t *testing.T, cfg *Config, viewer testUser, username string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := withURLParams(withToken(newRequest(t, http.MethodGet, "/", nil), viewer.Token), "username", username)
+	return serve(cfg.HandlerGetUser, r)
+}
+
+// profileFields decodes a profile loosely so tests can check which fields
+// were sent at all.
+func profileFields(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	expectStatus(t, rec, http.StatusOK)
+	return decodeResponse[map[string]any](t, rec)
+}
+
+func TestOwnProfile(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	setPrivateMode(t, cfg, alice)
+
+	fields := profileFields(t, getProfile(t, cfg, alice, alice.Username))
+	if fields["email"] != alice.Email || fields["private_mode"] != true {
+		t.Errorf("own profile = %v, want email and preferences", fields)
+	}
+	if _, ok := fields["relationship"]; ok {
+		t.Errorf("own profile has a relationship: %v", fields)
+	}
+}
+
+func TestOtherProfileHidesPrivateFields(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	followUser(t, cfg, alice, bob, http.StatusCreated)
+
+	fields := profileFields(t, getProfile(t, cfg, bob, alice.Username))
+	for _, key := range []string{"email", "dark_mode", "private_mode", "updated_at"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("%s leaked to another user: %v", key, fields)
+		}
+	}
+	if fields["followers"] == nil || fields["restricted"] == true {
+		t.Errorf("public profile = %v, want counts and no restriction", fields)
+	}
+
+	got := decodeResponse[GetUserResponse](t, getProfile(t, cfg, bob, alice.Username))
+	if got.Relationship == nil || !got.Relationship.FollowedBy || got.Relationship.Following {
+		t.Errorf("relationship = %+v, want followed_by only", got.Relationship)
+	}
+}
+
+func TestPrivateProfile(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	setPrivateMode(t, cfg, alice)
+
+	fields := profileFields(t, getProfile(t, cfg, bob, alice.Username))
+	if fields["restricted"] != true {
+		t.Errorf("private profile for a non-follower = %v, want restricted", fields)
+	}
+	for _, key := range []string{"bio", "followers", "following", "created_at", "email"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("%s shown to a non-follower: %v", key, fields)
+		}
+	}
+
+	// A pending request doesn't open the profile; an accepted one does.
+	followUser(t, cfg, bob, alice, http.StatusCreated)
+	got := decodeResponse[GetUserResponse](t, getProfile(t, cfg, bob, alice.Username))
+	if !got.Restricted || got.Relationship == nil || !got.Relationship.Pending {
+		t.Errorf("profile with a pending request = %+v", got)
+	}
+
+	r := withURLParams(withToken(newRequest(t, http.MethodPost, "/", nil), alice.Token), "username", bob.Username)
+	expectStatus(t, serve(cfg.HandlerAcceptFollower, r), http.StatusNoContent)
+	fields = profileFields(t, getProfile(t, cfg, bob, alice.Username))
+	if fields["restricted"] == true || fields["followers"] == nil {
+		t.Errorf("profile for a follower = %v, want the public fields", fields)
+	}
+	if _, ok := fields["email"]; ok {
+		t.Errorf("email shown to a follower: %v", fields)
+	}
+}
+
+func TestUnknownProfile(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+
+	got := decodeResponse[GetUserResponse](t, getProfile(t, cfg, alice, "nobody"))
+	if got.Exists {
+		t.Errorf("unknown user = %+v, want exists=false", got)
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMuteHidesPostsOneWay(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	createTestPost(t, cfg, alice, "hello from alice")
+	createTestPost(t, cfg, bob, "hello from bob")
+
+	r := withURLParams(withToken(newRequest(t, http.MethodPost, "/", nil), alice.Token), "username", bob.Username)
+	expectStatus(t, serve(cfg.HandlerMuteUser, r), http.StatusCreated)
+
+	if postedBy(timeline(t, cfg, alice), bob) {
+		t.Error("muted user's posts still on the timeline")
+	}
+	if !postedBy(timeline(t, cfg, bob), alice) {
+		t.Error("muting hid the muter's posts from the muted user")
+	}
+
+	r = withURLParams(withToken(newRequest(t, http.MethodDelete, "/", nil), alice.Token), "username", bob.Username)
+	rec := serve(cfg.HandlerUnmuteUser, r)
+	expectStatus(t, rec, http.StatusNoContent)
+	if rec.Body.Len() != 0 {
+		t.Errorf("204 response has a body: %q", rec.Body.String())
+	}
+
+	if !postedBy(timeline(t, cfg, alice), bob) {
+		t.Error("posts still hidden after unmuting")
+	}
+}
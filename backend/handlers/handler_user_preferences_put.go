@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/artyultra/tanglr/handlers/helpers"
@@ -22,7 +21,7 @@ func (c *Config) HandlerPutAvatarUrl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	claims, err := auth.ValidateJWT(token, c.jwtSecret)
+	claims, err := auth.ValidateJWT(token, c.jwtKeys)
 	if err != nil {
 		helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid token", err)
 		return
@@ -35,11 +34,8 @@ func (c *Config) HandlerPutAvatarUrl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err = decoder.Decode(&params)
-	if err != nil {
-		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid parameters", err)
+	if !c.decodeJSON(w, r, &params) {
 		return
 	}
 
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/google/uuid"
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+type UpdateUsernameResponse struct {
+	ID               uuid.UUID `json:"id"`
+	Username         string    `json:"username"`
+	PreviousUsername string    `json:"previous_username"`
+	RedirectUntil    time.Time `json:"redirect_until"`
+	NextChangeAt     time.Time `json:"next_change_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Token            string    `json:"token"`
+}
+
+func (cfg *Config) HandlerUpdateUsername(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Username string `json:"username"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+		return
+	}
+
+	params := parameters{}
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	if !usernamePattern.MatchString(params.Username) {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Usernames must be 3-30 letters, numbers or underscores", nil)
+		return
+	}
+
+	user, err := cfg.DB.GetUserById(r.Context(), database.GetUserByIdParams{
+		ID: userID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	if user.Username == params.Username {
+		helpers.RespondWithError(w, http.StatusBadRequest, "That is already your username", nil)
+		return
+	}
+
+	lastChange, err := cfg.DB.GetLastUsernameChange(r.Context(), userID)
+	if err != nil && err != sql.ErrNoRows {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get username history", err)
+		return
+	}
+	if err == nil && time.Since(lastChange) < cfg.limits.UsernameChangeCooldown {
+		w.Header().Set("Retry-After", lastChange.Add(cfg.limits.UsernameChangeCooldown).UTC().Format(http.TimeFormat))
+		helpers.RespondWithError(w, http.StatusTooManyRequests, "You changed your username recently", nil)
+		return
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error creating transaction", err)
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	available, err := qtx.IsUsernameAvailable(r.Context(), database.IsUsernameAvailableParams{
+		Username: params.Username,
+		UserID:   userID,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't check username", err)
+		return
+	}
+	if !available {
+		helpers.RespondWithError(w, http.StatusConflict, "Username is taken", nil)
+		return
+	}
+
+	// Taking back a name the user is still holding ends its redirect. Only the
+	// user's own hold is released; a name someone else gave up stays reserved
+	// until its released_at, so IsUsernameAvailable has already refused it
+	// above and the old owner's redirect can't be taken over.
+	err = qtx.ReleaseUsername(r.Context(), database.ReleaseUsernameParams{
+		UserID:      userID,
+		OldUsername: params.Username,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't update username history", err)
+		return
+	}
+
+	redirectUntil := time.Now().Add(cfg.limits.UsernameHoldPeriod)
+
+	err = qtx.RecordUsernameChange(r.Context(), database.RecordUsernameChangeParams{
+		UserID:      userID,
+		OldUsername: user.Username,
+		ReleasedAt:  redirectUntil,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't update username history", err)
+		return
+	}
+
+	updated, err := qtx.UpdateUsername(r.Context(), database.UpdateUsernameParams{
+		ID:       userID,
+		Username: params.Username,
+	})
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusConflict, "Username is taken", err)
+		return
+	}
+
+	// The backlinks that verified the user's links point at the old profile
+	// URL, so they prove nothing about the new one.
+	err = qtx.ClearProfileLinkVerification(r.Context(), userID)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't update links", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Error committing transaction", err)
+		return
+	}
+
+	tokenString, err := auth.MakeJWT(userID, updated.Username, auth.Role(user.Role), cfg.jwtKeys, cfg.tokens.AccessTTL)
+	if err != nil {
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't create token", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, UpdateUsernameResponse{
+		ID:               updated.ID,
+		Username:         updated.Username,
+		PreviousUsername: user.Username,
+		RedirectUntil:    redirectUntil,
+		NextChangeAt:     time.Now().Add(cfg.limits.UsernameChangeCooldown),
+		UpdatedAt:        updated.UpdatedAt,
+		Token:            tokenString,
+	})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func renameUser(t *testing.T, cfg *Config, user testUser, username string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := withToken(newRequest(t, http.MethodPatch, "/v1/users/me/username", map[string]string{"username": username}), user.Token)
+	return serve(cfg.HandlerUpdateUsername, r)
+}
+
+func signUp(t *testing.T, cfg *Config, username string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := newRequest(t, http.MethodPost, "/v1/users", map[string]string{
+		"username": username,
+		"email":    "signup@example.com",
+		"password": testPassword,
+	})
+	return serve(cfg.HandlerCreateUser, r)
+}
+
+func TestUsernamePattern(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+
+	for _, name := range []string{"ab", "has space", "dash-name", "émile", "a_name_that_is_far_too_long_to_fit"} {
+		t.Run(name, func(t *testing.T) {
+			expectStatus(t, signUp(t, cfg, name), http.StatusBadRequest)
+			expectStatus(t, renameUser(t, cfg, alice, name), http.StatusBadRequest)
+		})
+	}
+
+	expectStatus(t, signUp(t, cfg, "New_User_42"), http.StatusCreated)
+}
+
+func TestRenameRedirectsAndHoldsOldName(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	rec := renameUser(t, cfg, alice, "alice_2")
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[UpdateUsernameResponse](t, rec); got.PreviousUsername != "alice" || got.Token == "" {
+		t.Errorf("rename response = %+v", got)
+	}
+
+	rec = getProfile(t, cfg, bob, "alice")
+	expectStatus(t, rec, http.StatusTemporaryRedirect)
+	if got := rec.Header().Get("Location"); got != "/v1/users/alice_2" {
+		t.Errorf("Location = %q, want /v1/users/alice_2", got)
+	}
+
+	r := withURLParams(withToken(newRequest(t, http.MethodGet, "/", nil), bob.Token), "username", "alice")
+	rec = serve(cfg.HandlerGetAllUserPosts, r)
+	expectStatus(t, rec, http.StatusTemporaryRedirect)
+	if got := rec.Header().Get("Location"); got != "/v1/posts/alice_2" {
+		t.Errorf("posts Location = %q, want /v1/posts/alice_2", got)
+	}
+
+	// Nobody else can take the redirect over while it's held.
+	expectStatus(t, signUp(t, cfg, "alice"), http.StatusConflict)
+	expectStatus(t, renameUser(t, cfg, bob, "alice"), http.StatusConflict)
+
+	// Once the hold ends the name is free again.
+	_, err := cfg.DBConn.Exec("UPDATE username_history SET released_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-1 hour')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeResponse[GetUserResponse](t, getProfile(t, cfg, bob, "alice")); got.Exists {
+		t.Errorf("expired name = %+v, want exists=false", got)
+	}
+	expectStatus(t, renameUser(t, cfg, bob, "alice"), http.StatusOK)
+}
+
+func TestUsernamesIgnoreCase(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	expectStatus(t, signUp(t, cfg, "ALICE"), http.StatusConflict)
+	expectStatus(t, renameUser(t, cfg, bob, "Alice"), http.StatusConflict)
+
+	// A held name is held in every case.
+	expectStatus(t, renameUser(t, cfg, alice, "alice_2"), http.StatusOK)
+	expectStatus(t, renameUser(t, cfg, bob, "Alice"), http.StatusConflict)
+
+	// The index catches anything that gets past the availability check.
+	_, err := cfg.DBConn.Exec("UPDATE users SET username = 'ALICE_2' WHERE id = ?", bob.ID.String())
+	if err == nil {
+		t.Error("stored a username differing only in case")
+	}
+}
+
+func TestRenameCooldown(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg, "alice")
+
+	expectStatus(t, renameUser(t, cfg, alice, "alice_2"), http.StatusOK)
+	rec := renameUser(t, cfg, alice, "alice_3")
+	expectStatus(t, rec, http.StatusTooManyRequests)
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("cooldown response has no Retry-After header")
+	}
+}
+
+func TestOwnerCanReclaimHeldName(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.limits.UsernameChangeCooldown = 0
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	expectStatus(t, renameUser(t, cfg, alice, "alice_2"), http.StatusOK)
+	expectStatus(t, renameUser(t, cfg, alice, "alice"), http.StatusOK)
+
+	// Taking the name back ends its redirect; alice_2 is now the held one.
+	got := decodeResponse[GetUserResponse](t, getProfile(t, cfg, bob, "alice"))
+	if !got.Exists || got.Username != "alice" {
+		t.Errorf("reclaimed profile = %+v", got)
+	}
+	expectStatus(t, getProfile(t, cfg, bob, "alice_2"), http.StatusTemporaryRedirect)
+	expectStatus(t, renameUser(t, cfg, bob, "alice_2"), http.StatusConflict)
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"time"
 
@@ -38,11 +37,26 @@ func (cfg *Config) HandlerCreateUser(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err := decoder.Decode(&params)
+	if !cfg.decodeJSON(w, r, &params) {
+		return
+	}
+
+	if !usernamePattern.MatchString(params.Username) {
+		helpers.RespondWithError(w, http.StatusBadRequest, "Usernames must be 3-30 letters, numbers or underscores", nil)
+		return
+	}
+
+	available, err := cfg.DB.IsUsernameAvailable(r.Context(), database.IsUsernameAvailableParams{
+		Username: params.Username,
+		UserID:   uuid.Nil,
+	})
 	if err != nil {
-		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid request", err)
+		helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't check username", err)
+		return
+	}
+	if !available {
+		helpers.RespondWithError(w, http.StatusConflict, "Username is taken", nil)
 		return
 	}
 
@@ -2,20 +2,120 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/cache"
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/linkcheck"
+	"github.com/artyultra/tanglr/internal/notify"
+	"github.com/artyultra/tanglr/internal/ratelimit"
+	"github.com/artyultra/tanglr/internal/sso"
+	"github.com/artyultra/tanglr/internal/store"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
 )
 
 type Config struct {
-	DB        *database.Queries
-	DBConn    *sql.DB
-	jwtSecret string
+	DB          store.Repository
+	DBConn      *sql.DB
+	replicas    *store.ReplicaSet
+	replicaPoll time.Duration
+	// stickyUsers holds the IDs of users whose reads stay on the primary
+	// because they just wrote something.
+	stickyUsers *cache.TTL[string, struct{}]
+	jwtSecret   string
+	jwtKeys     *auth.Keyset
+	environment string
+	publicURL   string
+	tokens      config.Tokens
+	features    config.Features
+	limits      config.Limits
+	rateLimits  config.RateLimits
+	login       config.Login
+	notifier    notify.Notifier
+	passkeys    *webauthn.WebAuthn
+	sso         map[string]*sso.Provider
+	limiter     ratelimit.Store
+	suggestions *cache.TTL[uuid.UUID, []Suggestion]
+	links       *linkcheck.Verifier
+	draining    atomic.Bool
 }
 
-func NewConfig(db *database.Queries, dbConn *sql.DB, jwtSecret string) *Config {
+// NewConfig builds the handler config. replicas may be nil, in which case
+// every read goes to db.
+func NewConfig(db store.Repository, dbConn *sql.DB, replicas *store.ReplicaSet, appCfg *config.Config) *Config {
 	return &Config{
-		DB:        db,
-		DBConn:    dbConn,
-		jwtSecret: jwtSecret,
+		DB:          db,
+		DBConn:      dbConn,
+		replicas:    replicas,
+		replicaPoll: appCfg.Database.ReplicaHealthInterval,
+		stickyUsers: cache.NewTTL[string, struct{}](appCfg.Database.ReplicaStickiness, 100000),
+		jwtSecret:   appCfg.JWTSecret,
+		jwtKeys:     auth.NewKeyset(appCfg.JWTSecret, appCfg.Tokens.Algorithm, appCfg.Tokens.AcceptHMAC),
+		environment: appCfg.Environment,
+		publicURL:   appCfg.PublicURL,
+		tokens:      appCfg.Tokens,
+		features:    appCfg.Features,
+		limits:      appCfg.Limits,
+		rateLimits:  appCfg.RateLimits,
+		login:       appCfg.Login,
+		notifier:    notify.New(appCfg.Notify.WebhookURL, appCfg.Notify.WebhookSecret),
+		passkeys:    newWebAuthn(appCfg.WebAuthn),
+		sso:         newSSOProviders(appCfg.OIDC),
+		limiter:     newRateLimitStore(appCfg.RateLimits.Store, db),
+		suggestions: cache.NewTTL[uuid.UUID, []Suggestion](appCfg.Limits.SuggestionsCacheTTL, 10000),
+		links:       linkcheck.NewVerifier(linkcheck.NewHTTPClient(10 * time.Second)),
 	}
 }
+
+// decodeJSON reads the request body into dst, refusing bodies larger than the
+// configured limit. It writes the error response itself and returns false when
+// the request should stop.
+func (cfg *Config) decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, cfg.limits.MaxBodyBytes))
+	err := decoder.Decode(dst)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			helpers.RespondWithError(w, http.StatusRequestEntityTooLarge, "Request body too large", err)
+			return false
+		}
+		helpers.RespondWithError(w, http.StatusBadRequest, "Invalid request", err)
+		return false
+	}
+	return true
+}
+
+// clientIP returns the address a request came from: the last
+// X-Forwarded-For entry when the server trusts its proxy, otherwise the
+// connection's remote address.
+func (cfg *Config) clientIP(r *http.Request) string {
+	addr := ""
+	if cfg.rateLimits.TrustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			addr = strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+	if addr == "" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		addr = host
+	}
+
+	if ip, err := netip.ParseAddr(addr); err == nil {
+		return ip.Unmap().String()
+	}
+	return addr
+}
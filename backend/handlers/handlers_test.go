@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/artyultra/tanglr/internal/config"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	cfg := newTestConfig(t, func(c *config.Config) { c.Limits.MaxBodyBytes = 64 })
+
+	tests := []struct {
+		name   string
+		body   string
+		ok     bool
+		status int
+	}{
+		{name: "within the limit", body: `{"body": "hello"}`, ok: true, status: http.StatusOK},
+		{name: "over the limit", body: `{"body": "` + strings.Repeat("x", 64) + `"}`, status: http.StatusRequestEntityTooLarge},
+		{name: "malformed", body: `{"body": `, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			var dst struct {
+				Body string `json:"body"`
+			}
+			if ok := cfg.decodeJSON(rec, r, &dst); ok != tt.ok {
+				t.Fatalf("decodeJSON() = %v, want %v", ok, tt.ok)
+			}
+			expectStatus(t, rec, tt.status)
+		})
+	}
+}
+
+// Every handler that reads JSON goes through decodeJSON, so an oversized body
+// is refused before any work is done.
+func TestHandlersRefuseOversizedBodies(t *testing.T) {
+	cfg := newTestConfig(t, func(c *config.Config) { c.Limits.MaxBodyBytes = 1024 })
+	alice := createTestUser(t, cfg, "alice")
+	huge := map[string]string{"username": "bob", "body": strings.Repeat("x", 2048)}
+
+	expectStatus(t, serve(cfg.HandlerCreateUser, newRequest(t, http.MethodPost, "/v1/users", huge)), http.StatusRequestEntityTooLarge)
+	expectStatus(t, serve(cfg.HandlerCreatePost, withToken(newRequest(t, http.MethodPost, "/v1/posts", huge), alice.Token)), http.StatusRequestEntityTooLarge)
+	expectStatus(t, serve(cfg.HandlerLogin, newRequest(t, http.MethodPost, "/v1/login", huge)), http.StatusRequestEntityTooLarge)
+}
@@ -2,16 +2,22 @@ package helpers
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
+// errorRecorder is implemented by the logging middleware's response writer.
+// Errors handed to it are logged on the request's log line along with the
+// request ID, route and user.
+type errorRecorder interface {
+	RecordError(msg string, err error)
+}
+
 func RespondWithError(w http.ResponseWriter, status int, msg string, logError error) {
-	if logError != nil {
-		log.Println(logError)
-	}
-	if status > 499 {
-		log.Printf("Responding with 5XX error: %s", msg)
+	if rec, ok := w.(errorRecorder); ok {
+		rec.RecordError(msg, logError)
+	} else if logError != nil || status > 499 {
+		slog.Error("responding with error", "status", status, "error_message", msg, "error", logError)
 	}
 	type errorResponse struct {
 		Error string `json:"error"`
@@ -23,15 +29,14 @@ func RespondWithJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	data, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("Error marshalling response: %v", err)
+		slog.Error("error marshalling response", "error", err)
 		w.WriteHeader(500)
 		return
 	}
 	w.WriteHeader(status)
 	_, err = w.Write(data)
 	if err != nil {
-		log.Printf("Error writing response: %v", err)
-		w.WriteHeader(500)
+		slog.Error("error writing response", "error", err)
 		return
 	}
 
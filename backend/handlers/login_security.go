@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/internal/logging"
+	"github.com/artyultra/tanglr/internal/notify"
+	"github.com/google/uuid"
+)
+
+// Reasons a login attempt failed, as stored in login_attempts.failure_reason.
+const (
+	loginUnknownUser   = "unknown_user"
+	loginWrongPassword = "wrong_password"
+	loginLocked        = "locked"
+	loginSuspended     = "suspended"
+	loginMFAPending    = "mfa_pending"
+	loginWrongCode     = "wrong_mfa_code"
+	loginBadPasskey    = "invalid_passkey"
+	loginClonedPasskey = "cloned_passkey"
+)
+
+// Bounds on what is stored with each login attempt, since both come straight
+// from the request.
+const (
+	maxAttemptUsernameLength = 128
+	maxUserAgentLength       = 512
+)
+
+// respondInvalidLogin is the single answer for an unknown username, a wrong
+// password and a locked account, so a caller can't learn which accounts
+// exist or are locked.
+func respondInvalidLogin(w http.ResponseWriter) {
+	helpers.RespondWithError(w, http.StatusUnauthorized, "Invalid username or password", nil)
+}
+
+// loginSource identifies where a login attempt came from.
+type loginSource struct {
+	ip        string
+	userAgent string
+}
+
+func (cfg *Config) loginSource(r *http.Request) loginSource {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return loginSource{ip: cfg.clientIP(r), userAgent: userAgent}
+}
+
+// recordLoginAttempt writes the attempt to login_attempts. The login goes
+// ahead even if that fails; the error is only logged.
+func (cfg *Config) recordLoginAttempt(ctx context.Context, src loginSource, username string, userID uuid.UUID, failureReason string, newDevice bool) {
+	if len(username) > maxAttemptUsernameLength {
+		username = username[:maxAttemptUsernameLength]
+	}
+	err := cfg.DB.RecordLoginAttempt(ctx, database.RecordLoginAttemptParams{
+		UserID:        uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		Username:      username,
+		Succeeded:     failureReason == "",
+		FailureReason: failureReason,
+		IpAddress:     src.ip,
+		UserAgent:     src.userAgent,
+		NewDevice:     newDevice,
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to record login attempt", "error", err)
+	}
+}
+
+// isLoginLocked reports whether userID is in a lockout.
+func (cfg *Config) isLoginLocked(ctx context.Context, userID uuid.UUID) (bool, error) {
+	lockout, err := cfg.DB.GetLoginLockout(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return lockout.LockedUntil.Valid && lockout.LockedUntil.Time.After(time.Now()), nil
+}
+
+// registerFailedLogin counts a wrong password against userID and locks the
+// account once the failures in the window reach the limit.
+func (cfg *Config) registerFailedLogin(ctx context.Context, userID uuid.UUID) error {
+	failures, err := cfg.DB.RecordFailedLogin(ctx, database.RecordFailedLoginParams{
+		UserID:      userID,
+		WindowStart: time.Now().Add(-cfg.login.FailureWindow),
+	})
+	if err != nil {
+		return err
+	}
+
+	lockout := lockoutDuration(int(failures), cfg.login.MaxFailures, cfg.login.LockoutBase, cfg.login.LockoutMax)
+	if lockout == 0 {
+		return nil
+	}
+
+	logging.FromContext(ctx).Warn("locking account after failed logins", "user_id", userID, "failures", failures, "locked_for", lockout.String())
+	return cfg.DB.LockLogin(ctx, database.LockLoginParams{
+		UserID:      userID,
+		LockedUntil: sql.NullTime{Time: time.Now().Add(lockout), Valid: true},
+	})
+}
+
+// lockoutDuration is how long an account is locked after failures
+// consecutive failures: nothing below maxFailures, then base, doubling with
+// each further failure up to max.
+func lockoutDuration(failures, maxFailures int, base, max time.Duration) time.Duration {
+	if failures < maxFailures {
+		return 0
+	}
+	lockout := base
+	for i := maxFailures; i < failures && lockout < max; i++ {
+		lockout *= 2
+	}
+	return min(lockout, max)
+}
+
+// checkLoginSource compares a successful login with the user's earlier ones.
+// The first login ever isn't flagged since there is nothing to compare with.
+func (cfg *Config) checkLoginSource(ctx context.Context, userID uuid.UUID, src loginSource) (newIP, newDevice bool, err error) {
+	known, err := cfg.DB.GetKnownLoginSource(ctx, database.GetKnownLoginSourceParams{
+		UserID:    uuid.NullUUID{UUID: userID, Valid: true},
+		IpAddress: src.ip,
+		UserAgent: src.userAgent,
+	})
+	if err != nil || !known.HasHistory {
+		return false, false, err
+	}
+	return !known.KnownIp, !known.KnownDevice, nil
+}
+
+// notifyNewLogin tells the user about a login from a new IP or device. It
+// runs in the background so a slow webhook doesn't hold up the login.
+func (cfg *Config) notifyNewLogin(ctx context.Context, user database.GetUserByUsernameRow, src loginSource, newIP, newDevice bool) {
+	n := notify.Notification{
+		Kind:      notify.KindNewLogin,
+		UserID:    user.UserID,
+		Username:  user.Username,
+		Email:     user.Email,
+		IPAddress: src.ip,
+		UserAgent: src.userAgent,
+		NewIP:     newIP,
+		NewDevice: newDevice,
+		At:        time.Now().UTC(),
+	}
+	logger := logging.FromContext(ctx)
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		if err := cfg.notifier.Notify(ctx, n); err != nil {
+			logger.Error("failed to send new login notification", "user_id", n.UserID, "error", err)
+		}
+	}()
+}
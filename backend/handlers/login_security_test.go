@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/notify"
+)
+
+// recordingNotifier collects notifications instead of sending them.
+type recordingNotifier struct {
+	sent chan notify.Notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, notification notify.Notification) error {
+	n.sent <- notification
+	return nil
+}
+
+func withLockout(c *config.Config) {
+	c.Login.MaxFailures = 3
+	c.Login.LockoutBase = time.Minute
+	c.Login.LockoutMax = 4 * time.Minute
+}
+
+// expireLockout ends the user's lockout as if its time had run out.
+func expireLockout(t *testing.T, cfg *Config, user testUser) {
+	t.Helper()
+	_, err := cfg.DBConn.Exec("UPDATE login_lockouts SET locked_until = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-1 second') WHERE user_id = ?", user.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// lockedFor returns how much of the user's lockout is left.
+func lockedFor(t *testing.T, cfg *Config, user testUser) time.Duration {
+	t.Helper()
+	lockout, err := cfg.DB.GetLoginLockout(context.Background(), user.ID)
+	if err == sql.ErrNoRows || (err == nil && !lockout.LockedUntil.Valid) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return time.Until(lockout.LockedUntil.Time)
+}
+
+func expectLockedFor(t *testing.T, cfg *Config, user testUser, want time.Duration) {
+	t.Helper()
+	got := lockedFor(t, cfg, user)
+	if got > want || got < want-10*time.Second {
+		t.Errorf("locked for %v, want %v", got.Round(time.Second), want)
+	}
+}
+
+func TestLockoutDuration(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 0},
+		{failures: 4, want: 0},
+		{failures: 5, want: time.Minute},
+		{failures: 6, want: 2 * time.Minute},
+		{failures: 8, want: 8 * time.Minute},
+		{failures: 9, want: 10 * time.Minute},
+		{failures: 100, want: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := lockoutDuration(tt.failures, 5, time.Minute, 10*time.Minute); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginLockoutBacksOff(t *testing.T) {
+	cfg := newTestConfig(t, withLockout)
+	alice := createTestUser(t, cfg, "alice")
+
+	for range 2 {
+		expectStatus(t, login(t, cfg, "alice", "wrong"), http.StatusUnauthorized)
+	}
+	expectLockedFor(t, cfg, alice, 0)
+
+	expectStatus(t, login(t, cfg, "alice", "wrong"), http.StatusUnauthorized)
+	expectLockedFor(t, cfg, alice, time.Minute)
+
+	// The right password doesn't get through a lockout.
+	expectStatus(t, login(t, cfg, "alice", testPassword), http.StatusUnauthorized)
+
+	// Each failure after the lockout ends doubles the next one, up to the
+	// maximum.
+	for _, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		expireLockout(t, cfg, alice)
+		expectStatus(t, login(t, cfg, "alice", "wrong"), http.StatusUnauthorized)
+		expectLockedFor(t, cfg, alice, want)
+	}
+
+	expireLockout(t, cfg, alice)
+	expectStatus(t, login(t, cfg, "alice", testPassword), http.StatusOK)
+	if _, err := cfg.DB.GetLoginLockout(context.Background(), alice.ID); err != sql.ErrNoRows {
+		t.Errorf("lockout after a successful login: %v, want it cleared", err)
+	}
+}
+
+func TestLoginFailuresOutsideWindowReset(t *testing.T) {
+	cfg := newTestConfig(t, withLockout)
+	alice := createTestUser(t, cfg, "alice")
+
+	for range 2 {
+		expectStatus(t, login(t, cfg, "alice", "wrong"), http.StatusUnauthorized)
+	}
+	_, err := cfg.DBConn.Exec("UPDATE login_lockouts SET updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-1 day')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectStatus(t, login(t, cfg, "alice", "wrong"), http.StatusUnauthorized)
+	expectLockedFor(t, cfg, alice, 0)
+}
+
+func TestNewLoginSourceNotifies(t *testing.T) {
+	cfg := newTestConfig(t)
+	notifier := &recordingNotifier{sent: make(chan notify.Notification, 1)}
+	cfg.notifier = notifier
+	createTestUser(t, cfg, "alice")
+
+	loginFrom := func(addr, userAgent string) {
+		t.Helper()
+		r := newRequest(t, http.MethodPost, "/v1/login", map[string]string{"username": "alice", "password": testPassword})
+		r.RemoteAddr = addr
+		r.Header.Set("User-Agent", userAgent)
+		expectStatus(t, serve(cfg.HandlerLogin, r), http.StatusOK)
+	}
+	expectNotification := func(want bool) notify.Notification {
+		t.Helper()
+		select {
+		case n := <-notifier.sent:
+			if !want {
+				t.Fatalf("unexpected notification %+v", n)
+			}
+			return n
+		case <-time.After(200 * time.Millisecond):
+			if want {
+				t.Fatal("no notification sent")
+			}
+			return notify.Notification{}
+		}
+	}
+
+	// The first login has nothing to compare with.
+	loginFrom("203.0.113.1:1000", "Firefox")
+	expectNotification(false)
+	loginFrom("203.0.113.1:2000", "Firefox")
+	expectNotification(false)
+
+	loginFrom("198.51.100.7:1000", "Firefox")
+	if n := expectNotification(true); !n.NewIP || n.NewDevice || n.IPAddress != "198.51.100.7" {
+		t.Errorf("notification = %+v, want a new IP only", n)
+	}
+
+	loginFrom("203.0.113.1:1000", "curl")
+	if n := expectNotification(true); n.NewIP || !n.NewDevice {
+		t.Errorf("notification = %+v, want a new device only", n)
+	}
+
+	var flagged int
+	if err := cfg.DBConn.QueryRow("SELECT count(*) FROM login_attempts WHERE new_device").Scan(&flagged); err != nil {
+		t.Fatal(err)
+	}
+	if flagged != 2 {
+		t.Errorf("%d attempts flagged as new, want 2", flagged)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/google/uuid"
+)
+
+// RequireRole returns route middleware that validates the bearer token and
+// rejects callers whose role is not one of roles. The validated claims are
+// stored in the request context for the wrapped handler.
+//
+// The role is read from the database rather than trusted from the token, so
+// demoting a user takes their access away at once instead of when their
+// access token expires.
+func (cfg *Config) RequireRole(roles ...auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := auth.GetBearerToken(r.Header)
+			if err != nil {
+				helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+				return
+			}
+
+			claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+			if err != nil {
+				helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+				return
+			}
+
+			userID, err := uuid.Parse(claims.Subject)
+			if err != nil {
+				helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+				return
+			}
+
+			role, err := cfg.DB.GetUserRole(r.Context(), userID)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					helpers.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: missing or invalid token", err)
+					return
+				}
+				helpers.RespondWithError(w, http.StatusInternalServerError, "Couldn't get user role", err)
+				return
+			}
+			claims.Role = auth.Role(role)
+
+			if !slices.Contains(roles, claims.Role) {
+				helpers.RespondWithError(w, http.StatusForbidden, "Forbidden: insufficient role", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// userIDFromContext returns the subject of the claims stored by RequireRole.
+func userIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("no claims in request context")
+	}
+	return uuid.Parse(claims.Subject)
+}
+
+// RequestUserID returns the subject of a valid bearer token, or "" when the
+// request is anonymous. The logging middleware uses it to tag log lines.
+func (cfg *Config) RequestUserID(r *http.Request) string {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return ""
+	}
+	claims, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		return ""
+	}
+	return claims.Subject
+}
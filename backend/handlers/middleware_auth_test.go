@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/database"
+)
+
+func requireAdmin(cfg *Config, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, found := auth.ClaimsFromContext(r.Context()); !found {
+			http.Error(w, "no claims", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	cfg.RequireRole(auth.RoleAdmin)(ok).ServeHTTP(rec, r)
+	return rec
+}
+
+func TestRequireRole(t *testing.T) {
+	cfg := newTestConfig(t)
+	admin := createTestUserWithRole(t, cfg, "admin", auth.RoleAdmin)
+	alice := createTestUser(t, cfg, "alice")
+
+	expectStatus(t, requireAdmin(cfg, withToken(newRequest(t, http.MethodGet, "/", nil), admin.Token)), http.StatusOK)
+	expectStatus(t, requireAdmin(cfg, withToken(newRequest(t, http.MethodGet, "/", nil), alice.Token)), http.StatusForbidden)
+	expectStatus(t, requireAdmin(cfg, newRequest(t, http.MethodGet, "/", nil)), http.StatusUnauthorized)
+	expectStatus(t, requireAdmin(cfg, withToken(newRequest(t, http.MethodGet, "/", nil), "not-a-token")), http.StatusUnauthorized)
+}
+
+func TestRequireRoleUsesStoredRole(t *testing.T) {
+	cfg := newTestConfig(t)
+	admin := createTestUserWithRole(t, cfg, "admin", auth.RoleAdmin)
+	alice := createTestUser(t, cfg, "alice")
+
+	// A token minted before a promotion still says "user".
+	_, err := cfg.DB.UpdateUserRole(context.Background(), database.UpdateUserRoleParams{Username: alice.Username, Role: string(auth.RoleAdmin)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, requireAdmin(cfg, withToken(newRequest(t, http.MethodGet, "/", nil), alice.Token)), http.StatusOK)
+
+	// A demoted admin loses access while their token still claims admin.
+	_, err = cfg.DB.UpdateUserRole(context.Background(), database.UpdateUserRoleParams{Username: admin.Username, Role: string(auth.RoleUser)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, requireAdmin(cfg, withToken(newRequest(t, http.MethodGet, "/", nil), admin.Token)), http.StatusForbidden)
+
+	// So does a deleted one.
+	if err := cfg.DB.DeleteUser(context.Background(), alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, requireAdmin(cfg, withToken(newRequest(t, http.MethodGet, "/", nil), alice.Token)), http.StatusUnauthorized)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/logging"
+	"github.com/artyultra/tanglr/internal/metrics"
+	"github.com/artyultra/tanglr/internal/ratelimit"
+	"github.com/artyultra/tanglr/internal/store"
+)
+
+// RateLimitHeaders are the response headers RateLimit sets, for CORS to
+// expose to browsers.
+var RateLimitHeaders = []string{"Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"}
+
+// rateLimitRule is one bucket a route draws from. key picks the bucket for a
+// request; an empty key skips the rule, e.g. for anonymous users.
+type rateLimitRule struct {
+	name  string
+	limit ratelimit.Limit
+	key   func(r *http.Request) string
+}
+
+func newRateLimitStore(kind string, db store.Repository) ratelimit.Store {
+	if kind == "database" {
+		return ratelimit.NewDatabaseStore(db)
+	}
+	return ratelimit.NewMemoryStore()
+}
+
+func (cfg *Config) rateLimitRules(route string) []rateLimitRule {
+	limits, ok := cfg.rateLimits.Routes[route]
+	if !cfg.rateLimits.Enabled || !ok {
+		return nil
+	}
+
+	var rules []rateLimitRule
+	add := func(name string, rate config.Rate, key func(r *http.Request) string) {
+		if rate.Enabled() {
+			rules = append(rules, rateLimitRule{
+				name:  name,
+				limit: ratelimit.Limit{Requests: rate.Requests, Per: rate.Per},
+				key:   key,
+			})
+		}
+	}
+	add("ip", limits.IP, cfg.ipBucket)
+	add("user", limits.User, cfg.RequestUserID)
+	add("username", limits.Username, cfg.bodyUsername)
+	return rules
+}
+
+// RateLimit returns middleware that takes a token from each of route's buckets
+// and answers 429 once any of them is empty. The limits come from the
+// rate_limits config; a route with none configured isn't limited.
+//
+// Responses carry RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy for the bucket closest to empty, and Retry-After when
+// refused. If the bucket store fails the request is let through.
+func (cfg *Config) RateLimit(route string) func(http.Handler) http.Handler {
+	rules := cfg.rateLimitRules(route)
+	if len(rules) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var tightest *ratelimit.Result
+			for _, rule := range rules {
+				key := rule.key(r)
+				if key == "" {
+					continue
+				}
+
+				res, err := cfg.limiter.Take(r.Context(), route+":"+rule.name+":"+key, rule.limit)
+				if err != nil {
+					logging.FromContext(r.Context()).Error("rate limit store failed, allowing request", "route", route, "rule", rule.name, "error", err)
+					continue
+				}
+
+				if !res.Allowed {
+					metrics.RateLimited.WithLabelValues(route, rule.name).Inc()
+					setRateLimitHeaders(w.Header(), res)
+					w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+					helpers.RespondWithError(w, http.StatusTooManyRequests, "Too many requests, try again later", nil)
+					return
+				}
+				if tightest == nil || res.Remaining < tightest.Remaining {
+					tightest = &res
+				}
+			}
+
+			if tightest != nil {
+				setRateLimitHeaders(w.Header(), *tightest)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setRateLimitHeaders(h http.Header, res ratelimit.Result) {
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit.Requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	h.Set("RateLimit-Policy", strconv.Itoa(res.Limit.Requests)+";w="+strconv.Itoa(ceilSeconds(res.Limit.Per)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ipBucket keys the per-IP bucket. IPv6 clients usually control a whole
+// /64, so their address is cut down to that prefix.
+func (cfg *Config) ipBucket(r *http.Request) string {
+	addr := cfg.clientIP(r)
+	ip, err := netip.ParseAddr(addr)
+	if err != nil || !ip.Is6() {
+		return addr
+	}
+	prefix, _ := ip.Prefix(64)
+	return prefix.String()
+}
+
+// bodyUsername returns the lowercased "username" field of a JSON request body
+// and puts the body back for the handler to read.
+func (cfg *Config) bodyUsername(r *http.Request) string {
+	body, err := io.ReadAll(io.LimitReader(r.Body, cfg.limits.MaxBodyBytes))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil {
+		return ""
+	}
+
+	var params struct {
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal(body, &params); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(params.Username))
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/config"
+)
+
+// withRateLimits replaces the default routes with routes.
+func withRateLimits(routes map[string]config.RouteLimits) func(*config.Config) {
+	return func(c *config.Config) {
+		c.RateLimits.Routes = routes
+	}
+}
+
+// limitedLogin sends a wrong password for username from remoteAddr through the
+// login rate limiter.
+func limitedLogin(t *testing.T, cfg *Config, username, remoteAddr string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := newRequest(t, http.MethodPost, "/v1/login", map[string]string{
+		"username": username,
+		"password": "wrong password",
+	})
+	r.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	cfg.RateLimit("login")(http.HandlerFunc(cfg.HandlerLogin)).ServeHTTP(rec, r)
+	return rec
+}
+
+func TestRateLimitByUsername(t *testing.T) {
+	cfg := newTestConfig(t, withRateLimits(map[string]config.RouteLimits{
+		"login": {Username: config.Rate{Requests: 2, Per: time.Minute}},
+	}))
+	createTestUser(t, cfg, "alice")
+
+	// Spreading guesses over many IPs doesn't help.
+	for i, addr := range []string{"203.0.113.1:1000", "203.0.113.2:1000"} {
+		rec := limitedLogin(t, cfg, "alice", addr)
+		// The body is still there for the handler after the limiter read it.
+		expectStatus(t, rec, http.StatusUnauthorized)
+		if got := rec.Header().Get("RateLimit-Remaining"); got != []string{"1", "0"}[i] {
+			t.Errorf("RateLimit-Remaining = %q after %d attempts", got, i+1)
+		}
+	}
+
+	rec := limitedLogin(t, cfg, "ALICE", "203.0.113.3:1000")
+	expectStatus(t, rec, http.StatusTooManyRequests)
+	want := map[string]string{
+		"Retry-After":      "30",
+		"RateLimit-Limit":  "2",
+		"RateLimit-Policy": "2;w=60",
+	}
+	for header, value := range want {
+		if got := rec.Header().Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+
+	expectStatus(t, limitedLogin(t, cfg, "bob", "203.0.113.3:1000"), http.StatusUnauthorized)
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	cfg := newTestConfig(t, withRateLimits(map[string]config.RouteLimits{
+		"login": {IP: config.Rate{Requests: 1, Per: time.Minute}},
+	}))
+
+	expectStatus(t, limitedLogin(t, cfg, "alice", "[2001:db8:1:2::1]:1000"), http.StatusUnauthorized)
+	// Addresses in the same /64 share a bucket.
+	expectStatus(t, limitedLogin(t, cfg, "bob", "[2001:db8:1:2::99]:1000"), http.StatusTooManyRequests)
+	expectStatus(t, limitedLogin(t, cfg, "bob", "[2001:db8:1:3::1]:1000"), http.StatusUnauthorized)
+	expectStatus(t, limitedLogin(t, cfg, "bob", "198.51.100.7:1000"), http.StatusUnauthorized)
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	cfg := newTestConfig(t, withRateLimits(map[string]config.RouteLimits{
+		"create_post": {User: config.Rate{Requests: 1, Per: time.Minute}},
+	}))
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	post := func(user testUser) *httptest.ResponseRecorder {
+		r := withToken(newRequest(t, http.MethodPost, "/v1/posts", map[string]string{"body": "hi"}), user.Token)
+		rec := httptest.NewRecorder()
+		cfg.RateLimit("create_post")(http.HandlerFunc(cfg.HandlerCreatePost)).ServeHTTP(rec, r)
+		return rec
+	}
+	expectStatus(t, post(alice), http.StatusCreated)
+	expectStatus(t, post(alice), http.StatusTooManyRequests)
+	expectStatus(t, post(bob), http.StatusCreated)
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	cfg := newTestConfig(t, withRateLimits(map[string]config.RouteLimits{
+		"login": {IP: config.Rate{Requests: 1, Per: time.Minute}},
+	}), func(c *config.Config) { c.RateLimits.Enabled = false })
+
+	for range 3 {
+		rec := limitedLogin(t, cfg, "alice", "203.0.113.1:1000")
+		expectStatus(t, rec, http.StatusUnauthorized)
+		if rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatal("disabled rate limiting still set headers")
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "connection", remoteAddr: "203.0.113.1:1000", want: "203.0.113.1"},
+		{name: "mapped IPv4", remoteAddr: "[::ffff:203.0.113.1]:1000", want: "203.0.113.1"},
+		{name: "untrusted header", remoteAddr: "10.0.0.1:1000", forwarded: []string{"198.51.100.7"}, want: "10.0.0.1"},
+		{name: "proxy's hop", trustProxy: true, remoteAddr: "10.0.0.1:1000", forwarded: []string{"1.1.1.1, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "last header", trustProxy: true, remoteAddr: "10.0.0.1:1000", forwarded: []string{"1.1.1.1", "198.51.100.7"}, want: "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{}
+			cfg.rateLimits.TrustProxy = tt.trustProxy
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := cfg.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// Ceremonies a webauthn_sessions row can belong to.
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// maxPasskeyNameLength bounds the label a user gives a passkey.
+const maxPasskeyNameLength = 64
+
+var errCeremonyNotFound = errors.New("webauthn ceremony not found or expired")
+
+// newWebAuthn builds the relying party passkeys are registered with. Every
+// passkey is discoverable and needs user verification, so it stands in for
+// both the password and the second factor. config.Load has already checked
+// what webauthn.New does, so an error here is a bug.
+func newWebAuthn(c config.WebAuthn) *webauthn.WebAuthn {
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: c.Timeout, TimeoutUVD: c.Timeout}
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          c.RPID,
+		RPDisplayName: c.RPName,
+		RPOrigins:     c.Origins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		},
+		Timeouts: webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+	if err != nil {
+		panic(fmt.Sprintf("webauthn: %v", err))
+	}
+	return wa
+}
+
+// passkeyUser adapts a user and their stored credentials to webauthn.User.
+// The user handle is the user's ID.
+type passkeyUser struct {
+	id          uuid.UUID
+	username    string
+	credentials []webauthn.Credential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte                         { return u.id[:] }
+func (u *passkeyUser) WebAuthnName() string                       { return u.username }
+func (u *passkeyUser) WebAuthnDisplayName() string                { return u.username }
+func (u *passkeyUser) WebAuthnIcon() string                       { return "" }
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
+
+// loadPasskeyUser reads userID's registered passkeys.
+func (cfg *Config) loadPasskeyUser(ctx context.Context, userID uuid.UUID, username string) (*passkeyUser, error) {
+	rows, err := cfg.DB.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user := &passkeyUser{id: userID, username: username}
+	for _, row := range rows {
+		user.credentials = append(user.credentials, webauthnCredential(row))
+	}
+	return user, nil
+}
+
+func webauthnCredential(row database.WebauthnCredential) webauthn.Credential {
+	return webauthn.Credential{
+		ID:              row.CredentialID,
+		PublicKey:       row.PublicKey,
+		AttestationType: row.AttestationType,
+		Transport:       splitTransports(row.Transports),
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: row.BackupEligible,
+			BackupState:    row.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    row.Aaguid,
+			SignCount: uint32(row.SignCount),
+		},
+	}
+}
+
+// Transports are stored comma-separated so both dialects can hold them.
+func joinTransports(transports []protocol.AuthenticatorTransport) string {
+	names := make([]string, 0, len(transports))
+	for _, t := range transports {
+		names = append(names, string(t))
+	}
+	return strings.Join(names, ",")
+}
+
+func splitTransports(s string) []protocol.AuthenticatorTransport {
+	if s == "" {
+		return nil
+	}
+	var transports []protocol.AuthenticatorTransport
+	for _, name := range strings.Split(s, ",") {
+		transports = append(transports, protocol.AuthenticatorTransport(name))
+	}
+	return transports
+}
+
+// saveCeremony stores the state of a ceremony between its begin and finish
+// requests and returns the ID the client sends back to finish it. userID is
+// unset for a login, where the user isn't known until the passkey answers.
+func (cfg *Config) saveCeremony(ctx context.Context, ceremony string, userID uuid.NullUUID, session *webauthn.SessionData) (uuid.UUID, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	id := uuid.New()
+	err = cfg.DB.CreateWebAuthnSession(ctx, database.CreateWebAuthnSessionParams{
+		ID:        id,
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Data:      string(data),
+		ExpiresAt: session.Expires,
+	})
+	return id, err
+}
+
+// takeCeremony loads and deletes a ceremony's state, so each challenge can
+// be answered only once.
+func (cfg *Config) takeCeremony(ctx context.Context, ceremony string, id uuid.UUID) (webauthn.SessionData, uuid.NullUUID, error) {
+	var session webauthn.SessionData
+
+	row, err := cfg.DB.TakeWebAuthnSession(ctx, database.TakeWebAuthnSessionParams{
+		ID:       id,
+		Ceremony: ceremony,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return session, uuid.NullUUID{}, errCeremonyNotFound
+		}
+		return session, uuid.NullUUID{}, err
+	}
+	if time.Now().After(row.ExpiresAt) {
+		return session, uuid.NullUUID{}, errCeremonyNotFound
+	}
+
+	err = json.Unmarshal([]byte(row.Data), &session)
+	return session, row.UserID, err
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/artyultra/tanglr/internal/store"
+	"github.com/google/uuid"
+)
+
+// reader returns the Repository for a read the viewer can tolerate being
+// slightly stale: a healthy replica, unless the viewer wrote something within
+// the stickiness window and should see it, or no replica is available.
+func (cfg *Config) reader(viewerID uuid.UUID) store.Repository {
+	if cfg.replicas == nil {
+		return cfg.DB
+	}
+	if _, wrote := cfg.stickyUsers.Get(viewerID.String()); wrote {
+		return cfg.DB
+	}
+	if replica, ok := cfg.replicas.Reader(); ok {
+		return replica
+	}
+	return cfg.DB
+}
+
+// TrackWrites remembers users who just made a write request so their reads
+// stay on the primary until the replicas have caught up.
+func (cfg *Config) TrackWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if userID := cfg.RequestUserID(r); userID != "" {
+			cfg.stickyUsers.Set(userID, struct{}{})
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/artyultra/tanglr/internal/store"
+)
+
+// withReplica gives cfg a single read replica that never receives the
+// primary's writes, so any read served from it is visibly stale.
+func withReplica(t *testing.T, cfg *Config) *sql.DB {
+	t.Helper()
+	replica := newTestDB(t)
+	cfg.replicas = store.NewReplicaSet(store.SQLite, []*sql.DB{replica})
+	return replica
+}
+
+func profileExists(t *testing.T, cfg *Config, viewer testUser, username string) bool {
+	t.Helper()
+	rec := getProfile(t, cfg, viewer, username)
+	expectStatus(t, rec, http.StatusOK)
+	return decodeResponse[GetUserResponse](t, rec).Exists
+}
+
+func TestReadsGoToReplica(t *testing.T) {
+	cfg := newTestConfig(t)
+	withReplica(t, cfg)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	createTestPost(t, cfg, alice, "hello")
+
+	if profileExists(t, cfg, bob, alice.Username) {
+		t.Error("profile read wasn't served by the replica")
+	}
+	if posts := timeline(t, cfg, alice); len(posts) != 0 {
+		t.Errorf("timeline = %v, want the replica's empty one", posts)
+	}
+}
+
+func TestWritesStickToPrimary(t *testing.T) {
+	cfg := newTestConfig(t)
+	withReplica(t, cfg)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+	carol := createTestUser(t, cfg, "carol")
+
+	// Reads don't make a user sticky.
+	r := withToken(newRequest(t, http.MethodGet, "/v1/posts", nil), carol.Token)
+	cfg.TrackWrites(http.HandlerFunc(cfg.HandlerGetAllPosts)).ServeHTTP(httptest.NewRecorder(), r)
+
+	r = withToken(newRequest(t, http.MethodPost, "/v1/posts", map[string]string{"body": "hi"}), bob.Token)
+	rec := httptest.NewRecorder()
+	cfg.TrackWrites(http.HandlerFunc(cfg.HandlerCreatePost)).ServeHTTP(rec, r)
+	expectStatus(t, rec, http.StatusCreated)
+
+	if !profileExists(t, cfg, bob, alice.Username) {
+		t.Error("a user who just wrote was served from the replica")
+	}
+	if posts := timeline(t, cfg, bob); len(posts) != 1 {
+		t.Errorf("timeline = %v, want bob's own post", posts)
+	}
+	if profileExists(t, cfg, carol, alice.Username) {
+		t.Error("a user who only read was moved to the primary")
+	}
+}
+
+func TestUnhealthyReplicaFallsBackToPrimary(t *testing.T) {
+	cfg := newTestConfig(t)
+	replica := withReplica(t, cfg)
+	alice := createTestUser(t, cfg, "alice")
+	bob := createTestUser(t, cfg, "bob")
+
+	replica.Close()
+	cfg.replicas.CheckHealth(t.Context())
+	if healthy, total := cfg.replicas.Healthy(); healthy != 0 || total != 1 {
+		t.Fatalf("Healthy() = %d/%d, want 0/1", healthy, total)
+	}
+
+	if !profileExists(t, cfg, bob, alice.Username) {
+		t.Error("read wasn't served by the primary with no healthy replica")
+	}
+
+	rec := serve(cfg.HandlerReadyz, newRequest(t, http.MethodGet, "/readyz", nil))
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeResponse[ReadinessResponse](t, rec).Checks["replicas"]; got != "0/1 healthy" {
+		t.Errorf("replicas check = %q, want 0/1 healthy", got)
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/internal/migrate"
+	"github.com/artyultra/tanglr/internal/store"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const testPassword = "correct horse battery staple"
+
+func TestMain(m *testing.M) {
+	// Handlers log every error response; keep test output to failures.
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// newTestConfig returns a handler config backed by a freshly migrated SQLite
+// database in a temporary directory. configure may adjust the defaults before
+// the config is built.
+func newTestConfig(t *testing.T, configure ...func(*config.Config)) *Config {
+	t.Helper()
+
+	appCfg := config.Default()
+	appCfg.Environment = "development"
+	appCfg.JWTSecret = "test-secret-that-is-long-enough-to-use"
+	appCfg.WebAuthn.RPID = "localhost"
+	appCfg.WebAuthn.Origins = []string{"http://localhost:3000"}
+	for _, f := range configure {
+		f(&appCfg)
+	}
+
+	db := newTestDB(t)
+	cfg := NewConfig(store.New(store.SQLite, db), db, nil, &appCfg)
+	if err := cfg.LoadSigningKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// newTestDB returns a freshly migrated SQLite database in a temporary
+// directory.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	target, err := store.ParseURL("sqlite:" + filepath.Join(t.TempDir(), "tanglr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(target.Driver, target.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, target.Dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
+
+type testUser struct {
+	ID       uuid.UUID
+	Username string
+	Email    string
+	Token    string
+}
+
+// createTestUser adds a user with testPassword straight to the database and
+// returns it with a fresh access token.
+func createTestUser(t *testing.T, cfg *Config, username string) testUser {
+	t.Helper()
+	ctx := context.Background()
+
+	hashed, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	user, err := cfg.DB.CreateUser(ctx, database.CreateUserParams{
+		Username:       username,
+		Email:          username + "@example.com",
+		HashedPassword: hashed,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	if err != nil {
+		t.Fatalf("creating user %s: %v", username, err)
+	}
+	err = cfg.DB.CreateUserPreferences(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("creating preferences for %s: %v", username, err)
+	}
+
+	return testUser{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Token:    makeTestToken(t, cfg, user.ID, user.Username, auth.RoleUser),
+	}
+}
+
+// createTestUserWithRole is createTestUser for a user who holds role, both
+// in the database and in their token.
+func createTestUserWithRole(t *testing.T, cfg *Config, username string, role auth.Role) testUser {
+	t.Helper()
+	user := createTestUser(t, cfg, username)
+	_, err := cfg.DB.UpdateUserRole(context.Background(), database.UpdateUserRoleParams{
+		Username: username,
+		Role:     string(role),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Token = makeTestToken(t, cfg, user.ID, username, role)
+	return user
+}
+
+func makeTestToken(t *testing.T, cfg *Config, userID uuid.UUID, username string, role auth.Role) string {
+	t.Helper()
+	token, err := auth.MakeJWT(userID, username, role, cfg.jwtKeys, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// login posts a username and password to HandlerLogin.
+func login(t *testing.T, cfg *Config, username, password string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := newRequest(t, http.MethodPost, "/v1/login", map[string]string{
+		"username": username,
+		"password": password,
+	})
+	return serve(cfg.HandlerLogin, r)
+}
+
+// newRequest builds a request with body encoded as JSON. A nil body sends
+// none.
+func newRequest(t *testing.T, method, target string, body any) *http.Request {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func withToken(r *http.Request, token string) *http.Request {
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// withURLParams sets chi URL parameters from key, value pairs, as the router
+// would for a matched route.
+func withURLParams(r *http.Request, kv ...string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for i := 0; i+1 < len(kv); i += 2 {
+		rctx.URLParams.Add(kv[i], kv[i+1])
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func serve(h http.HandlerFunc, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	return rec
+}
+
+// serveModerator runs h behind the middleware that guards the moderation
+// routes.
+func serveModerator(cfg *Config, h http.HandlerFunc, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	cfg.RequireRole(auth.RoleModerator, auth.RoleAdmin)(h).ServeHTTP(rec, r)
+	return rec
+}
+
+// expectStatus fails the test unless the response has the wanted status.
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, want, rec.Body.String())
+	}
+}
+
+func decodeResponse[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
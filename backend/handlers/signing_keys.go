@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/artyultra/tanglr/handlers/helpers"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/database"
+)
+
+// signingKeyReload is how often every instance reloads the signing keys.
+// Config validation keeps JWT_KEY_OVERLAP at least this long, so a rotated
+// key is known everywhere by the time it turns up in a token.
+const signingKeyReload = time.Minute
+
+// LoadSigningKeys loads the access token key pairs, first adding the next
+// one when it is due. It runs before the server starts and then every
+// signingKeyReload. With HS256 no key pairs are made, but ones left from an
+// earlier algorithm keep verifying until they expire.
+func (cfg *Config) LoadSigningKeys(ctx context.Context) error {
+	now := time.Now()
+	keys, err := cfg.loadSigningKeys(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	if notBefore, due := nextSigningKey(keys, cfg.tokens, now); due {
+		key, err := auth.NewSigningKey(cfg.tokens.Algorithm, notBefore, notBefore.Add(cfg.tokens.KeyRotation+cfg.tokens.KeyOverlap))
+		if err != nil {
+			return err
+		}
+		sealed, err := auth.SealPrivateKey(key.Private, cfg.jwtSecret)
+		if err != nil {
+			return err
+		}
+		err = cfg.DB.CreateSigningKey(ctx, database.CreateSigningKeyParams{
+			Kid:        key.ID,
+			Algorithm:  key.Algorithm,
+			PrivateKey: sealed,
+			NotBefore:  key.NotBefore,
+			ExpiresAt:  key.ExpiresAt,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't store signing key: %w", err)
+		}
+		slog.Info("created signing key", "kid", key.ID, "algorithm", key.Algorithm, "not_before", key.NotBefore)
+
+		// Another instance may have added one at the same time; both are
+		// published, and the newest signs everywhere.
+		keys, err = cfg.loadSigningKeys(ctx, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	cfg.jwtKeys.SetKeys(keys)
+	return nil
+}
+
+func (cfg *Config) loadSigningKeys(ctx context.Context, now time.Time) ([]auth.SigningKey, error) {
+	rows, err := cfg.DB.ListSigningKeys(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load signing keys: %w", err)
+	}
+
+	keys := make([]auth.SigningKey, 0, len(rows))
+	for _, row := range rows {
+		private, err := auth.OpenPrivateKey(row.PrivateKey, cfg.jwtSecret)
+		if err != nil {
+			// Most likely JWT_SECRET changed; a new key takes its place.
+			slog.Error("skipping signing key", "kid", row.Kid, "error", err)
+			continue
+		}
+		keys = append(keys, auth.SigningKey{
+			ID:        row.Kid,
+			Algorithm: row.Algorithm,
+			Private:   private,
+			NotBefore: row.NotBefore,
+			ExpiresAt: row.ExpiresAt,
+		})
+	}
+	return keys, nil
+}
+
+// nextSigningKey reports whether a new key pair is due and when it should
+// start signing. keys is ordered oldest NotBefore first. A key is replaced
+// KeyRotation after it starts signing, and its successor is published
+// KeyOverlap before that. With no usable key, one is needed right away.
+func nextSigningKey(keys []auth.SigningKey, tokens config.Tokens, now time.Time) (time.Time, bool) {
+	if tokens.Algorithm == auth.AlgHS256 {
+		return time.Time{}, false
+	}
+	if len(keys) == 0 {
+		return now, true
+	}
+
+	newest := keys[len(keys)-1]
+	rotateAt := newest.NotBefore.Add(tokens.KeyRotation)
+	if newest.Algorithm != tokens.Algorithm {
+		// The algorithm changed: the current key keeps signing while the new
+		// one is published.
+		rotateAt = now.Add(tokens.KeyOverlap)
+	}
+	if now.Before(rotateAt.Add(-tokens.KeyOverlap)) {
+		return time.Time{}, false
+	}
+	if rotateAt.Before(now) {
+		rotateAt = now
+	}
+	return rotateAt, true
+}
+
+// HandlerJWKS publishes the public keys access tokens are signed with, for
+// services that verify Tanglr tokens themselves.
+func (cfg *Config) HandlerJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	helpers.RespondWithJSON(w, http.StatusOK, cfg.jwtKeys.JWKS())
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func withKeyPairs(c *config.Config) {
+	c.Tokens.Algorithm = auth.AlgEdDSA
+	c.Tokens.AcceptHMAC = false
+	c.Tokens.KeyRotation = 24 * time.Hour
+	c.Tokens.KeyOverlap = time.Hour
+}
+
+func TestNextSigningKey(t *testing.T) {
+	now := time.Now()
+	tokens := config.Tokens{Algorithm: auth.AlgEdDSA, KeyRotation: 24 * time.Hour, KeyOverlap: time.Hour}
+	keyFrom := func(algorithm string, notBefore time.Time) []auth.SigningKey {
+		return []auth.SigningKey{{Algorithm: algorithm, NotBefore: notBefore}}
+	}
+
+	tests := []struct {
+		name       string
+		keys       []auth.SigningKey
+		algorithm  string
+		wantDue    bool
+		wantBefore time.Time
+	}{
+		{name: "HS256 needs no keys", algorithm: auth.AlgHS256},
+		{name: "no keys", wantDue: true, wantBefore: now},
+		{name: "fresh key", keys: keyFrom(auth.AlgEdDSA, now.Add(-time.Hour))},
+		{name: "just before the overlap", keys: keyFrom(auth.AlgEdDSA, now.Add(-22*time.Hour-59*time.Minute))},
+		{
+			name:       "inside the overlap",
+			keys:       keyFrom(auth.AlgEdDSA, now.Add(-23*time.Hour)),
+			wantDue:    true,
+			wantBefore: now.Add(time.Hour),
+		},
+		{name: "overdue", keys: keyFrom(auth.AlgEdDSA, now.Add(-48*time.Hour)), wantDue: true, wantBefore: now},
+		{
+			name:       "algorithm changed",
+			keys:       keyFrom(auth.AlgRS256, now.Add(-time.Hour)),
+			wantDue:    true,
+			wantBefore: now.Add(time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := tokens
+			if tt.algorithm != "" {
+				tokens.Algorithm = tt.algorithm
+			}
+			notBefore, due := nextSigningKey(tt.keys, tokens, now)
+			if due != tt.wantDue || !notBefore.Equal(tt.wantBefore) {
+				t.Errorf("nextSigningKey() = %v, %v; want %v, %v", notBefore, due, tt.wantBefore, tt.wantDue)
+			}
+		})
+	}
+}
+
+// shiftSigningKeys moves every stored key's lifetime back by d, as if d had
+// passed.
+func shiftSigningKeys(t *testing.T, cfg *Config, d time.Duration) {
+	t.Helper()
+	ctx := context.Background()
+	rows, err := cfg.DB.ListSigningKeys(ctx, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.DBConn.Exec("DELETE FROM signing_keys"); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		err := cfg.DB.CreateSigningKey(ctx, database.CreateSigningKeyParams{
+			Kid:        row.Kid,
+			Algorithm:  row.Algorithm,
+			PrivateKey: row.PrivateKey,
+			NotBefore:  row.NotBefore.Add(-d),
+			ExpiresAt:  row.ExpiresAt.Add(-d),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func publishedKids(t *testing.T, cfg *Config) []string {
+	t.Helper()
+	rec := serve(cfg.HandlerJWKS, newRequest(t, http.MethodGet, "/.well-known/jwks.json", nil))
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=300" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	var kids []string
+	for _, key := range decodeResponse[auth.JWKS](t, rec).Keys {
+		kids = append(kids, key.KeyID)
+	}
+	return kids
+}
+
+// kidOf returns the kid header of a token without verifying it.
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func loadSigningKeys(t *testing.T, cfg *Config) {
+	t.Helper()
+	if err := cfg.LoadSigningKeys(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	cfg := newTestConfig(t, withKeyPairs)
+	alice := createTestUser(t, cfg, "alice")
+
+	first := publishedKids(t, cfg)
+	if len(first) != 1 {
+		t.Fatalf("published keys at start = %v, want one", first)
+	}
+	// Reloading before the key is due adds nothing.
+	loadSigningKeys(t, cfg)
+	if got := publishedKids(t, cfg); len(got) != 1 {
+		t.Fatalf("published keys after a reload = %v", got)
+	}
+
+	// Inside the overlap the successor is published but doesn't sign yet.
+	shiftSigningKeys(t, cfg, 23*time.Hour+30*time.Minute)
+	loadSigningKeys(t, cfg)
+	kids := publishedKids(t, cfg)
+	if len(kids) != 2 || kids[0] != first[0] {
+		t.Fatalf("published keys in the overlap = %v", kids)
+	}
+	if token := makeTestToken(t, cfg, alice.ID, alice.Username, auth.RoleUser); kidOf(t, token) != first[0] {
+		t.Error("successor signed before its time")
+	}
+
+	// Once it's due the successor signs, and tokens from the old key keep
+	// working until it expires.
+	shiftSigningKeys(t, cfg, time.Hour)
+	loadSigningKeys(t, cfg)
+	if token := makeTestToken(t, cfg, alice.ID, alice.Username, auth.RoleUser); kidOf(t, token) != kids[1] {
+		t.Error("new tokens aren't signed with the successor")
+	}
+	expectStatus(t, getProfile(t, cfg, alice, "alice"), http.StatusOK)
+
+	shiftSigningKeys(t, cfg, time.Hour)
+	loadSigningKeys(t, cfg)
+	if got := publishedKids(t, cfg); len(got) != 1 || got[0] != kids[1] {
+		t.Errorf("published keys after the old one expired = %v", got)
+	}
+	expectStatus(t, getProfile(t, cfg, alice, "alice"), http.StatusUnauthorized)
+}
+
+// A key sealed under an old JWT_SECRET can't be used, so a new one takes
+// its place.
+func TestSigningKeysAfterSecretChange(t *testing.T) {
+	cfg := newTestConfig(t, withKeyPairs)
+	before := publishedKids(t, cfg)
+
+	cfg.jwtSecret = "a-new-secret-that-is-also-long-enough"
+	loadSigningKeys(t, cfg)
+	after := publishedKids(t, cfg)
+	if len(after) != 1 || after[0] == before[0] {
+		t.Errorf("published keys after the secret changed = %v, was %v", after, before)
+	}
+}
+
+func TestJWKSWithHMAC(t *testing.T) {
+	cfg := newTestConfig(t)
+	if got := publishedKids(t, cfg); len(got) != 0 {
+		t.Errorf("HS256 published keys %v", got)
+	}
+}
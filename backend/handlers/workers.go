@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// RunWorkers runs the background jobs the handlers depend on and blocks until
+// ctx is cancelled and every job has returned.
+func (cfg *Config) RunWorkers(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		every(ctx, time.Minute, cfg.suggestions.Purge)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		every(ctx, time.Hour, func() {
+			err := cfg.DB.DeleteLoginAttemptsBefore(ctx, time.Now().Add(-cfg.login.AttemptRetention))
+			if err != nil {
+				slog.Error("failed to delete old login attempts", "error", err)
+			}
+		})
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		every(ctx, signingKeyReload, func() {
+			if err := cfg.DB.DeleteExpiredSigningKeys(ctx, time.Now()); err != nil {
+				slog.Error("failed to delete expired signing keys", "error", err)
+			}
+			if err := cfg.LoadSigningKeys(ctx); err != nil {
+				slog.Error("failed to reload signing keys", "error", err)
+			}
+		})
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		every(ctx, time.Minute, func() {
+			if err := cfg.DB.DeleteExpiredWebAuthnSessions(ctx, time.Now()); err != nil {
+				slog.Error("failed to delete expired passkey ceremonies", "error", err)
+			}
+		})
+	}()
+
+	if len(cfg.sso) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			every(ctx, time.Minute, func() {
+				if err := cfg.DB.DeleteExpiredOIDCLogins(ctx, time.Now()); err != nil {
+					slog.Error("failed to delete expired OIDC logins", "error", err)
+				}
+			})
+		}()
+	}
+
+	if cfg.rateLimits.Enabled {
+		idle := cfg.rateLimits.LongestPer()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			every(ctx, time.Minute, func() {
+				if err := cfg.limiter.Purge(ctx, idle); err != nil {
+					slog.Error("failed to purge rate limit buckets", "error", err)
+				}
+			})
+		}()
+	}
+
+	if cfg.replicas != nil {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			every(ctx, cfg.replicaPoll, func() { cfg.replicas.CheckHealth(ctx) })
+		}()
+		go func() {
+			defer wg.Done()
+			every(ctx, time.Minute, cfg.stickyUsers.Purge)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// every calls fn on each tick of interval until ctx is cancelled.
+func every(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
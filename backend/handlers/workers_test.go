@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		every(ctx, time.Millisecond, func() { calls.Add(1) })
+		close(done)
+	}()
+
+	for calls.Load() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("every kept running after its context was cancelled")
+	}
+}
+
+// Shutdown waits for RunWorkers, so it has to return promptly once cancelled.
+func TestRunWorkersStopsOnCancel(t *testing.T) {
+	cfg := newTestConfig(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		cfg.RunWorkers(ctx)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunWorkers didn't return after its context was cancelled")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -51,15 +52,48 @@ func CheckPassword(password, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
+// dummyHash is a bcrypt hash at the default cost that no password matches.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte(uuid.NewString()), bcrypt.DefaultCost)
+	return hash
+})
+
+// CheckPasswordNoUser spends as long as CheckPassword does for a real user,
+// so a login for a username that doesn't exist can't be told apart by its
+// response time.
+func CheckPasswordNoUser(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+}
+
+// Role is the privilege level carried in the access token. It mirrors the
+// users.role column.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+func ParseRole(s string) (Role, error) {
+	switch Role(s) {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return Role(s), nil
+	}
+	return "", fmt.Errorf("unknown role %q", s)
+}
+
 type CustomClaims struct {
 	Username string `json:"username"`
+	Role     Role   `json:"role"`
 	jwt.RegisteredClaims
 }
 
-func MakeJWT(userID uuid.UUID, username string, tokenSecret string, expiresIn time.Duration) (string, error) {
+func MakeJWT(userID uuid.UUID, username string, role Role, keys *Keyset, expiresIn time.Duration) (string, error) {
 
 	claims := CustomClaims{
 		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "tanglr",
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -68,9 +102,7 @@ func MakeJWT(userID uuid.UUID, username string, tokenSecret string, expiresIn ti
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	tokenString, err := token.SignedString([]byte(tokenSecret))
+	tokenString, err := keys.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("couldn't sign token: %w", err)
 	}
@@ -78,13 +110,12 @@ func MakeJWT(userID uuid.UUID, username string, tokenSecret string, expiresIn ti
 	return tokenString, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (*CustomClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return []byte(tokenSecret), nil
-	})
+// ValidateJWT checks an access token against keys: HS256 tokens against
+// JWT_SECRET while HMAC is accepted, the others against the key pair named
+// by their kid header.
+func ValidateJWT(tokenString string, keys *Keyset) (*CustomClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, keys.verificationKey,
+		jwt.WithValidMethods([]string{AlgHS256, AlgEdDSA, AlgRS256}))
 	if err != nil || !token.Valid {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
@@ -97,10 +128,17 @@ func ValidateJWT(tokenString, tokenSecret string) (*CustomClaims, error) {
 	// Manually extract and convert
 	sub, _ := mapClaims["sub"].(string)
 	username, _ := mapClaims["username"].(string)
+	// Tokens issued before roles existed carry no role claim.
+	roleString, _ := mapClaims["role"].(string)
+	role, err := ParseRole(roleString)
+	if err != nil {
+		role = RoleUser
+	}
 	// You can parse issuedAt and expiresAt too, if needed
 
 	return &CustomClaims{
 		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject: sub,
 			// Optionally set IssuedAt, ExpiresAt, etc.
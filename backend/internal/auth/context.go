@@ -0,0 +1,18 @@
+package auth
+
+import "context"
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+// WithClaims returns a copy of ctx carrying the validated token claims.
+func WithClaims(ctx context.Context, claims *CustomClaims) context.Context {
+	return context.WithValue(ctx, claimsContextKey, claims)
+}
+
+// ClaimsFromContext returns the claims stored by WithClaims, if any.
+func ClaimsFromContext(ctx context.Context) (*CustomClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*CustomClaims)
+	return claims, ok
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algorithms access tokens can be signed with.
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+// SigningKey is one key pair in a Keyset. It signs tokens from NotBefore
+// until a newer key takes over, and verifies them until ExpiresAt.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	NotBefore time.Time
+	ExpiresAt time.Time
+}
+
+// NewSigningKey generates a key pair for algorithm with a random ID.
+func NewSigningKey(algorithm string, notBefore, expiresAt time.Time) (SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return SigningKey{}, fmt.Errorf("can't generate a key pair for %q", algorithm)
+	}
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("couldn't generate signing key: %w", err)
+	}
+	return SigningKey{
+		ID:        rand.Text(),
+		Algorithm: algorithm,
+		Private:   private,
+		NotBefore: notBefore,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// sealingKey derives the key private keys are encrypted with at rest from
+// the access token secret, so a copy of the database alone can't sign
+// tokens.
+func sealingKey(tokenSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	mac.Write([]byte("signing_keys"))
+	return mac.Sum(nil)
+}
+
+func sealingAEAD(tokenSecret string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(sealingKey(tokenSecret))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealPrivateKey encrypts a private key for storage.
+func SealPrivateKey(key crypto.Signer, tokenSecret string) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encode private key: %w", err)
+	}
+	aead, err := sealingAEAD(tokenSecret)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	return aead.Seal(nonce, nonce, der, nil), nil
+}
+
+// OpenPrivateKey decrypts a key sealed by SealPrivateKey. It fails if
+// JWT_SECRET has changed since.
+func OpenPrivateKey(sealed []byte, tokenSecret string) (crypto.Signer, error) {
+	aead, err := sealingAEAD(tokenSecret)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed private key is too short")
+	}
+	der, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decrypt private key: %w", err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// Keyset holds the keys access tokens are signed and verified with. With
+// HS256 every token is signed with JWT_SECRET. Otherwise the newest key pair
+// that has reached its NotBefore signs, its ID goes in the token's kid
+// header, and ValidateJWT looks the key up by kid.
+type Keyset struct {
+	secret     []byte
+	algorithm  string
+	acceptHMAC bool
+
+	mu   sync.RWMutex
+	keys []SigningKey // oldest NotBefore first
+}
+
+// NewKeyset returns a keyset signing with algorithm. acceptHMAC keeps HS256
+// tokens signed with tokenSecret valid when algorithm is a key pair one.
+func NewKeyset(tokenSecret, algorithm string, acceptHMAC bool) *Keyset {
+	return &Keyset{
+		secret:     []byte(tokenSecret),
+		algorithm:  algorithm,
+		acceptHMAC: acceptHMAC || algorithm == AlgHS256,
+	}
+}
+
+// Algorithm is the algorithm new tokens are meant to be signed with.
+func (k *Keyset) Algorithm() string {
+	return k.algorithm
+}
+
+// SetKeys replaces the key pairs.
+func (k *Keyset) SetKeys(keys []SigningKey) {
+	keys = slices.Clone(keys)
+	slices.SortStableFunc(keys, func(a, b SigningKey) int {
+		return a.NotBefore.Compare(b.NotBefore)
+	})
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+}
+
+// Keys returns the key pairs that haven't expired, oldest NotBefore first.
+func (k *Keyset) Keys(now time.Time) []SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var keys []SigningKey
+	for _, key := range k.keys {
+		if now.Before(key.ExpiresAt) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (k *Keyset) sign(claims jwt.Claims) (string, error) {
+	if k.algorithm == AlgHS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+
+	now := time.Now()
+	var signing *SigningKey
+	for _, key := range k.Keys(now) {
+		if !now.Before(key.NotBefore) {
+			signing = &key
+		}
+	}
+	if signing == nil {
+		return "", errors.New("no signing key is active")
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(signing.Algorithm), claims)
+	token.Header["kid"] = signing.ID
+	return token.SignedString(signing.Private)
+}
+
+func (k *Keyset) verificationKey(t *jwt.Token) (any, error) {
+	if t.Method.Alg() == AlgHS256 {
+		if !k.acceptHMAC {
+			return nil, errors.New("HS256 tokens are no longer accepted")
+		}
+		return k.secret, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	for _, key := range k.Keys(time.Now()) {
+		if key.ID != kid {
+			continue
+		}
+		if key.Algorithm != t.Method.Alg() {
+			return nil, fmt.Errorf("key %s is not a %s key", kid, t.Method.Alg())
+		}
+		return key.Private.Public(), nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// JWK is a public key in JSON Web Key form (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is the body of /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every key pair that hasn't expired,
+// including one that is published but not signing yet. The HS256 secret is
+// never included.
+func (k *Keyset) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.Keys(time.Now()) {
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+		switch public := key.Private.Public().(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const testSecret = "test-secret-that-is-long-enough-to-use"
+
+func newTestKey(t *testing.T, algorithm string, notBefore, expiresAt time.Time) SigningKey {
+	t.Helper()
+	key, err := NewSigningKey(algorithm, notBefore, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func makeToken(t *testing.T, keys *Keyset) string {
+	t.Helper()
+	token, err := MakeJWT(uuid.New(), "alice", RoleUser, keys, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// kidOf returns the kid header of a token without verifying it.
+func kidOf(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestSealPrivateKey(t *testing.T) {
+	for _, algorithm := range []string{AlgEdDSA, AlgRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			key := newTestKey(t, algorithm, time.Now(), time.Now().Add(time.Hour))
+			sealed, err := SealPrivateKey(key.Private, testSecret)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opened, err := OpenPrivateKey(sealed, testSecret)
+			if err != nil {
+				t.Fatalf("OpenPrivateKey() error = %v", err)
+			}
+			if !opened.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(key.Private.Public()) {
+				t.Error("opened key doesn't match the sealed one")
+			}
+
+			if _, err := OpenPrivateKey(sealed, "a-different-secret-of-enough-length"); err == nil {
+				t.Error("OpenPrivateKey() with another secret succeeded")
+			}
+			if _, err := OpenPrivateKey(sealed[:4], testSecret); err == nil {
+				t.Error("OpenPrivateKey() of a truncated key succeeded")
+			}
+		})
+	}
+}
+
+func TestKeysetSignsWithNewestActiveKey(t *testing.T) {
+	now := time.Now()
+	expired := newTestKey(t, AlgEdDSA, now.Add(-3*time.Hour), now.Add(-time.Minute))
+	previous := newTestKey(t, AlgEdDSA, now.Add(-2*time.Hour), now.Add(time.Hour))
+	current := newTestKey(t, AlgEdDSA, now.Add(-time.Hour), now.Add(2*time.Hour))
+	next := newTestKey(t, AlgEdDSA, now.Add(time.Hour), now.Add(3*time.Hour))
+
+	keys := NewKeyset(testSecret, AlgEdDSA, false)
+	// Order doesn't matter to SetKeys.
+	keys.SetKeys([]SigningKey{next, current, expired, previous})
+
+	token := makeToken(t, keys)
+	if kid := kidOf(t, token); kid != current.ID {
+		t.Errorf("token signed with %s, want the current key %s", kid, current.ID)
+	}
+	if _, err := ValidateJWT(token, keys); err != nil {
+		t.Errorf("ValidateJWT() error = %v", err)
+	}
+
+	var published []string
+	for _, jwk := range keys.JWKS().Keys {
+		published = append(published, jwk.KeyID)
+	}
+	if len(published) != 3 || published[0] != previous.ID || published[2] != next.ID {
+		t.Errorf("JWKS kids = %v, want previous, current and next", published)
+	}
+}
+
+func TestValidateJWTByKid(t *testing.T) {
+	now := time.Now()
+	old := newTestKey(t, AlgEdDSA, now.Add(-2*time.Hour), now.Add(time.Hour))
+	keys := NewKeyset(testSecret, AlgEdDSA, false)
+	keys.SetKeys([]SigningKey{old})
+	oldToken := makeToken(t, keys)
+
+	// After rotation the old key's tokens still verify by their kid.
+	current := newTestKey(t, AlgRS256, now.Add(-time.Hour), now.Add(2*time.Hour))
+	keys.SetKeys([]SigningKey{old, current})
+	if _, err := ValidateJWT(oldToken, keys); err != nil {
+		t.Errorf("token from the previous key: %v", err)
+	}
+	newToken := makeToken(t, keys)
+	if kidOf(t, newToken) != current.ID {
+		t.Error("new token isn't signed with the current key")
+	}
+
+	// Once the old key is gone, so are its tokens.
+	keys.SetKeys([]SigningKey{current})
+	if _, err := ValidateJWT(oldToken, keys); err == nil {
+		t.Error("token from a dropped key still verifies")
+	}
+
+	// A token can't name a key of a different algorithm than it's signed
+	// with.
+	forged := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{"sub": uuid.NewString()})
+	forged.Header["kid"] = current.ID
+	forgedString, err := forged.SignedString(old.Private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateJWT(forgedString, keys); err == nil {
+		t.Error("EdDSA token naming an RS256 key verified")
+	}
+}
+
+func TestKeysetWithoutActiveKey(t *testing.T) {
+	keys := NewKeyset(testSecret, AlgEdDSA, false)
+	if _, err := MakeJWT(uuid.New(), "alice", RoleUser, keys, time.Hour); err == nil {
+		t.Error("MakeJWT() with no keys succeeded")
+	}
+
+	keys.SetKeys([]SigningKey{newTestKey(t, AlgEdDSA, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))})
+	if _, err := MakeJWT(uuid.New(), "alice", RoleUser, keys, time.Hour); err == nil {
+		t.Error("MakeJWT() with only a key that isn't signing yet succeeded")
+	}
+}
+
+func TestHMACAcceptance(t *testing.T) {
+	hmacKeys := NewKeyset(testSecret, AlgHS256, false)
+	token := makeToken(t, hmacKeys)
+	if kid := kidOf(t, token); kid != "" {
+		t.Errorf("HS256 token has kid %q", kid)
+	}
+	if _, err := ValidateJWT(token, hmacKeys); err != nil {
+		t.Errorf("HS256 token against an HS256 keyset: %v", err)
+	}
+	if got := hmacKeys.JWKS().Keys; len(got) != 0 {
+		t.Errorf("HS256 keyset published %v", got)
+	}
+
+	active := newTestKey(t, AlgEdDSA, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	accepting := NewKeyset(testSecret, AlgEdDSA, true)
+	accepting.SetKeys([]SigningKey{active})
+	if _, err := ValidateJWT(token, accepting); err != nil {
+		t.Errorf("HS256 token while HMAC is accepted: %v", err)
+	}
+
+	strict := NewKeyset(testSecret, AlgEdDSA, false)
+	strict.SetKeys([]SigningKey{active})
+	if _, err := ValidateJWT(token, strict); err == nil {
+		t.Error("HS256 token verified after HMAC was turned off")
+	}
+
+	if _, err := ValidateJWT(makeToken(t, NewKeyset("another-secret-that-is-long-enough", AlgHS256, false)), accepting); err == nil {
+		t.Error("HS256 token signed with another secret verified")
+	}
+}
+
+// The published keys must verify the tokens, so services that only read
+// the JWKS can check them.
+func TestJWKSVerifiesTokens(t *testing.T) {
+	for _, algorithm := range []string{AlgEdDSA, AlgRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			keys := NewKeyset(testSecret, algorithm, false)
+			keys.SetKeys([]SigningKey{newTestKey(t, algorithm, time.Now().Add(-time.Minute), time.Now().Add(time.Hour))})
+			token := makeToken(t, keys)
+
+			jwks := keys.JWKS()
+			if len(jwks.Keys) != 1 {
+				t.Fatalf("JWKS = %+v", jwks)
+			}
+			jwk := jwks.Keys[0]
+			if jwk.KeyID != kidOf(t, token) || jwk.Algorithm != algorithm || jwk.Use != "sig" {
+				t.Errorf("JWK = %+v", jwk)
+			}
+
+			var public any
+			switch jwk.KeyType {
+			case "OKP":
+				x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+				if err != nil || jwk.Curve != "Ed25519" {
+					t.Fatalf("OKP key %+v: %v", jwk, err)
+				}
+				public = ed25519.PublicKey(x)
+			case "RSA":
+				n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+				e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+				if errN != nil || errE != nil {
+					t.Fatalf("RSA key %+v", jwk)
+				}
+				public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			default:
+				t.Fatalf("unexpected key type %q", jwk.KeyType)
+			}
+
+			_, err := jwt.Parse(token, func(*jwt.Token) (any, error) { return public, nil }, jwt.WithValidMethods([]string{algorithm}))
+			if err != nil {
+				t.Errorf("token doesn't verify against the published key: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// mfaPendingPurpose marks a token that only proves the password was right.
+const mfaPendingPurpose = "mfa_pending"
+
+type mfaPendingClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// mfaPendingKey derives the key for mfa_pending tokens from the access token
+// secret, so ValidateJWT never accepts one as an access token.
+func mfaPendingKey(tokenSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	mac.Write([]byte(mfaPendingPurpose))
+	return mac.Sum(nil)
+}
+
+// MakeMFAPendingToken issues the short-lived token a user with two-factor
+// authentication gets after their password, to exchange together with a
+// second factor for an access token.
+func MakeMFAPendingToken(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	claims := mfaPendingClaims{
+		Purpose: mfaPendingPurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "tanglr",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(mfaPendingKey(tokenSecret))
+	if err != nil {
+		return "", fmt.Errorf("couldn't sign token: %w", err)
+	}
+	return token, nil
+}
+
+// ValidateMFAPendingToken checks a token from MakeMFAPendingToken and returns
+// the user it was issued to.
+func ValidateMFAPendingToken(tokenString, tokenSecret string) (uuid.UUID, error) {
+	claims := mfaPendingClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (any, error) {
+		return mfaPendingKey(tokenSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return uuid.Nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if claims.Purpose != mfaPendingPurpose {
+		return uuid.Nil, fmt.Errorf("invalid token: not an mfa_pending token")
+	}
+	return uuid.Parse(claims.Subject)
+}
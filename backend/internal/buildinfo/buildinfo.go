@@ -0,0 +1,56 @@
+// Package buildinfo reports which build of the server is running. GitSHA and
+// BuildTime are meant to be set at link time:
+//
+//	go build -ldflags "-X github.com/artyultra/tanglr/internal/buildinfo.GitSHA=$(git rev-parse HEAD) \
+//	  -X github.com/artyultra/tanglr/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// When they aren't, the VCS details the Go toolchain stamps into the binary
+// are used instead.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	GitSHA    = ""
+	BuildTime = ""
+)
+
+type Info struct {
+	GitSHA    string `json:"git_sha"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+func Get() Info {
+	info := Info{
+		GitSHA:    GitSHA,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.GitSHA == "" {
+					info.GitSHA = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			}
+		}
+	}
+
+	if info.GitSHA == "" {
+		info.GitSHA = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// TTL is a small in-memory cache whose entries expire after a fixed duration.
+// It holds at most maxEntries values; when full, expired entries are purged
+// first and then arbitrary entries are evicted.
+type TTL[K comparable, V any] struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[K]entry[V]
+	// generation is advanced by every Delete; see SetIfCurrent.
+	generation uint64
+}
+
+func NewTTL[K comparable, V any](ttl time.Duration, maxEntries int) *TTL[K, V] {
+	return &TTL[K, V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[K]entry[V]),
+	}
+}
+
+func (c *TTL[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (c *TTL[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setLocked(key, value)
+}
+
+// Generation returns the cache's current generation. Read it before computing
+// a value to store with SetIfCurrent.
+func (c *TTL[K, V]) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// SetIfCurrent stores value only if nothing has been deleted since gen was
+// read, so a value computed from data that was invalidated meanwhile isn't
+// cached. It reports whether the value was stored.
+func (c *TTL[K, V]) SetIfCurrent(key K, value V, gen uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != gen {
+		return false
+	}
+	c.setLocked(key, value)
+	return true
+}
+
+func (c *TTL[K, V]) Delete(keys ...K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+// Purge drops every expired entry.
+func (c *TTL[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *TTL[K, V]) setLocked(key K, value V) {
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictLocked()
+	}
+	c.entries[key] = entry[V]{value: value, expiresAt: time.Now().Add(c.ttl)}
+}
+
+func (c *TTL[K, V]) evictLocked() {
+	now := time.Now()
+	for key, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.maxEntries {
+			return
+		}
+		delete(c.entries, key)
+	}
+}
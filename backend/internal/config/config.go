@@ -0,0 +1,813 @@
+// Package config loads the server configuration. Values come from built-in
+// defaults, then an optional YAML file, then the environment (including a
+// .env file), with later sources overriding earlier ones.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigFile = "config.yaml"
+
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+type Config struct {
+	Environment string     `yaml:"environment"`
+	Port        string     `yaml:"port"`
+	PublicURL   string     `yaml:"public_url"`
+	JWTSecret   string     `yaml:"jwt_secret"`
+	LogLevel    string     `yaml:"log_level"`
+	Server      Server     `yaml:"server"`
+	Database    Database   `yaml:"database"`
+	CORS        CORS       `yaml:"cors"`
+	Tokens      Tokens     `yaml:"tokens"`
+	Features    Features   `yaml:"features"`
+	Limits      Limits     `yaml:"limits"`
+	RateLimits  RateLimits `yaml:"rate_limits"`
+	Login       Login      `yaml:"login"`
+	Notify      Notify     `yaml:"notify"`
+	WebAuthn    WebAuthn   `yaml:"webauthn"`
+	OIDC        OIDC       `yaml:"oidc"`
+}
+
+type Server struct {
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	DrainDelay        time.Duration `yaml:"drain_delay"`
+}
+
+type Database struct {
+	// Provider is how the connection is made: "dsn" opens URL, "unix"
+	// connects to Postgres over the socket in SocketDir and "cloudsql" uses
+	// the Cloud SQL connector for Instance.
+	Provider string `yaml:"provider"`
+	URL      string `yaml:"url"`
+
+	// Used by the unix and cloudsql providers.
+	SocketDir string `yaml:"socket_dir"`
+	Instance  string `yaml:"instance"`
+	User      string `yaml:"user"`
+	Password  string `yaml:"password"`
+	Name      string `yaml:"name"`
+	IAMAuth   bool   `yaml:"iam_auth"`
+	PrivateIP bool   `yaml:"private_ip"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	// ReplicaURLs are read replicas of the primary, in the same dialect.
+	// Profile and feed reads go to a healthy replica unless the user wrote
+	// something within ReplicaStickiness.
+	ReplicaURLs           []string      `yaml:"replica_urls"`
+	ReplicaStickiness     time.Duration `yaml:"replica_stickiness"`
+	ReplicaHealthInterval time.Duration `yaml:"replica_health_interval"`
+
+	// ConnectAttempts is how many times the first connection is tried,
+	// waiting ConnectBackoff after the first failure and doubling the wait
+	// after each one.
+	ConnectAttempts int           `yaml:"connect_attempts"`
+	ConnectBackoff  time.Duration `yaml:"connect_backoff"`
+	// AutoMigrate applies pending migrations at startup instead of refusing
+	// to start.
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+type CORS struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	MaxAge         int      `yaml:"max_age"`
+}
+
+type Tokens struct {
+	AccessTTL  time.Duration `yaml:"access_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+	// MFATTL is how long a user has to enter their second factor after
+	// their password.
+	MFATTL time.Duration `yaml:"mfa_ttl"`
+	// Algorithm signs access tokens: HS256 with JWT_SECRET, or EdDSA or
+	// RS256 with a key pair that is replaced every KeyRotation. A new key is
+	// published KeyOverlap before it starts signing, and the old one is kept
+	// for KeyOverlap after it stops.
+	Algorithm   string        `yaml:"algorithm"`
+	KeyRotation time.Duration `yaml:"key_rotation"`
+	KeyOverlap  time.Duration `yaml:"key_overlap"`
+	// AcceptHMAC keeps HS256 tokens valid after switching to a key pair.
+	AcceptHMAC bool `yaml:"accept_hmac"`
+}
+
+type Features struct {
+	Search           bool `yaml:"search"`
+	Suggestions      bool `yaml:"suggestions"`
+	LinkVerification bool `yaml:"link_verification"`
+	Metrics          bool `yaml:"metrics"`
+}
+
+type Limits struct {
+	MaxBodyBytes           int64         `yaml:"max_body_bytes"`
+	MaxSearchResults       int           `yaml:"max_search_results"`
+	MaxProfileLinks        int           `yaml:"max_profile_links"`
+	UsernameChangeCooldown time.Duration `yaml:"username_change_cooldown"`
+	UsernameHoldPeriod     time.Duration `yaml:"username_hold_period"`
+	SuggestionsCacheTTL    time.Duration `yaml:"suggestions_cache_ttl"`
+}
+
+// Login controls account lockout. After MaxFailures failed logins within
+// FailureWindow an account is locked for LockoutBase, doubling with every
+// further failure up to LockoutMax.
+type Login struct {
+	MaxFailures      int           `yaml:"max_failures"`
+	FailureWindow    time.Duration `yaml:"failure_window"`
+	LockoutBase      time.Duration `yaml:"lockout_base"`
+	LockoutMax       time.Duration `yaml:"lockout_max"`
+	AttemptRetention time.Duration `yaml:"attempt_retention"`
+}
+
+// Notify says where security notifications such as new-device logins go.
+// Without a WebhookURL they are only logged.
+type Notify struct {
+	WebhookURL string `yaml:"webhook_url"`
+	// WebhookSecret signs each webhook body with HMAC-SHA256.
+	WebhookSecret string `yaml:"webhook_secret"`
+}
+
+// WebAuthn describes the relying party passkeys are registered with. RPID
+// defaults to the host of PublicURL and Origins to PublicURL itself; a
+// passkey only works on the RPID it was registered for.
+type WebAuthn struct {
+	RPID    string   `yaml:"rp_id"`
+	RPName  string   `yaml:"rp_name"`
+	Origins []string `yaml:"origins"`
+	// Timeout is how long the browser has to finish a registration or
+	// login ceremony once it has started.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// OIDC lists the OpenID Connect providers users can sign in with, keyed by
+// the name used in their URLs.
+type OIDC struct {
+	Providers map[string]OIDCProvider `yaml:"providers"`
+}
+
+// oidcEnvPrefix is the start of the environment variables for a provider,
+// e.g. OIDC_MY_IDP for my-idp.
+func oidcEnvPrefix(name string) string {
+	return "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// OIDCProvider is one provider, found through discovery on Issuer.
+// RedirectURL defaults to PUBLIC_URL + "/auth/oidc/<name>/callback".
+type OIDCProvider struct {
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+type RateLimits struct {
+	Enabled bool `yaml:"enabled"`
+	// Store is where the token buckets live: "memory" per instance, or
+	// "database" to share them between instances.
+	Store string `yaml:"store"`
+	// TrustProxy takes the client IP from the last X-Forwarded-For entry
+	// instead of the connection, for servers behind a load balancer.
+	TrustProxy bool `yaml:"trust_proxy"`
+	// Routes maps a route name to its limits. A route listed in the config
+	// file replaces its defaults entirely.
+	Routes map[string]RouteLimits `yaml:"routes"`
+}
+
+// LongestPer returns the longest window of any configured rate. A bucket
+// unused for that long is full again and can be forgotten.
+func (r RateLimits) LongestPer() time.Duration {
+	var longest time.Duration
+	for _, limits := range r.Routes {
+		longest = max(longest, limits.IP.Per, limits.User.Per, limits.Username.Per)
+	}
+	return longest
+}
+
+// RouteLimits are the buckets a request to one route draws from: one per
+// client IP, one per authenticated user and one per username named in the
+// request body. A zero Rate leaves that key unlimited.
+type RouteLimits struct {
+	IP       Rate `yaml:"ip"`
+	User     Rate `yaml:"user"`
+	Username Rate `yaml:"username"`
+}
+
+// Rate allows Requests requests per Per, in bursts of up to Requests. It is
+// written as "20/1m"; "off" or "" is no limit.
+type Rate struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseRate parses the "20/1m" form of a Rate.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return Rate{}, nil
+	}
+	count, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("rate must look like 20/1m, got %q", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return Rate{}, fmt.Errorf("rate must start with a positive count, got %q", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || d <= 0 {
+		return Rate{}, fmt.Errorf("rate must end with a positive duration, got %q", s)
+	}
+	return Rate{Requests: n, Per: d}, nil
+}
+
+func (r *Rate) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: rate must look like 20/1m", value.Line)
+	}
+	rate, err := ParseRate(value.Value)
+	if err != nil {
+		return err
+	}
+	*r = rate
+	return nil
+}
+
+// Enabled reports whether the rate limits anything.
+func (r Rate) Enabled() bool {
+	return r.Requests > 0
+}
+
+// Default returns the configuration used when nothing overrides it. Secrets
+// and the database URL have no default.
+func Default() Config {
+	return Config{
+		Environment: "production",
+		PublicURL:   "http://localhost:3000",
+		LogLevel:    "info",
+		Server: Server{
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: Database{
+			Provider:        "dsn",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 5 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectAttempts: 5,
+			ConnectBackoff:  time.Second,
+
+			ReplicaStickiness:     5 * time.Second,
+			ReplicaHealthInterval: 10 * time.Second,
+		},
+		CORS: CORS{
+			AllowedOrigins: []string{"https://*", "http://*"},
+			MaxAge:         300,
+		},
+		Tokens: Tokens{
+			AccessTTL:   time.Hour,
+			RefreshTTL:  60 * 24 * time.Hour,
+			MFATTL:      5 * time.Minute,
+			Algorithm:   "HS256",
+			KeyRotation: 30 * 24 * time.Hour,
+			KeyOverlap:  24 * time.Hour,
+			AcceptHMAC:  true,
+		},
+		Features: Features{
+			Search:           true,
+			Suggestions:      true,
+			LinkVerification: true,
+			Metrics:          true,
+		},
+		Limits: Limits{
+			MaxBodyBytes:           1 << 20,
+			MaxSearchResults:       50,
+			MaxProfileLinks:        5,
+			UsernameChangeCooldown: 30 * 24 * time.Hour,
+			UsernameHoldPeriod:     14 * 24 * time.Hour,
+			SuggestionsCacheTTL:    10 * time.Minute,
+		},
+		Login: Login{
+			MaxFailures:      5,
+			FailureWindow:    24 * time.Hour,
+			LockoutBase:      time.Minute,
+			LockoutMax:       time.Hour,
+			AttemptRetention: 90 * 24 * time.Hour,
+		},
+		WebAuthn: WebAuthn{
+			RPName:  "Tanglr",
+			Timeout: 5 * time.Minute,
+		},
+		RateLimits: RateLimits{
+			Enabled: true,
+			Store:   "memory",
+			Routes: map[string]RouteLimits{
+				"login": {
+					IP:       Rate{Requests: 20, Per: time.Minute},
+					Username: Rate{Requests: 10, Per: 15 * time.Minute},
+				},
+				"create_user": {
+					IP: Rate{Requests: 5, Per: time.Hour},
+				},
+				"login_mfa": {
+					IP: Rate{Requests: 20, Per: time.Minute},
+				},
+				"mfa": {
+					User: Rate{Requests: 10, Per: 15 * time.Minute},
+				},
+				"passkey_login": {
+					IP: Rate{Requests: 20, Per: time.Minute},
+				},
+				"passkeys": {
+					User: Rate{Requests: 10, Per: 15 * time.Minute},
+				},
+				"oidc_login": {
+					IP: Rate{Requests: 20, Per: time.Minute},
+				},
+				"oidc_link": {
+					User: Rate{Requests: 10, Per: 15 * time.Minute},
+				},
+				"appeals": {
+					IP:       Rate{Requests: 20, Per: time.Minute},
+					Username: Rate{Requests: 10, Per: 15 * time.Minute},
+				},
+				"create_post": {
+					IP:   Rate{Requests: 60, Per: time.Minute},
+					User: Rate{Requests: 30, Per: time.Minute},
+				},
+			},
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the YAML file named by
+// CONFIG_FILE (or config.yaml if it exists) and the environment. The returned
+// error lists every problem found, not just the first.
+func Load() (*Config, error) {
+	// A missing .env is fine; the environment may be set some other way.
+	_ = godotenv.Load(".env")
+
+	cfg := Default()
+	var errs []error
+
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = defaultConfigFile
+	}
+	if err := loadFile(path, &cfg); err != nil {
+		if explicit || !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	env := envLoader{}
+	env.string("ENVIRONMENT", &cfg.Environment)
+	env.string("PORT", &cfg.Port)
+	env.string("PUBLIC_URL", &cfg.PublicURL)
+	env.string("JWT_SECRET", &cfg.JWTSecret)
+	env.string("LOG_LEVEL", &cfg.LogLevel)
+	env.duration("SERVER_READ_TIMEOUT", &cfg.Server.ReadTimeout)
+	env.duration("SERVER_READ_HEADER_TIMEOUT", &cfg.Server.ReadHeaderTimeout)
+	env.duration("SERVER_WRITE_TIMEOUT", &cfg.Server.WriteTimeout)
+	env.duration("SERVER_IDLE_TIMEOUT", &cfg.Server.IdleTimeout)
+	env.int("SERVER_MAX_HEADER_BYTES", &cfg.Server.MaxHeaderBytes)
+	env.duration("SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+	env.duration("SHUTDOWN_DRAIN_DELAY", &cfg.Server.DrainDelay)
+	env.string("DB_PROVIDER", &cfg.Database.Provider)
+	env.string("DATABASE_URL", &cfg.Database.URL)
+	env.string("DB_SOCKET_DIR", &cfg.Database.SocketDir)
+	env.string("DB_INSTANCE", &cfg.Database.Instance)
+	env.string("DB_USER", &cfg.Database.User)
+	env.string("DB_PASSWORD", &cfg.Database.Password)
+	env.string("DB_NAME", &cfg.Database.Name)
+	env.bool("DB_IAM_AUTH", &cfg.Database.IAMAuth)
+	env.bool("DB_PRIVATE_IP", &cfg.Database.PrivateIP)
+	env.int("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	env.int("DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	env.duration("DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+	env.duration("DB_CONN_MAX_IDLE_TIME", &cfg.Database.ConnMaxIdleTime)
+	env.int("DB_CONNECT_ATTEMPTS", &cfg.Database.ConnectAttempts)
+	env.duration("DB_CONNECT_BACKOFF", &cfg.Database.ConnectBackoff)
+	env.list("DB_REPLICA_URLS", &cfg.Database.ReplicaURLs)
+	env.duration("DB_REPLICA_STICKINESS", &cfg.Database.ReplicaStickiness)
+	env.duration("DB_REPLICA_HEALTH_INTERVAL", &cfg.Database.ReplicaHealthInterval)
+	env.bool("DB_AUTO_MIGRATE", &cfg.Database.AutoMigrate)
+	env.list("CORS_ALLOWED_ORIGINS", &cfg.CORS.AllowedOrigins)
+	env.int("CORS_MAX_AGE", &cfg.CORS.MaxAge)
+	env.duration("ACCESS_TOKEN_TTL", &cfg.Tokens.AccessTTL)
+	env.duration("REFRESH_TOKEN_TTL", &cfg.Tokens.RefreshTTL)
+	env.duration("MFA_TOKEN_TTL", &cfg.Tokens.MFATTL)
+	env.string("JWT_ALGORITHM", &cfg.Tokens.Algorithm)
+	env.duration("JWT_KEY_ROTATION", &cfg.Tokens.KeyRotation)
+	env.duration("JWT_KEY_OVERLAP", &cfg.Tokens.KeyOverlap)
+	env.bool("JWT_ACCEPT_HMAC", &cfg.Tokens.AcceptHMAC)
+	env.bool("FEATURE_SEARCH", &cfg.Features.Search)
+	env.bool("FEATURE_SUGGESTIONS", &cfg.Features.Suggestions)
+	env.bool("FEATURE_LINK_VERIFICATION", &cfg.Features.LinkVerification)
+	env.bool("FEATURE_METRICS", &cfg.Features.Metrics)
+	env.int64("MAX_BODY_BYTES", &cfg.Limits.MaxBodyBytes)
+	env.int("MAX_SEARCH_RESULTS", &cfg.Limits.MaxSearchResults)
+	env.int("MAX_PROFILE_LINKS", &cfg.Limits.MaxProfileLinks)
+	env.duration("USERNAME_CHANGE_COOLDOWN", &cfg.Limits.UsernameChangeCooldown)
+	env.duration("USERNAME_HOLD_PERIOD", &cfg.Limits.UsernameHoldPeriod)
+	env.duration("SUGGESTIONS_CACHE_TTL", &cfg.Limits.SuggestionsCacheTTL)
+	env.int("LOGIN_MAX_FAILURES", &cfg.Login.MaxFailures)
+	env.duration("LOGIN_FAILURE_WINDOW", &cfg.Login.FailureWindow)
+	env.duration("LOGIN_LOCKOUT_BASE", &cfg.Login.LockoutBase)
+	env.duration("LOGIN_LOCKOUT_MAX", &cfg.Login.LockoutMax)
+	env.duration("LOGIN_ATTEMPT_RETENTION", &cfg.Login.AttemptRetention)
+	env.string("NOTIFY_WEBHOOK_URL", &cfg.Notify.WebhookURL)
+	env.string("NOTIFY_WEBHOOK_SECRET", &cfg.Notify.WebhookSecret)
+	env.string("WEBAUTHN_RP_ID", &cfg.WebAuthn.RPID)
+	env.string("WEBAUTHN_RP_NAME", &cfg.WebAuthn.RPName)
+	env.list("WEBAUTHN_ORIGINS", &cfg.WebAuthn.Origins)
+	env.duration("WEBAUTHN_TIMEOUT", &cfg.WebAuthn.Timeout)
+	var oidcProviders []string
+	env.list("OIDC_PROVIDERS", &oidcProviders)
+	for _, name := range oidcProviders {
+		if cfg.OIDC.Providers == nil {
+			cfg.OIDC.Providers = map[string]OIDCProvider{}
+		}
+		provider := cfg.OIDC.Providers[name]
+		prefix := oidcEnvPrefix(name)
+		env.string(prefix+"_ISSUER", &provider.Issuer)
+		env.string(prefix+"_CLIENT_ID", &provider.ClientID)
+		env.string(prefix+"_CLIENT_SECRET", &provider.ClientSecret)
+		env.string(prefix+"_REDIRECT_URL", &provider.RedirectURL)
+		env.list(prefix+"_SCOPES", &provider.Scopes)
+		cfg.OIDC.Providers[name] = provider
+	}
+	env.bool("RATE_LIMIT_ENABLED", &cfg.RateLimits.Enabled)
+	env.string("RATE_LIMIT_STORE", &cfg.RateLimits.Store)
+	env.bool("RATE_LIMIT_TRUST_PROXY", &cfg.RateLimits.TrustProxy)
+	for route, limits := range cfg.RateLimits.Routes {
+		prefix := "RATE_LIMIT_" + strings.ToUpper(route)
+		env.rate(prefix+"_IP", &limits.IP)
+		env.rate(prefix+"_USER", &limits.User)
+		env.rate(prefix+"_USERNAME", &limits.Username)
+		cfg.RateLimits.Routes[route] = limits
+	}
+	errs = append(errs, env.errs...)
+
+	for name, provider := range cfg.OIDC.Providers {
+		if provider.RedirectURL == "" {
+			provider.RedirectURL = strings.TrimSuffix(cfg.PublicURL, "/") + "/auth/oidc/" + name + "/callback"
+			cfg.OIDC.Providers[name] = provider
+		}
+	}
+
+	// Passkeys follow the public URL unless told otherwise.
+	if u, err := url.Parse(cfg.PublicURL); err == nil {
+		if cfg.WebAuthn.RPID == "" {
+			cfg.WebAuthn.RPID = u.Hostname()
+		}
+		if len(cfg.WebAuthn.Origins) == 0 && u.Host != "" {
+			cfg.WebAuthn.Origins = []string{u.Scheme + "://" + u.Host}
+		}
+	}
+
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return &cfg, nil
+}
+
+// SlogLevel parses LogLevel.
+func (c *Config) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.LogLevel))
+	return level, err
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.Environment {
+	case "development", "staging", "production":
+	default:
+		fail("ENVIRONMENT must be development, staging or production, got %q", c.Environment)
+	}
+
+	if c.Port == "" {
+		fail("PORT is required")
+	} else if port, err := strconv.Atoi(c.Port); err != nil || port <= 0 || port > 65535 {
+		fail("PORT must be a number between 1 and 65535, got %q", c.Port)
+	}
+
+	if u, err := url.Parse(c.PublicURL); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("PUBLIC_URL must be an absolute URL, got %q", c.PublicURL)
+	}
+
+	if c.JWTSecret == "" {
+		fail("JWT_SECRET is required")
+	} else if c.Environment == "production" && len(c.JWTSecret) < 32 {
+		fail("JWT_SECRET must be at least 32 bytes in production")
+	}
+
+	if _, err := c.SlogLevel(); err != nil {
+		fail("LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel)
+	}
+
+	if c.Server.ReadTimeout <= 0 || c.Server.ReadHeaderTimeout <= 0 ||
+		c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 {
+		fail("server timeouts must be positive")
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		fail("SERVER_MAX_HEADER_BYTES must be positive")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("SHUTDOWN_TIMEOUT must be positive")
+	}
+	if c.Server.DrainDelay < 0 {
+		fail("SHUTDOWN_DRAIN_DELAY can't be negative")
+	}
+
+	switch c.Database.Provider {
+	case "dsn":
+		if c.Database.URL == "" {
+			fail("DATABASE_URL is required")
+		}
+	case "unix":
+		if c.Database.SocketDir == "" || c.Database.User == "" || c.Database.Name == "" {
+			fail("DB_PROVIDER=unix needs DB_SOCKET_DIR, DB_USER and DB_NAME")
+		}
+	case "cloudsql":
+		if c.Database.Instance == "" || c.Database.User == "" || c.Database.Name == "" {
+			fail("DB_PROVIDER=cloudsql needs DB_INSTANCE, DB_USER and DB_NAME")
+		}
+		if !c.Database.IAMAuth && c.Database.Password == "" {
+			fail("DB_PROVIDER=cloudsql needs DB_PASSWORD unless DB_IAM_AUTH is set")
+		}
+	default:
+		fail("DB_PROVIDER must be dsn, unix or cloudsql, got %q", c.Database.Provider)
+	}
+	if c.Database.MaxOpenConns <= 0 {
+		fail("DB_MAX_OPEN_CONNS must be positive")
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		fail("DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS")
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		fail("DB_CONN_MAX_LIFETIME can't be negative")
+	}
+	if c.Database.ConnMaxIdleTime < 0 {
+		fail("DB_CONN_MAX_IDLE_TIME can't be negative")
+	}
+	if c.Database.ConnectAttempts <= 0 {
+		fail("DB_CONNECT_ATTEMPTS must be positive")
+	}
+	if c.Database.ConnectBackoff <= 0 {
+		fail("DB_CONNECT_BACKOFF must be positive")
+	}
+	if c.Database.ReplicaStickiness < 0 {
+		fail("DB_REPLICA_STICKINESS can't be negative")
+	}
+	if c.Database.ReplicaHealthInterval <= 0 {
+		fail("DB_REPLICA_HEALTH_INTERVAL must be positive")
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		fail("CORS_ALLOWED_ORIGINS needs at least one origin")
+	}
+	if c.CORS.MaxAge < 0 {
+		fail("CORS_MAX_AGE can't be negative")
+	}
+
+	if c.Tokens.AccessTTL <= 0 {
+		fail("ACCESS_TOKEN_TTL must be positive")
+	}
+	if c.Tokens.RefreshTTL <= c.Tokens.AccessTTL {
+		fail("REFRESH_TOKEN_TTL must be longer than ACCESS_TOKEN_TTL")
+	}
+	if c.Tokens.MFATTL <= 0 {
+		fail("MFA_TOKEN_TTL must be positive")
+	}
+	switch c.Tokens.Algorithm {
+	case "HS256":
+	case "EdDSA", "RS256":
+		// Tokens must stay verifiable for their whole life after their key
+		// stops signing.
+		if c.Tokens.KeyOverlap < c.Tokens.AccessTTL || c.Tokens.KeyOverlap < time.Minute {
+			fail("JWT_KEY_OVERLAP must be at least ACCESS_TOKEN_TTL and at least 1m")
+		}
+		if c.Tokens.KeyRotation <= c.Tokens.KeyOverlap {
+			fail("JWT_KEY_ROTATION must be longer than JWT_KEY_OVERLAP")
+		}
+	default:
+		fail("JWT_ALGORITHM must be HS256, EdDSA or RS256, got %q", c.Tokens.Algorithm)
+	}
+
+	if c.Limits.MaxBodyBytes <= 0 {
+		fail("MAX_BODY_BYTES must be positive")
+	}
+	if c.Limits.MaxSearchResults <= 0 {
+		fail("MAX_SEARCH_RESULTS must be positive")
+	}
+	if c.Limits.MaxProfileLinks < 0 {
+		fail("MAX_PROFILE_LINKS can't be negative")
+	}
+	if c.Limits.UsernameChangeCooldown < 0 {
+		fail("USERNAME_CHANGE_COOLDOWN can't be negative")
+	}
+	if c.Limits.UsernameHoldPeriod < 0 {
+		fail("USERNAME_HOLD_PERIOD can't be negative")
+	}
+	if c.Limits.SuggestionsCacheTTL <= 0 {
+		fail("SUGGESTIONS_CACHE_TTL must be positive")
+	}
+
+	if c.Login.MaxFailures <= 0 {
+		fail("LOGIN_MAX_FAILURES must be positive")
+	}
+	if c.Login.FailureWindow <= 0 {
+		fail("LOGIN_FAILURE_WINDOW must be positive")
+	}
+	if c.Login.LockoutBase <= 0 || c.Login.LockoutMax < c.Login.LockoutBase {
+		fail("LOGIN_LOCKOUT_BASE must be positive and no longer than LOGIN_LOCKOUT_MAX")
+	}
+	if c.Login.AttemptRetention <= 0 {
+		fail("LOGIN_ATTEMPT_RETENTION must be positive")
+	}
+	if c.Notify.WebhookURL != "" {
+		if u, err := url.Parse(c.Notify.WebhookURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			fail("NOTIFY_WEBHOOK_URL must be an http(s) URL, got %q", c.Notify.WebhookURL)
+		}
+	}
+
+	if c.WebAuthn.RPID == "" || c.WebAuthn.RPName == "" {
+		fail("WEBAUTHN_RP_ID and WEBAUTHN_RP_NAME can't be empty")
+	}
+	if len(c.WebAuthn.Origins) == 0 {
+		fail("WEBAUTHN_ORIGINS needs at least one origin")
+	}
+	for _, origin := range c.WebAuthn.Origins {
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("WEBAUTHN_ORIGINS must be absolute origins like https://tanglr.example, got %q", origin)
+		}
+	}
+	if c.WebAuthn.Timeout <= 0 {
+		fail("WEBAUTHN_TIMEOUT must be positive")
+	}
+
+	for name, provider := range c.OIDC.Providers {
+		if !oidcProviderName.MatchString(name) {
+			fail("OIDC provider names must be lowercase letters, digits and dashes, got %q", name)
+		}
+		prefix := oidcEnvPrefix(name)
+		if u, err := url.Parse(provider.Issuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			fail("%s_ISSUER must be an http(s) URL, got %q", prefix, provider.Issuer)
+		}
+		if provider.ClientID == "" {
+			fail("%s_CLIENT_ID is required", prefix)
+		}
+		if u, err := url.Parse(provider.RedirectURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("%s_REDIRECT_URL must be an absolute URL, got %q", prefix, provider.RedirectURL)
+		}
+	}
+
+	switch c.RateLimits.Store {
+	case "memory", "database":
+	default:
+		fail("RATE_LIMIT_STORE must be memory or database, got %q", c.RateLimits.Store)
+	}
+
+	return errs
+}
+
+// envLoader overrides config values from environment variables, collecting
+// parse errors instead of stopping at the first one.
+type envLoader struct {
+	errs []error
+}
+
+func (l *envLoader) lookup(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(value), true
+}
+
+func (l *envLoader) string(key string, dst *string) {
+	if value, ok := l.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (l *envLoader) int(key string, dst *int) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+		return
+	}
+	*dst = n
+}
+
+func (l *envLoader) int64(key string, dst *int64) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+		return
+	}
+	*dst = n
+}
+
+func (l *envLoader) bool(key string, dst *bool) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s must be true or false, got %q", key, value))
+		return
+	}
+	*dst = b
+}
+
+func (l *envLoader) duration(key string, dst *time.Duration) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s must be a duration like 1h or 30m, got %q", key, value))
+		return
+	}
+	*dst = d
+}
+
+func (l *envLoader) list(key string, dst *[]string) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func (l *envLoader) rate(key string, dst *Rate) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+	rate, err := ParseRate(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: %v", key, err))
+		return
+	}
+	*dst = rate
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupEnv runs the test in an empty directory, so no stray .env or
+// config.yaml is picked up, with the settings that have no default.
+func setupEnv(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	t.Setenv("PORT", "8080")
+	t.Setenv("DATABASE_URL", "postgres://localhost/tanglr")
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
+}
+
+func writeConfigFile(t *testing.T, contents string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tanglr.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setupEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := Default()
+	if cfg.Tokens.AccessTTL != want.Tokens.AccessTTL || cfg.Database.MaxOpenConns != want.Database.MaxOpenConns {
+		t.Errorf("Load() = %+v, want the defaults", cfg)
+	}
+	// Passkeys follow PUBLIC_URL when nothing else is set.
+	if cfg.WebAuthn.RPID != "localhost" || len(cfg.WebAuthn.Origins) != 1 || cfg.WebAuthn.Origins[0] != "http://localhost:3000" {
+		t.Errorf("WebAuthn = %+v, want it derived from PUBLIC_URL", cfg.WebAuthn)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	setupEnv(t)
+	writeConfigFile(t, `
+port: "9000"
+log_level: debug
+tokens:
+  access_ttl: 15m
+cors:
+  allowed_origins: ["https://from-file.example"]
+rate_limits:
+  routes:
+    login:
+      ip: 3/1m
+`)
+	t.Setenv("PORT", "9100")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example, https://b.example")
+	t.Setenv("RATE_LIMIT_LOGIN_USERNAME", "off")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Port != "9100" {
+		t.Errorf("Port = %q, want the environment to beat the file", cfg.Port)
+	}
+	if cfg.LogLevel != "debug" || cfg.Tokens.AccessTTL != 15*time.Minute {
+		t.Errorf("LogLevel = %q, AccessTTL = %v, want the file to beat the defaults", cfg.LogLevel, cfg.Tokens.AccessTTL)
+	}
+	if got := cfg.CORS.AllowedOrigins; len(got) != 2 || got[1] != "https://b.example" {
+		t.Errorf("AllowedOrigins = %q", got)
+	}
+	login := cfg.RateLimits.Routes["login"]
+	if login.IP != (Rate{Requests: 3, Per: time.Minute}) || login.Username.Enabled() {
+		t.Errorf("login limits = %+v", login)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("PORT", "http")
+	t.Setenv("ACCESS_TOKEN_TTL", "soon")
+	t.Setenv("DB_MAX_OPEN_CONNS", "2")
+	t.Setenv("DB_MAX_IDLE_CONNS", "10")
+	t.Setenv("RATE_LIMIT_STORE", "redis")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Load() error = nil")
+	}
+	for _, want := range []string{
+		"JWT_SECRET is required",
+		"PORT must be a number",
+		"ACCESS_TOKEN_TTL must be a duration",
+		"DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS",
+		"RATE_LIMIT_STORE must be memory or database",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Load() error is missing %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadMissingConfigFile(t *testing.T) {
+	setupEnv(t)
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load() without config.yaml = %v, want nil", err)
+	}
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := Load(); err == nil {
+		t.Error("Load() with a missing CONFIG_FILE = nil, want an error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{
+			name:   "short production secret",
+			modify: func(c *Config) { c.JWTSecret = "short" },
+			want:   "JWT_SECRET must be at least 32 bytes",
+		},
+		{
+			name:   "refresh shorter than access",
+			modify: func(c *Config) { c.Tokens.RefreshTTL = c.Tokens.AccessTTL },
+			want:   "REFRESH_TOKEN_TTL must be longer",
+		},
+		{
+			name: "key overlap shorter than access tokens",
+			modify: func(c *Config) {
+				c.Tokens.Algorithm = "EdDSA"
+				c.Tokens.KeyOverlap = c.Tokens.AccessTTL / 2
+			},
+			want: "JWT_KEY_OVERLAP must be at least ACCESS_TOKEN_TTL",
+		},
+		{
+			name:   "cloudsql without credentials",
+			modify: func(c *Config) { c.Database.Provider = "cloudsql" },
+			want:   "DB_PROVIDER=cloudsql needs DB_INSTANCE",
+		},
+		{
+			name:   "bad OIDC provider name",
+			modify: func(c *Config) { c.OIDC.Providers = map[string]OIDCProvider{"My IdP": {}} },
+			want:   "OIDC provider names must be lowercase",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Port = "8080"
+			cfg.JWTSecret = strings.Repeat("s", 32)
+			cfg.Database.URL = "postgres://localhost/tanglr"
+			cfg.WebAuthn.RPID = "localhost"
+			cfg.WebAuthn.Origins = []string{"http://localhost:3000"}
+			if errs := cfg.validate(); len(errs) != 0 {
+				t.Fatalf("validate() of the base config = %v", errs)
+			}
+
+			tt.modify(&cfg)
+			found := false
+			for _, err := range cfg.validate() {
+				if strings.Contains(err.Error(), tt.want) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("validate() = %v, want an error containing %q", cfg.validate(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		wantErr bool
+	}{
+		{in: "20/1m", want: Rate{Requests: 20, Per: time.Minute}},
+		{in: " 5 / 15m ", want: Rate{Requests: 5, Per: 15 * time.Minute}},
+		{in: "off"},
+		{in: ""},
+		{in: "20", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "20/forever", wantErr: true},
+		{in: "20/-1m", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRate(%q) = %+v, %v; want %+v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
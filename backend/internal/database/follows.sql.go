@@ -31,6 +31,126 @@ func (q *Queries) AcceptFollowRequest(ctx context.Context, arg AcceptFollowReque
 	return err
 }
 
+const deleteFollowsBetween = `-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (initiator_id = $1 AND target_id = $2)
+   OR (initiator_id = $2 AND target_id = $1)
+`
+
+type DeleteFollowsBetweenParams struct {
+	InitiatorID uuid.UUID
+	TargetID    uuid.UUID
+}
+
+func (q *Queries) DeleteFollowsBetween(ctx context.Context, arg DeleteFollowsBetweenParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFollowsBetween, arg.InitiatorID, arg.TargetID)
+	return err
+}
+
+const getFollowSuggestions = `-- name: GetFollowSuggestions :many
+WITH my_following AS (
+    SELECT target_id
+    FROM follows
+    WHERE initiator_id = $1
+        AND status = 'accepted'
+),
+candidates AS (
+    SELECT f.target_id AS user_id, COUNT(*) AS mutual_count
+    FROM follows f
+    JOIN my_following mf ON f.initiator_id = mf.target_id
+    WHERE f.status = 'accepted'
+    GROUP BY f.target_id
+    UNION ALL
+    SELECT DISTINCT p.user_id, 0
+    FROM posts p
+    WHERE p.created_at > NOW() - INTERVAL '30 days'
+        AND p.is_deleted = false
+),
+ranked AS (
+    SELECT user_id, MAX(mutual_count)::bigint AS mutual_count
+    FROM candidates
+    GROUP BY user_id
+)
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url,
+    r.mutual_count,
+    (
+      SELECT COUNT(*)
+      FROM follows f
+      WHERE f.target_id = u.id
+      AND f.status = 'accepted'
+    ) AS follower_count,
+    (
+      SELECT MAX(p.created_at)
+      FROM posts p
+      WHERE p.user_id = u.id
+      AND p.is_deleted = false
+    )::timestamptz AS last_posted_at
+FROM ranked r
+JOIN users u ON r.user_id = u.id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE u.id != $1
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM follows f
+    WHERE f.initiator_id = $1
+        AND f.target_id = u.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $1 AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = $1)
+)
+ORDER BY r.mutual_count DESC, last_posted_at DESC NULLS LAST, follower_count DESC
+LIMIT $2
+`
+
+type GetFollowSuggestionsParams struct {
+	ViewerID    uuid.UUID
+	ResultLimit int32
+}
+
+type GetFollowSuggestionsRow struct {
+	ID            uuid.UUID
+	Username      string
+	AvatarUrl     sql.NullString
+	MutualCount   int64
+	FollowerCount int64
+	LastPostedAt  sql.NullTime
+}
+
+func (q *Queries) GetFollowSuggestions(ctx context.Context, arg GetFollowSuggestionsParams) ([]GetFollowSuggestionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowSuggestions, arg.ViewerID, arg.ResultLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowSuggestionsRow
+	for rows.Next() {
+		var i GetFollowSuggestionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.AvatarUrl,
+			&i.MutualCount,
+			&i.FollowerCount,
+			&i.LastPostedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFollowerList = `-- name: GetFollowerList :many
 SELECT
     f.initiator_id, f.target_id, f.status, f.created_at, f.updated_at,
@@ -143,9 +263,184 @@ func (q *Queries) GetFollowingList(ctx context.Context, initiatorID uuid.UUID) (
 	return items, nil
 }
 
+const getFriendsList = `-- name: GetFriendsList :many
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url,
+    GREATEST(f1.updated_at, f2.updated_at)::timestamptz AS friends_since
+FROM follows f1
+JOIN follows f2
+    ON f2.initiator_id = f1.target_id
+    AND f2.target_id = f1.initiator_id
+    AND f2.status = 'accepted'
+JOIN users u ON u.id = f1.target_id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE f1.initiator_id = $1
+AND f1.status = 'accepted'
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = $2)
+)
+ORDER BY u.username
+`
+
+type GetFriendsListParams struct {
+	UserID   uuid.UUID
+	ViewerID uuid.UUID
+}
+
+type GetFriendsListRow struct {
+	ID           uuid.UUID
+	Username     string
+	AvatarUrl    sql.NullString
+	FriendsSince time.Time
+}
+
+func (q *Queries) GetFriendsList(ctx context.Context, arg GetFriendsListParams) ([]GetFriendsListRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFriendsList, arg.UserID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFriendsListRow
+	for rows.Next() {
+		var i GetFriendsListRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.AvatarUrl,
+			&i.FriendsSince,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMutualFriends = `-- name: GetMutualFriends :many
+WITH friends AS (
+    SELECT f1.initiator_id AS user_id, f1.target_id AS friend_id
+    FROM follows f1
+    JOIN follows f2
+        ON f2.initiator_id = f1.target_id
+        AND f2.target_id = f1.initiator_id
+        AND f2.status = 'accepted'
+    WHERE f1.status = 'accepted'
+        AND f1.initiator_id IN ($1, $2)
+)
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url
+FROM friends vf
+JOIN friends tf ON tf.friend_id = vf.friend_id
+JOIN users u ON u.id = vf.friend_id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE vf.user_id = $1
+AND tf.user_id = $2
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+ORDER BY u.username
+`
+
+type GetMutualFriendsParams struct {
+	ViewerID uuid.UUID
+	TargetID uuid.UUID
+}
+
+type GetMutualFriendsRow struct {
+	ID        uuid.UUID
+	Username  string
+	AvatarUrl sql.NullString
+}
+
+func (q *Queries) GetMutualFriends(ctx context.Context, arg GetMutualFriendsParams) ([]GetMutualFriendsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getMutualFriends, arg.ViewerID, arg.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMutualFriendsRow
+	for rows.Next() {
+		var i GetMutualFriendsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.AvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRelationship = `-- name: GetRelationship :one
+SELECT
+    (
+      SELECT f.status
+      FROM follows f
+      WHERE f.initiator_id = $1
+      AND f.target_id = $2
+    ) AS following_status,
+    (
+      SELECT f.status
+      FROM follows f
+      WHERE f.initiator_id = $2
+      AND f.target_id = $1
+    ) AS followed_by_status,
+    EXISTS (
+      SELECT 1 FROM blocks b
+      WHERE b.blocker_id = $1
+      AND b.blocked_id = $2
+    ) AS blocking
+`
+
+type GetRelationshipParams struct {
+	ViewerID uuid.UUID
+	TargetID uuid.UUID
+}
+
+type GetRelationshipRow struct {
+	FollowingStatus  sql.NullString
+	FollowedByStatus sql.NullString
+	Blocking         bool
+}
+
+func (q *Queries) GetRelationship(ctx context.Context, arg GetRelationshipParams) (GetRelationshipRow, error) {
+	row := q.db.QueryRowContext(ctx, getRelationship, arg.ViewerID, arg.TargetID)
+	var i GetRelationshipRow
+	err := row.Scan(
+		&i.FollowingStatus,
+		&i.FollowedByStatus,
+		&i.Blocking,
+	)
+	return i, err
+}
+
 const initiateFollowRequest = `-- name: InitiateFollowRequest :one
 INSERT INTO follows (initiator_id, target_id, status)
-VALUES ($1, $2, $3)
+SELECT $1::uuid, $2::uuid, $3::text
+WHERE NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $1::uuid AND b.blocked_id = $2::uuid)
+       OR (b.blocker_id = $2::uuid AND b.blocked_id = $1::uuid)
+)
 RETURNING initiator_id, target_id, status, created_at, updated_at
 `
 
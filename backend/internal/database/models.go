@@ -11,6 +11,12 @@ import (
 	"github.com/google/uuid"
 )
 
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Follow struct {
 	InitiatorID uuid.UUID
 	TargetID    uuid.UUID
@@ -19,6 +25,61 @@ type Follow struct {
 	UpdatedAt   time.Time
 }
 
+type Identity struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Provider    string
+	Subject     string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+type LoginAttempt struct {
+	ID            uuid.UUID
+	UserID        uuid.NullUUID
+	Username      string
+	Succeeded     bool
+	FailureReason string
+	IpAddress     string
+	UserAgent     string
+	NewDevice     bool
+	CreatedAt     time.Time
+}
+
+type LoginLockout struct {
+	UserID      uuid.UUID
+	FailedCount int32
+	LockedUntil sql.NullTime
+	UpdatedAt   time.Time
+}
+
+type ModerationAction struct {
+	ID           uuid.UUID
+	ModeratorID  uuid.NullUUID
+	ReportID     uuid.NullUUID
+	Action       string
+	TargetUserID uuid.NullUUID
+	TargetPostID uuid.NullUUID
+	Note         string
+	CreatedAt    time.Time
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type OidcLogin struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	UserID       uuid.NullUUID
+}
+
 type Post struct {
 	ID         uuid.UUID
 	Body       string
@@ -29,6 +90,30 @@ type Post struct {
 	Visibility string
 }
 
+type ProfileLink struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Url        string
+	Position   int32
+	VerifiedAt sql.NullTime
+	CreatedAt  time.Time
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	Allowed   bool
+	UpdatedAt time.Time
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
 type RefreshToken struct {
 	Token     string
 	UserID    uuid.UUID
@@ -38,15 +123,57 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
-type User struct {
+type Report struct {
 	ID             uuid.UUID
-	Username       string
-	Email          string
-	HashedPassword string
+	ReporterID     uuid.UUID
+	ReportedUserID uuid.UUID
+	PostID         uuid.NullUUID
+	Reason         string
+	Details        string
+	Status         string
+	ClaimedBy      uuid.NullUUID
+	ClaimedAt      sql.NullTime
+	ResolvedBy     uuid.NullUUID
+	ResolvedAt     sql.NullTime
+	Resolution     sql.NullString
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 }
 
+type SigningKey struct {
+	Kid        string
+	Algorithm  string
+	PrivateKey []byte
+	NotBefore  time.Time
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+}
+
+type TotpSecret struct {
+	UserID       uuid.UUID
+	Secret       string
+	ConfirmedAt  sql.NullTime
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+type User struct {
+	ID               uuid.UUID
+	Username         string
+	Email            string
+	HashedPassword   string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Role             string
+	SuspendedAt      sql.NullTime
+	SuspendedUntil   sql.NullTime
+	SuspensionReason string
+	AppealNote       string
+	DisplayName      string
+	Bio              string
+	Location         string
+}
+
 type UserPreference struct {
 	ID          uuid.UUID
 	AvatarUrl   string
@@ -57,3 +184,35 @@ type UserPreference struct {
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
+
+type UsernameHistory struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	OldUsername string
+	ChangedAt   time.Time
+	ReleasedAt  time.Time
+}
+
+type WebauthnCredential struct {
+	ID              uuid.UUID
+	UserID          uuid.UUID
+	CredentialID    []byte
+	PublicKey       []byte
+	AttestationType string
+	Aaguid          []byte
+	SignCount       int64
+	Transports      string
+	BackupEligible  bool
+	BackupState     bool
+	Name            string
+	CreatedAt       time.Time
+	LastUsedAt      sql.NullTime
+}
+
+type WebauthnSession struct {
+	ID        uuid.UUID
+	UserID    uuid.NullUUID
+	Ceremony  string
+	Data      string
+	ExpiresAt time.Time
+}
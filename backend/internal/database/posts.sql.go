@@ -35,31 +35,77 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
 	return err
 }
 
+const getPostById = `-- name: GetPostById :one
+SELECT posts.id, posts.body, posts.created_at, posts.updated_at, posts.user_id, posts.is_deleted, posts.visibility FROM posts
+JOIN users u ON posts.user_id = u.id
+WHERE posts.id = $1
+AND posts.is_deleted = false
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = posts.user_id)
+       OR (b.blocker_id = posts.user_id AND b.blocked_id = $2)
+)
+`
+
+type GetPostByIdParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetPostById(ctx context.Context, arg GetPostByIdParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostById, arg.ID, arg.ViewerID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.IsDeleted,
+		&i.Visibility,
+	)
+	return i, err
+}
+
 const getPosts = `-- name: GetPosts :many
 SELECT 
     posts.id, posts.body, posts.created_at, posts.updated_at, posts.user_id, posts.is_deleted, posts.visibility,
     u.username AS username,
+    u.display_name AS display_name,
     up.avatar_url AS avatar_url
 FROM posts
 JOIN users u ON posts.user_id = u.id
 LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE posts.is_deleted = false
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $1 AND b.blocked_id = posts.user_id)
+       OR (b.blocker_id = posts.user_id AND b.blocked_id = $1)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes m
+    WHERE m.muter_id = $1 AND m.muted_id = posts.user_id
+)
 ORDER BY posts.created_at DESC
 `
 
 type GetPostsRow struct {
-	ID         uuid.UUID
-	Body       string
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
-	UserID     uuid.UUID
-	IsDeleted  bool
-	Visibility string
-	Username   string
-	AvatarUrl  sql.NullString
+	ID          uuid.UUID
+	Body        string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	IsDeleted   bool
+	Visibility  string
+	Username    string
+	DisplayName string
+	AvatarUrl   sql.NullString
 }
 
-func (q *Queries) GetPosts(ctx context.Context) ([]GetPostsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPosts)
+func (q *Queries) GetPosts(ctx context.Context, viewerID uuid.UUID) ([]GetPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPosts, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +122,7 @@ func (q *Queries) GetPosts(ctx context.Context) ([]GetPostsRow, error) {
 			&i.IsDeleted,
 			&i.Visibility,
 			&i.Username,
+			&i.DisplayName,
 			&i.AvatarUrl,
 		); err != nil {
 			return nil, err
@@ -95,14 +142,27 @@ const getPostsByUsername = `-- name: GetPostsByUsername :many
 SELECT 
     posts.id, posts.body, posts.created_at, posts.updated_at, posts.user_id, posts.is_deleted, posts.visibility,
     u.username,
+    u.display_name,
     up.avatar_url as user_avatar_url
 FROM posts 
 JOIN users u ON posts.user_id = u.id
 LEFT JOIN user_preferences up ON up.user_id = u.id
 WHERE u.username = $1
+AND posts.is_deleted = false
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = posts.user_id)
+       OR (b.blocker_id = posts.user_id AND b.blocked_id = $2)
+)
 ORDER BY posts.created_at DESC
 `
 
+type GetPostsByUsernameParams struct {
+	Username string
+	ViewerID uuid.UUID
+}
+
 type GetPostsByUsernameRow struct {
 	ID            uuid.UUID
 	Body          string
@@ -112,11 +172,12 @@ type GetPostsByUsernameRow struct {
 	IsDeleted     bool
 	Visibility    string
 	Username      string
+	DisplayName   string
 	UserAvatarUrl sql.NullString
 }
 
-func (q *Queries) GetPostsByUsername(ctx context.Context, username string) ([]GetPostsByUsernameRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsByUsername, username)
+func (q *Queries) GetPostsByUsername(ctx context.Context, arg GetPostsByUsernameParams) ([]GetPostsByUsernameRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByUsername, arg.Username, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -133,6 +194,7 @@ func (q *Queries) GetPostsByUsername(ctx context.Context, username string) ([]Ge
 			&i.IsDeleted,
 			&i.Visibility,
 			&i.Username,
+			&i.DisplayName,
 			&i.UserAvatarUrl,
 		); err != nil {
 			return nil, err
@@ -148,6 +210,18 @@ func (q *Queries) GetPostsByUsername(ctx context.Context, username string) ([]Ge
 	return items, nil
 }
 
+const hidePost = `-- name: HidePost :exec
+UPDATE posts
+SET is_deleted = true,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) HidePost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, hidePost, id)
+	return err
+}
+
 const resetPostsTable = `-- name: ResetPostsTable :exec
 DELETE FROM posts
 `
@@ -156,3 +230,94 @@ func (q *Queries) ResetPostsTable(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetPostsTable)
 	return err
 }
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+    posts.id, posts.body, posts.created_at, posts.updated_at, posts.user_id, posts.is_deleted, posts.visibility,
+    u.username,
+    u.display_name,
+    up.avatar_url,
+    ts_rank(to_tsvector('english', posts.body), websearch_to_tsquery('english', $1)) AS rank,
+    ts_headline('english', posts.body, websearch_to_tsquery('english', $1),
+        'StartSel=**, StopSel=**, MaxFragments=2') AS highlight
+FROM posts
+JOIN users u ON posts.user_id = u.id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE to_tsvector('english', posts.body) @@ websearch_to_tsquery('english', $1)
+AND posts.is_deleted = false
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND (
+    posts.visibility = 'public'
+    OR posts.user_id = $2
+    OR (posts.visibility = 'friends' AND EXISTS (
+        SELECT 1 FROM follows f
+        WHERE f.initiator_id = $2
+            AND f.target_id = posts.user_id
+            AND f.status = 'accepted'
+    ))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = posts.user_id)
+       OR (b.blocker_id = posts.user_id AND b.blocked_id = $2)
+)
+ORDER BY rank DESC, posts.created_at DESC
+LIMIT $3
+`
+
+type SearchPostsParams struct {
+	Query       string
+	ViewerID    uuid.UUID
+	ResultLimit int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Body        string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	IsDeleted   bool
+	Visibility  string
+	Username    string
+	DisplayName string
+	AvatarUrl   sql.NullString
+	Rank        float32
+	Highlight   string
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts, arg.Query, arg.ViewerID, arg.ResultLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.IsDeleted,
+			&i.Visibility,
+			&i.Username,
+			&i.DisplayName,
+			&i.AvatarUrl,
+			&i.Rank,
+			&i.Highlight,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -66,7 +67,7 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshTok
 }
 
 const getUserByRefreshToken = `-- name: GetUserByRefreshToken :one
-SELECT u.id, u.username, u.email, u.updated_at, u.created_at
+SELECT u.id, u.username, u.email, u.updated_at, u.created_at, u.role, u.suspended_at, u.suspended_until
 FROM users u
 JOIN refresh_tokens rt ON u.id = rt.user_id
 WHERE rt.token = $1
@@ -75,11 +76,14 @@ AND rt.expires_at > NOW()
 `
 
 type GetUserByRefreshTokenRow struct {
-	ID        uuid.UUID
-	Username  string
-	Email     string
-	UpdatedAt time.Time
-	CreatedAt time.Time
+	ID             uuid.UUID
+	Username       string
+	Email          string
+	UpdatedAt      time.Time
+	CreatedAt      time.Time
+	Role           string
+	SuspendedAt    sql.NullTime
+	SuspendedUntil sql.NullTime
 }
 
 func (q *Queries) GetUserByRefreshToken(ctx context.Context, token string) (GetUserByRefreshTokenRow, error) {
@@ -91,6 +95,9 @@ func (q *Queries) GetUserByRefreshToken(ctx context.Context, token string) (GetU
 		&i.Email,
 		&i.UpdatedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
 	)
 	return i, err
 }
@@ -115,3 +122,16 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
 	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
 	return err
 }
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
@@ -16,7 +16,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, username, email, hashed_password, created_at, updated_at)
 VALUES (gen_random_uuid(), $1, $2, $3, $4, $5)
-RETURNING id, username, email, hashed_password, created_at, updated_at
+RETURNING id, username, email, hashed_password, created_at, updated_at, role, suspended_at, suspended_until, suspension_reason, appeal_note, display_name, bio, location
 `
 
 type CreateUserParams struct {
@@ -43,6 +43,14 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.HashedPassword,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.AppealNote,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
 	)
 	return i, err
 }
@@ -57,7 +65,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, hashed_password, created_at, updated_at FROM users WHERE email = $1
+SELECT id, username, email, hashed_password, created_at, updated_at, role, suspended_at, suspended_until, suspension_reason, appeal_note, display_name, bio, location FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -70,6 +78,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.HashedPassword,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.AppealNote,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
 	)
 	return i, err
 }
@@ -82,6 +98,13 @@ SELECT
     u.hashed_password,
     u.created_at as user_created_at,
     u.updated_at as user_updated_at,
+    u.role,
+    u.suspended_at,
+    u.suspended_until,
+    u.suspension_reason,
+    u.display_name,
+    u.bio,
+    u.location,
     up.id as preferences_id,
     up.avatar_url,
     up.cover_url,
@@ -106,8 +129,18 @@ FROM
 LEFT JOIN
     user_preferences up ON up.user_id = u.id
 WHERE u.id = $1
+  AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = $2)
+  )
 `
 
+type GetUserByIdParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
 type GetUserByIdRow struct {
 	UserID               uuid.UUID
 	Username             string
@@ -115,6 +148,13 @@ type GetUserByIdRow struct {
 	HashedPassword       string
 	UserCreatedAt        time.Time
 	UserUpdatedAt        time.Time
+	Role                 string
+	SuspendedAt          sql.NullTime
+	SuspendedUntil       sql.NullTime
+	SuspensionReason     string
+	DisplayName          string
+	Bio                  string
+	Location             string
 	PreferencesID        uuid.NullUUID
 	AvatarUrl            sql.NullString
 	CoverUrl             sql.NullString
@@ -126,8 +166,8 @@ type GetUserByIdRow struct {
 	FollowerCount        int64
 }
 
-func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (GetUserByIdRow, error) {
-	row := q.db.QueryRowContext(ctx, getUserById, id)
+func (q *Queries) GetUserById(ctx context.Context, arg GetUserByIdParams) (GetUserByIdRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserById, arg.ID, arg.ViewerID)
 	var i GetUserByIdRow
 	err := row.Scan(
 		&i.UserID,
@@ -136,6 +176,13 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (GetUserByIdRow
 		&i.HashedPassword,
 		&i.UserCreatedAt,
 		&i.UserUpdatedAt,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
 		&i.PreferencesID,
 		&i.AvatarUrl,
 		&i.CoverUrl,
@@ -157,6 +204,13 @@ SELECT
     u.hashed_password,
     u.created_at as user_created_at,
     u.updated_at as user_updated_at,
+    u.role,
+    u.suspended_at,
+    u.suspended_until,
+    u.suspension_reason,
+    u.display_name,
+    u.bio,
+    u.location,
     up.id as preferences_id,
     up.avatar_url,
     up.cover_url,
@@ -181,8 +235,18 @@ FROM
 LEFT JOIN
     user_preferences up ON up.user_id = u.id
 WHERE u.username = $1
+  AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = $2)
+  )
 `
 
+type GetUserByUsernameParams struct {
+	Username string
+	ViewerID uuid.NullUUID
+}
+
 type GetUserByUsernameRow struct {
 	UserID               uuid.UUID
 	Username             string
@@ -190,6 +254,13 @@ type GetUserByUsernameRow struct {
 	HashedPassword       string
 	UserCreatedAt        time.Time
 	UserUpdatedAt        time.Time
+	Role                 string
+	SuspendedAt          sql.NullTime
+	SuspendedUntil       sql.NullTime
+	SuspensionReason     string
+	DisplayName          string
+	Bio                  string
+	Location             string
 	PreferencesID        uuid.NullUUID
 	AvatarUrl            sql.NullString
 	CoverUrl             sql.NullString
@@ -201,8 +272,8 @@ type GetUserByUsernameRow struct {
 	FollowerCount        int64
 }
 
-func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUserByUsernameRow, error) {
-	row := q.db.QueryRowContext(ctx, getUserByUsername, username)
+func (q *Queries) GetUserByUsername(ctx context.Context, arg GetUserByUsernameParams) (GetUserByUsernameRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserByUsername, arg.Username, arg.ViewerID)
 	var i GetUserByUsernameRow
 	err := row.Scan(
 		&i.UserID,
@@ -211,6 +282,13 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUs
 		&i.HashedPassword,
 		&i.UserCreatedAt,
 		&i.UserUpdatedAt,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
 		&i.PreferencesID,
 		&i.AvatarUrl,
 		&i.CoverUrl,
@@ -232,3 +310,131 @@ func (q *Queries) ResetUsersTable(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetUsersTable)
 	return err
 }
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url
+FROM users u
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE lower(u.username) LIKE lower($1) || '%'
+  AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+  AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = $2)
+  )
+ORDER BY length(u.username), u.username
+LIMIT $3
+`
+
+type SearchUsersParams struct {
+	Prefix      string
+	ViewerID    uuid.UUID
+	ResultLimit int32
+}
+
+type SearchUsersRow struct {
+	ID        uuid.UUID
+	Username  string
+	AvatarUrl sql.NullString
+}
+
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchUsers, arg.Prefix, arg.ViewerID, arg.ResultLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUsersRow
+	for rows.Next() {
+		var i SearchUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.AvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET display_name = $2,
+    bio = $3,
+    location = $4,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING display_name, bio, location, updated_at
+`
+
+type UpdateUserProfileParams struct {
+	ID          uuid.UUID
+	DisplayName string
+	Bio         string
+	Location    string
+}
+
+type UpdateUserProfileRow struct {
+	DisplayName string
+	Bio         string
+	Location    string
+	UpdatedAt   time.Time
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.DisplayName,
+		arg.Bio,
+		arg.Location,
+	)
+	var i UpdateUserProfileRow
+	err := row.Scan(
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateUsername = `-- name: UpdateUsername :one
+UPDATE users
+SET username = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, username, updated_at
+`
+
+type UpdateUsernameParams struct {
+	ID       uuid.UUID
+	Username string
+}
+
+type UpdateUsernameRow struct {
+	ID        uuid.UUID
+	Username  string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateUsername(ctx context.Context, arg UpdateUsernameParams) (UpdateUsernameRow, error) {
+	row := q.db.QueryRowContext(ctx, updateUsername, arg.ID, arg.Username)
+	var i UpdateUsernameRow
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,170 @@
+// Package linkcheck verifies profile links by fetching the linked page and
+// looking for a rel="me" link back to the user's Tanglr profile, the same
+// convention Mastodon and IndieWeb sites use.
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxBodyBytes caps how much of a linked page is read while looking for the
+// backlink.
+const maxBodyBytes = 1 << 20
+
+var ErrBlockedAddress = errors.New("linkcheck: refusing to connect to a non-public address")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598). net.IP has no
+// helper for it and it is routable inside some cloud networks.
+var sharedAddressSpace = &net.IPNet{
+	IP:   net.IPv4(100, 64, 0, 0),
+	Mask: net.CIDRMask(10, 32),
+}
+
+// HTTPClient is the part of *http.Client the verifier needs, so tests can
+// serve pages without touching the network.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type Verifier struct {
+	client HTTPClient
+}
+
+func NewVerifier(client HTTPClient) *Verifier {
+	return &Verifier{client: client}
+}
+
+// NewHTTPClient returns a client suitable for fetching user-supplied URLs. It
+// only dials public addresses so links can't be used to probe the internal
+// network, and it gives up after timeout.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("linkcheck: too many redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// dialControl runs after DNS resolution, so it sees the address actually being
+// dialled and a hostname can't be pointed at an internal one.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// Verify fetches pageURL and reports whether it contains an <a> or <link>
+// element with rel="me" whose href points at profileURL.
+func (v *Verifier) Verify(ctx context.Context, pageURL, profileURL string) (bool, error) {
+	want, err := url.Parse(profileURL)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("linkcheck: %s returned %s", pageURL, resp.Status)
+	}
+
+	base := req.URL
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+
+	tokenizer := html.NewTokenizer(io.LimitReader(resp.Body, maxBodyBytes))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return false, err
+			}
+			return false, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "a" && token.Data != "link" {
+				continue
+			}
+			if isMeLink(token, base, want) {
+				return true, nil
+			}
+		}
+	}
+}
+
+func isMeLink(token html.Token, base, want *url.URL) bool {
+	var rel, href string
+	for _, attr := range token.Attr {
+		switch strings.ToLower(attr.Key) {
+		case "rel":
+			rel = attr.Val
+		case "href":
+			href = attr.Val
+		}
+	}
+
+	isMe := false
+	for _, value := range strings.Fields(rel) {
+		if strings.EqualFold(value, "me") {
+			isMe = true
+			break
+		}
+	}
+	if !isMe || href == "" {
+		return false
+	}
+
+	got, err := base.Parse(href)
+	if err != nil {
+		return false
+	}
+	return sameURL(got, want)
+}
+
+// sameURL compares host and path, ignoring scheme, case in the host and a
+// trailing slash.
+func sameURL(a, b *url.URL) bool {
+	return strings.EqualFold(a.Host, b.Host) &&
+		strings.TrimSuffix(a.Path, "/") == strings.TrimSuffix(b.Path, "/")
+}
@@ -0,0 +1,122 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const profileURL = "https://tanglr.example/alice"
+
+func TestVerify(t *testing.T) {
+	mux := http.NewServeMux()
+	page := func(path, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		})
+	}
+	page("/anchor", `<html><body><a rel="me" href="https://tanglr.example/alice">me</a></body></html>`)
+	page("/link", `<html><head><link rel="ME author" href="https://TANGLR.example/alice/"></head></html>`)
+	page("/no-rel", `<a href="https://tanglr.example/alice">alice</a>`)
+	page("/other-user", `<a rel="me" href="https://tanglr.example/bob">bob</a>`)
+	page("/oversized", "<p>"+strings.Repeat("x", maxBodyBytes)+`</p><a rel="me" href="https://tanglr.example/alice">me</a>`)
+	page("/moved/page", `<a rel="me" href="/alice">me</a>`)
+	mux.Handle("/redirect", http.RedirectHandler("/moved/page", http.StatusFound))
+	mux.HandleFunc("/missing", http.NotFound)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		profile string
+		want    bool
+		wantErr bool
+	}{
+		{name: "rel=me anchor", path: "/anchor", want: true},
+		{name: "rel=me link element", path: "/link", want: true},
+		{name: "no rel=me", path: "/no-rel"},
+		{name: "backlink to someone else", path: "/other-user"},
+		{name: "backlink past the read limit", path: "/oversized"},
+		// Relative hrefs resolve against the page the redirect ended on.
+		{name: "redirect", path: "/redirect", profile: server.URL + "/alice", want: true},
+		{name: "not found", path: "/missing", wantErr: true},
+	}
+
+	verifier := NewVerifier(server.Client())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := tt.profile
+			if profile == "" {
+				profile = profileURL
+			}
+			got, err := verifier.Verify(context.Background(), server.URL+tt.path, profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := server.Client()
+	client.Timeout = 50 * time.Millisecond
+	_, err := NewVerifier(client).Verify(context.Background(), server.URL, profileURL)
+	if err == nil {
+		t.Fatal("Verify() of a page that never answers returned no error")
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	blocked := []string{
+		"127.0.0.1:80",
+		"[::1]:443",
+		"10.1.2.3:80",
+		"172.16.0.1:80",
+		"192.168.1.1:80",
+		"169.254.169.254:80",
+		"[fe80::1]:80",
+		"[fd00::1]:80",
+		"100.64.0.1:80",
+		"100.127.255.254:80",
+		"0.0.0.0:80",
+		"224.0.0.1:80",
+	}
+	for _, address := range blocked {
+		if err := dialControl("tcp", address, nil); !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("dialControl(%s) = %v, want ErrBlockedAddress", address, err)
+		}
+	}
+
+	for _, address := range []string{"93.184.216.34:443", "100.128.0.1:80", "[2606:4700::1111]:443"} {
+		if err := dialControl("tcp", address, nil); err != nil {
+			t.Errorf("dialControl(%s) = %v, want nil", address, err)
+		}
+	}
+}
+
+func TestHTTPClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the guarded client reached a loopback server")
+	}))
+	defer server.Close()
+
+	_, err := NewVerifier(NewHTTPClient(time.Second)).Verify(context.Background(), server.URL, profileURL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Verify() error = %v, want ErrBlockedAddress", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Package logging sets up JSON logging with log/slog, carries a per-request
+// logger in the request context and keeps secrets out of the output.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are attribute key fragments whose values are never logged.
+var secretKeys = []string{"password", "token", "secret", "authorization", "cookie", "api_key"}
+
+var (
+	bearerPattern  = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+	urlUserPattern = regexp.MustCompile(`(://[^:/@\s]+:)[^@\s]+@`)
+)
+
+// New returns a JSON logger writing to w at the given level, with secrets
+// redacted.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}))
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scrub removes bearer tokens and URL passwords from free-form text such as
+// error messages.
+func Scrub(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "${1}"+redacted)
+	return urlUserPattern.ReplaceAllString(s, "${1}"+redacted+"@")
+}
+
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	if isSecretKey(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Scrub(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, Scrub(err.Error()))
+		}
+	}
+	return attr
+}
+
+type ctxKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the request's logger, or the default logger outside a
+// request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits which incoming request IDs are trusted, so clients
+// can't inject arbitrary text into the logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the ID assigned by Middleware.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// responseRecorder captures what the handler sent so it can be logged.
+type responseRecorder struct {
+	http.ResponseWriter
+	status   int
+	bytes    int
+	errorMsg string
+	err      error
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// RecordError is called by helpers.RespondWithError so the failure ends up on
+// the request's log line.
+func (rec *responseRecorder) RecordError(msg string, err error) {
+	rec.errorMsg = msg
+	rec.err = err
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Middleware assigns every request an ID, echoes it in X-Request-ID, stores a
+// request-scoped logger in the context and logs one line per request with the
+// route, status and latency. userID, when not nil, returns the authenticated
+// user for the log line or "" for anonymous requests.
+func Middleware(userID func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID.MatchString(requestID) {
+				requestID = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			logger := slog.Default().With(
+				"request_id", requestID,
+				"method", r.Method,
+				"path", r.URL.Path,
+			)
+			if userID != nil {
+				if id := userID(r); id != "" {
+					logger = logger.With("user_id", id)
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+			ctx = WithLogger(ctx, logger)
+			r = r.WithContext(ctx)
+
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+
+			attrs := []any{
+				"status", rec.status,
+				"bytes", rec.bytes,
+				"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				attrs = append(attrs, "route", rctx.RoutePattern())
+			}
+			if rec.errorMsg != "" {
+				attrs = append(attrs, "error_message", rec.errorMsg)
+			}
+			if rec.err != nil {
+				attrs = append(attrs, "error", rec.err)
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case rec.status >= 500:
+				level = slog.LevelError
+			case rec.status >= 400:
+				level = slog.LevelWarn
+			}
+			logger.Log(r.Context(), level, "request completed", attrs...)
+		})
+	}
+}
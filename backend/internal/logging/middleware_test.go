@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+// captureLogs points the default logger, which Middleware builds on, at a
+// buffer for the rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(New(&buf, slog.LevelDebug))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// logLines decodes every JSON log line written so far.
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var line map[string]any
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	captureLogs(t)
+	var seen string
+	handler := Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "none", incoming: ""},
+		{name: "valid", incoming: "lb-1234.abc_def", keep: true},
+		{name: "log injection", incoming: "abc\" level=ERROR"},
+		{name: "too long", incoming: string(bytes.Repeat([]byte("a"), 65))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				r.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			got := rec.Header().Get(RequestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("X-Request-ID = %q, context has %q", got, seen)
+			}
+			if (got == tt.incoming) != tt.keep {
+				t.Errorf("X-Request-ID = %q for incoming %q, keep = %v", got, tt.incoming, tt.keep)
+			}
+		})
+	}
+}
+
+func TestMiddlewareLogLine(t *testing.T) {
+	buf := captureLogs(t)
+
+	router := chi.NewRouter()
+	router.Use(Middleware(func(r *http.Request) string { return "user-42" }))
+	router.Get("/users/{username}", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("inside handler")
+		w.(interface{ RecordError(string, error) }).RecordError("Couldn't get user", errors.New("connection reset"))
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/users/alice", nil)
+	r.Header.Set("Authorization", "Bearer secret-token")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	lines := logLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2: %v", len(lines), lines)
+	}
+	inner, done := lines[0], lines[1]
+	if inner["request_id"] == nil || inner["request_id"] != done["request_id"] || inner["user_id"] != "user-42" {
+		t.Errorf("handler log line = %v, want the request's ID and user", inner)
+	}
+
+	want := map[string]any{
+		"level":         "ERROR",
+		"route":         "/users/{username}",
+		"status":        float64(http.StatusInternalServerError),
+		"error_message": "Couldn't get user",
+		"error":         "connection reset",
+	}
+	for key, value := range want {
+		if done[key] != value {
+			t.Errorf("%s = %v, want %v", key, done[key], value)
+		}
+	}
+	if _, ok := done["latency_ms"]; !ok {
+		t.Errorf("request log line has no latency: %v", done)
+	}
+}
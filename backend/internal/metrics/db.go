@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/database"
+)
+
+// instrumentedDB times every statement sent through it. It satisfies
+// database.DBTX so it can sit between the sqlc Queries and a *sql.DB or
+// *sql.Tx.
+type instrumentedDB struct {
+	db database.DBTX
+}
+
+// WrapDBTX returns db with per-query latency recorded.
+func WrapDBTX(db database.DBTX) database.DBTX {
+	return instrumentedDB{db: db}
+}
+
+// queryName extracts the name from the "-- name: GetUser :one" header sqlc
+// puts at the top of every generated query. Hand-written SQL is grouped
+// under "other".
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(query), "-- name: ")
+	if !ok {
+		return "other"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
+
+func observe(query string, start time.Time, err error) {
+	name := queryName(query)
+	dbQueryDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		dbQueryErrors.WithLabelValues(name).Inc()
+	}
+}
+
+func (i instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := i.db.ExecContext(ctx, query, args...)
+	observe(query, start, err)
+	return res, err
+}
+
+func (i instrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return i.db.PrepareContext(ctx, query)
+}
+
+func (i instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := i.db.QueryContext(ctx, query, args...)
+	observe(query, start, err)
+	return rows, err
+}
+
+// QueryRowContext can't see the error until the row is scanned, so only the
+// latency of the round trip is recorded.
+func (i instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := i.db.QueryRowContext(ctx, query, args...)
+	observe(query, start, row.Err())
+	return row
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/artyultra/tanglr/internal/database"
+)
+
+// fakeDB answers Exec and Query with a fixed error.
+type fakeDB struct {
+	database.DBTX
+	err error
+}
+
+func (f *fakeDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, f.err
+}
+
+func (f *fakeDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, f.err
+}
+
+func TestQueryName(t *testing.T) {
+	tests := map[string]string{
+		"-- name: GetUserById :one\nSELECT 1":          "GetUserById",
+		"\n  -- name: DeletePost :exec\nDELETE FROM x": "DeletePost",
+		"SELECT version_id FROM goose_db_version":      "other",
+	}
+	for query, want := range tests {
+		if got := queryName(query); got != want {
+			t.Errorf("queryName(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestInstrumentedDB(t *testing.T) {
+	ctx := context.Background()
+
+	db := WrapDBTX(&fakeDB{})
+	db.ExecContext(ctx, "-- name: MetricsTestOK :exec\nUPDATE x")
+
+	// No rows is an answer, not a failure.
+	db = WrapDBTX(&fakeDB{err: sql.ErrNoRows})
+	db.QueryContext(ctx, "-- name: MetricsTestNoRows :many\nSELECT x")
+
+	db = WrapDBTX(&fakeDB{err: errors.New("connection reset")})
+	db.ExecContext(ctx, "-- name: MetricsTestFailed :exec\nUPDATE x")
+	db.QueryContext(ctx, "-- name: MetricsTestFailed :many\nSELECT x")
+
+	output := scrape(t)
+	expectSeries(t, output, `tanglr_db_query_duration_seconds_count{query="MetricsTestOK"} 1`)
+	expectSeries(t, output, `tanglr_db_query_duration_seconds_count{query="MetricsTestFailed"} 2`)
+	expectSeries(t, output, `tanglr_db_query_errors_total{query="MetricsTestFailed"} 2`)
+	for _, query := range []string{"MetricsTestOK", "MetricsTestNoRows"} {
+		if series := `tanglr_db_query_errors_total{query="` + query + `"}`; containsSeries(output, series) {
+			t.Errorf("/metrics counted an error for %s", query)
+		}
+	}
+}
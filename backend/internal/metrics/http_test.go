@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+func TestMiddlewareLabelsRoutePattern(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/metrics-test/{username}", func(w http.ResponseWriter, r *http.Request) {})
+	router.Post("/metrics-test/{username}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/metrics-test/alice"},
+		{http.MethodGet, "/metrics-test/bob"},
+		{http.MethodPost, "/metrics-test/alice"},
+		{http.MethodGet, "/no-such-route"},
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	output := scrape(t)
+	// One series per route pattern, however many users are looked up.
+	expectSeries(t, output, `tanglr_http_requests_total{method="GET",route="/metrics-test/{username}",status="200"} 2`)
+	expectSeries(t, output, `tanglr_http_requests_total{method="POST",route="/metrics-test/{username}",status="201"} 1`)
+	expectSeries(t, output, `tanglr_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	expectSeries(t, output, `tanglr_http_request_duration_seconds_count{method="POST",route="/metrics-test/{username}",status="201"} 1`)
+}
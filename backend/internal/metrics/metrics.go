@@ -0,0 +1,130 @@
+// Package metrics holds the Prometheus collectors exposed on /metrics: HTTP
+// traffic, database pool and query latency, and authentication events.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tanglr"
+
+// Registry holds every tanglr collector. A dedicated registry keeps metrics
+// registered by libraries on the default one out of the output.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route pattern, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Database query latency by sqlc query name.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"query"})
+
+	dbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Database queries that returned an error other than no rows.",
+	}, []string{"query"})
+
+	replicaHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "replica_healthy",
+		Help:      "Whether a read replica passed its last health check (1) or not (0).",
+	}, []string{"replica"})
+
+	// RateLimited counts requests refused by the rate limiter, by route
+	// and by the bucket that ran out: "ip", "user" or "username".
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Requests refused by the rate limiter.",
+	}, []string{"route", "key"})
+
+	// Logins counts login attempts by result: "success",
+	// "invalid_credentials", "locked", "suspended", "mfa_pending" (password
+	// right, second factor still to come), "invalid_mfa",
+	// "invalid_passkey" or "invalid_oidc".
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "logins_total",
+		Help:      "Login attempts by result.",
+	}, []string{"result"})
+
+	// Refreshes counts refresh-token exchanges by result: "success",
+	// "invalid" or "suspended".
+	Refreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "token_refreshes_total",
+		Help:      "Refresh-token exchanges by result.",
+	}, []string{"result"})
+
+	// Revocations counts refresh-token revocations by reason: "logout" or
+	// "suspension".
+	Revocations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "token_revocations_total",
+		Help:      "Refresh-token revocations by reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dbQueryDuration,
+		dbQueryErrors,
+		replicaHealthy,
+		RateLimited,
+		Logins,
+		Refreshes,
+		Revocations,
+	)
+}
+
+// RegisterDB exposes the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// SetReplicaHealthy records the result of a replica health check.
+func SetReplicaHealthy(replica string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	replicaHealthy.WithLabelValues(replica).Set(value)
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
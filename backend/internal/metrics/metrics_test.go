@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scrape returns the /metrics output.
+func scrape(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics status = %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+// containsSeries reports whether the scrape has a line starting with series.
+func containsSeries(output, series string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, series) {
+			return true
+		}
+	}
+	return false
+}
+
+func expectSeries(t *testing.T, output, series string) {
+	t.Helper()
+	if !containsSeries(output, series) {
+		t.Errorf("/metrics has no %s", series)
+	}
+}
+
+func TestAuthCountersExposed(t *testing.T) {
+	Logins.WithLabelValues("invalid_credentials").Inc()
+	Refreshes.WithLabelValues("success").Inc()
+	Revocations.WithLabelValues("logout").Inc()
+	SetReplicaHealthy("replica-1", false)
+
+	output := scrape(t)
+	expectSeries(t, output, `tanglr_auth_logins_total{result="invalid_credentials"} `)
+	expectSeries(t, output, `tanglr_auth_token_refreshes_total{result="success"} `)
+	expectSeries(t, output, `tanglr_auth_token_revocations_total{reason="logout"} `)
+	expectSeries(t, output, `tanglr_db_replica_healthy{replica="replica-1"} 0`)
+	expectSeries(t, output, `go_goroutines `)
+}
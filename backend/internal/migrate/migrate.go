@@ -0,0 +1,113 @@
+// Package migrate applies the goose migrations embedded in the sql/schema
+// package, so neither the server nor operators need the goose CLI.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/store"
+	"github.com/artyultra/tanglr/sql/schema"
+	"github.com/artyultra/tanglr/sql/schema/sqlite"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// ErrSchemaBehind is returned by Check when migrations embedded in the
+// binary have not been applied.
+var ErrSchemaBehind = errors.New("database schema is behind; run `tanglr migrate up` or set DB_AUTO_MIGRATE=true")
+
+// Migrator runs the embedded migrations against one database. On Postgres
+// every operation that changes the schema holds an advisory lock, so several
+// instances starting at once apply each migration exactly once.
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// New returns a Migrator for db using the migrations written for dialect.
+func New(db *sql.DB, dialect store.Dialect) (*Migrator, error) {
+	var provider *goose.Provider
+	var err error
+	switch dialect {
+	case store.SQLite:
+		provider, err = goose.NewProvider(goose.DialectSQLite3, db, sqlite.FS)
+	default:
+		var locker lock.SessionLocker
+		locker, err = lock.NewPostgresSessionLocker()
+		if err != nil {
+			return nil, err
+		}
+		provider, err = goose.NewProvider(goose.DialectPostgres, db, schema.FS,
+			goose.WithSessionLocker(locker),
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	return m.provider.Up(ctx)
+}
+
+// Down rolls back the most recent migration.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	return m.provider.Down(ctx)
+}
+
+// Redo rolls back the most recent migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.provider.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := m.provider.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// Versions returns the applied schema version and the latest version
+// embedded in the binary.
+func (m *Migrator) Versions(ctx context.Context) (current, target int64, err error) {
+	return m.provider.GetVersions(ctx)
+}
+
+// Check returns ErrSchemaBehind when any embedded migration is pending.
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.provider.HasPending(ctx)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return ErrSchemaBehind
+	}
+	return nil
+}
+
+// WriteStatus prints one line per migration with when it was applied.
+func (m *Migrator) WriteStatus(ctx context.Context, w io.Writer) error {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tMIGRATION\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.State == goose.StateApplied {
+			appliedAt = status.AppliedAt.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", status.Source.Version, status.Source.Path, appliedAt)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,153 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/artyultra/tanglr/internal/store"
+	"github.com/artyultra/tanglr/sql/schema"
+	"github.com/artyultra/tanglr/sql/schema/sqlite"
+)
+
+func newTestMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	target, err := store.ParseURL("sqlite:" + filepath.Join(t.TempDir(), "tanglr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(target.Driver, target.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := New(db, target.Dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrator
+}
+
+func TestUpAndCheck(t *testing.T) {
+	ctx := context.Background()
+	migrator := newTestMigrator(t)
+
+	if err := migrator.Check(ctx); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("Check() on an empty database = %v, want ErrSchemaBehind", err)
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatalf("Check() after Up = %v", err)
+	}
+
+	current, target, err := migrator.Versions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := schema.LatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != latest || target != latest {
+		t.Errorf("Versions() = %d, %d, want %d", current, target, latest)
+	}
+
+	results, err := migrator.Up(ctx)
+	if err != nil || len(results) != 0 {
+		t.Errorf("second Up() = %v, %v, want nothing to apply", results, err)
+	}
+}
+
+func TestDownAndRedo(t *testing.T) {
+	ctx := context.Background()
+	migrator := newTestMigrator(t)
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_, latest, err := migrator.Versions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := migrator.Redo(ctx)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("Redo() = %v, %v", results, err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Errorf("Check() after Redo = %v", err)
+	}
+
+	down, err := migrator.Down(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if down.Source.Version != latest {
+		t.Errorf("Down() rolled back %d, want %d", down.Source.Version, latest)
+	}
+	if err := migrator.Check(ctx); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("Check() after Down = %v, want ErrSchemaBehind", err)
+	}
+
+	var status bytes.Buffer
+	if err := migrator.WriteStatus(ctx, &status); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(status.String()), "\n")
+	if last := lines[len(lines)-1]; !strings.HasSuffix(last, "pending") {
+		t.Errorf("last status line = %q, want the rolled back migration pending", last)
+	}
+}
+
+// Every down migration has to work, or `tanglr migrate down` strands the
+// database part way.
+func TestDownToZeroAndBack(t *testing.T) {
+	ctx := context.Background()
+	migrator := newTestMigrator(t)
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		current, _, err := migrator.Versions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current == 0 {
+			break
+		}
+		if _, err := migrator.Down(ctx); err != nil {
+			t.Fatalf("rolling back %d: %v", current, err)
+		}
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() after rolling everything back: %v", err)
+	}
+}
+
+// The SQLite migrations are twins of the Postgres ones and must keep the
+// same names so schema versions mean the same thing on both.
+func TestDialectsHaveTheSameMigrations(t *testing.T) {
+	names := func(fsys fs.FS) []string {
+		matches, err := fs.Glob(fsys, "*.sql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return matches
+	}
+
+	postgres, sqliteNames := names(schema.FS), names(sqlite.FS)
+	if !slices.Equal(postgres, sqliteNames) {
+		t.Errorf("migrations differ:\npostgres: %v\nsqlite:   %v", postgres, sqliteNames)
+	}
+}
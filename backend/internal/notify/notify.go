@@ -0,0 +1,103 @@
+// Package notify delivers security notifications to users, such as a login
+// from a device they haven't used before. The server doesn't send email
+// itself: notifications are logged, and optionally posted to a webhook that
+// forwards them to whatever mail or push service is in use.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of notification.
+const (
+	KindNewLogin = "new_login"
+)
+
+type Notification struct {
+	Kind      string    `json:"kind"`
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	// NewIP and NewDevice say which part of a login hadn't been seen before.
+	NewIP     bool      `json:"new_ip,omitempty"`
+	NewDevice bool      `json:"new_device,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// New returns a Notifier that logs every notification and, when webhookURL
+// is set, also posts it there.
+func New(webhookURL, webhookSecret string) Notifier {
+	if webhookURL == "" {
+		return LogNotifier{}
+	}
+	return &Webhook{
+		URL:    webhookURL,
+		Secret: webhookSecret,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// LogNotifier only logs notifications.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	slog.InfoContext(ctx, "notification", "kind", n.Kind, "user_id", n.UserID, "ip_address", n.IPAddress, "new_ip", n.NewIP, "new_device", n.NewDevice)
+	return nil
+}
+
+// Webhook posts each notification as JSON to URL. When Secret is set the body
+// is signed with HMAC-SHA256 and the hex digest sent as
+// "X-Tanglr-Signature: sha256=<digest>".
+type Webhook struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+func (wh *Webhook) Notify(ctx context.Context, n Notification) error {
+	LogNotifier{}.Notify(ctx, n)
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if wh.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(wh.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Tanglr-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := wh.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notification webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/internal/store"
+)
+
+// DatabaseStore keeps buckets in the rate_limit_buckets table so every
+// instance sharing the database draws from the same buckets. Each Take is a
+// single upsert, so concurrent requests can't overdraw a bucket.
+type DatabaseStore struct {
+	db store.Repository
+}
+
+func NewDatabaseStore(db store.Repository) *DatabaseStore {
+	return &DatabaseStore{db: db}
+}
+
+func (s *DatabaseStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	bucket, err := s.db.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Key:           key,
+		Burst:         float64(limit.Requests),
+		RatePerSecond: limit.ratePerSecond(),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return result(bucket.Tokens, bucket.Allowed, limit), nil
+}
+
+func (s *DatabaseStore) Purge(ctx context.Context, idle time.Duration) error {
+	return s.db.DeleteIdleRateLimitBuckets(ctx, time.Now().Add(-idle))
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process memory. Each instance counts on its
+// own, so behind a load balancer the effective limit is multiplied by the
+// number of instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	tokens, allowed := take(refill(b.tokens, now.Sub(b.updatedAt), limit))
+	b.tokens = tokens
+	b.updatedAt = now
+
+	return result(tokens, allowed, limit), nil
+}
+
+func (s *MemoryStore) Purge(_ context.Context, idle time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := s.now().Add(-idle)
+	for key, b := range s.buckets {
+		if b.updatedAt.Before(cutoff) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
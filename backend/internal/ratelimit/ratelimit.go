@@ -0,0 +1,79 @@
+// Package ratelimit implements token-bucket rate limiting. Each bucket holds
+// up to Limit.Requests tokens and refills at Requests per Limit.Per; every
+// request takes one token and is refused when none is left.
+//
+// Buckets live in a Store: MemoryStore for a single instance, DatabaseStore
+// to share them between instances through the database.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests requests per Per, in bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ratePerSecond is how many tokens the bucket gains each second.
+func (l Limit) ratePerSecond() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the state of a bucket after a Take.
+type Result struct {
+	Allowed bool
+	Limit   Limit
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// RetryAfter is how long until the next token, when the request was
+	// refused.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps buckets by key.
+type Store interface {
+	// Take refills the bucket at key for the time since it was last used,
+	// then takes a token from it if there is one.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Purge forgets buckets unused for longer than idle. A bucket idle for
+	// its limit's Per is full, so idle should be the longest Per in use.
+	Purge(ctx context.Context, idle time.Duration) error
+}
+
+// refill returns the tokens in a bucket that held tokens elapsed ago.
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	return math.Min(float64(limit.Requests), tokens+elapsed.Seconds()*limit.ratePerSecond())
+}
+
+// take removes a token from a bucket holding tokens, if it has a whole one.
+func take(tokens float64) (float64, bool) {
+	if tokens >= 1 {
+		return tokens - 1, true
+	}
+	return tokens, false
+}
+
+// result describes a bucket left with tokens after a Take.
+func result(tokens float64, allowed bool, limit Limit) Result {
+	rate := limit.ratePerSecond()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(s, 0) * float64(time.Second))
+}
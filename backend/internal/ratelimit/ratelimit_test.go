@@ -0,0 +1,200 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/store"
+	"github.com/artyultra/tanglr/sql/schema/sqlite"
+	"github.com/pressly/goose/v3"
+)
+
+// threePerThreeSeconds refills one token a second.
+var threePerThreeSeconds = Limit{Requests: 3, Per: 3 * time.Second}
+
+// fakeClock is a MemoryStore clock the test moves by hand.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newMemoryStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	s := NewMemoryStore()
+	s.now = clock.Now
+	return s, clock
+}
+
+func newDatabaseStore(t *testing.T) *DatabaseStore {
+	t.Helper()
+	target, err := store.ParseURL("sqlite:" + filepath.Join(t.TempDir(), "tanglr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(target.Driver, target.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// As in production, SQLite gets a single connection.
+	db.SetMaxOpenConns(1)
+
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, sqlite.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return NewDatabaseStore(store.New(target.Dialect, db))
+}
+
+func takeToken(t *testing.T, s Store, key string, limit Limit) Result {
+	t.Helper()
+	res, err := s.Take(context.Background(), key, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestResult(t *testing.T) {
+	limit := Limit{Requests: 10, Per: 10 * time.Second}
+
+	got := result(4.5, true, limit)
+	if got.Remaining != 4 || got.RetryAfter != 0 || got.Reset != 5500*time.Millisecond {
+		t.Errorf("result(4.5, allowed) = %+v", got)
+	}
+
+	got = result(0.25, false, limit)
+	if got.Remaining != 0 || got.RetryAfter != 750*time.Millisecond || got.Reset != 9750*time.Millisecond {
+		t.Errorf("result(0.25, refused) = %+v", got)
+	}
+}
+
+func TestMemoryStoreBucket(t *testing.T) {
+	s, clock := newMemoryStore()
+
+	for i, wantRemaining := range []int{2, 1, 0} {
+		res := takeToken(t, s, "ip:1.2.3.4", threePerThreeSeconds)
+		if !res.Allowed || res.Remaining != wantRemaining {
+			t.Fatalf("request %d = %+v, want allowed with %d left", i+1, res, wantRemaining)
+		}
+	}
+
+	res := takeToken(t, s, "ip:1.2.3.4", threePerThreeSeconds)
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Fatalf("request over the burst = %+v, want refused for 1s", res)
+	}
+
+	// Other keys have their own buckets.
+	if res := takeToken(t, s, "ip:5.6.7.8", threePerThreeSeconds); !res.Allowed {
+		t.Error("a different key was refused")
+	}
+
+	// Half a token isn't enough; a whole one is.
+	clock.Advance(500 * time.Millisecond)
+	if res := takeToken(t, s, "ip:1.2.3.4", threePerThreeSeconds); res.Allowed {
+		t.Errorf("request after 0.5s = %+v, want refused", res)
+	}
+	clock.Advance(500 * time.Millisecond)
+	if res := takeToken(t, s, "ip:1.2.3.4", threePerThreeSeconds); !res.Allowed {
+		t.Errorf("request after 1s = %+v, want allowed", res)
+	}
+
+	// A long pause refills the bucket only up to the burst.
+	clock.Advance(time.Hour)
+	if res := takeToken(t, s, "ip:1.2.3.4", threePerThreeSeconds); res.Remaining != 2 {
+		t.Errorf("request after an hour = %+v, want 2 left", res)
+	}
+}
+
+func TestMemoryStorePurge(t *testing.T) {
+	s, clock := newMemoryStore()
+	takeToken(t, s, "old", threePerThreeSeconds)
+	clock.Advance(time.Minute)
+	takeToken(t, s, "recent", threePerThreeSeconds)
+
+	if err := s.Purge(context.Background(), 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.buckets["old"]; ok {
+		t.Error("Purge kept an idle bucket")
+	}
+	if _, ok := s.buckets["recent"]; !ok {
+		t.Error("Purge dropped a recently used bucket")
+	}
+}
+
+func TestDatabaseStoreBucket(t *testing.T) {
+	s := newDatabaseStore(t)
+	limit := Limit{Requests: 3, Per: 300 * time.Millisecond}
+
+	for i := range 3 {
+		if res := takeToken(t, s, "login:username:alice", limit); !res.Allowed {
+			t.Fatalf("request %d = %+v, want allowed", i+1, res)
+		}
+	}
+	res := takeToken(t, s, "login:username:alice", limit)
+	if res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > 100*time.Millisecond {
+		t.Fatalf("request over the burst = %+v, want refused for up to 100ms", res)
+	}
+	if res := takeToken(t, s, "login:username:bob", limit); !res.Allowed {
+		t.Error("a different key was refused")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if res := takeToken(t, s, "login:username:alice", limit); !res.Allowed {
+		t.Errorf("request after refilling = %+v, want allowed", res)
+	}
+
+	if err := s.Purge(context.Background(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if res := takeToken(t, s, "login:username:alice", limit); res.Remaining > 1 {
+		t.Errorf("Purge dropped a bucket in use: %+v", res)
+	}
+}
+
+// Every store must hand out exactly the burst under concurrent requests.
+func TestStoresDontOverdraw(t *testing.T) {
+	stores := map[string]Store{
+		"memory":   NewMemoryStore(),
+		"database": newDatabaseStore(t),
+	}
+	limit := Limit{Requests: 5, Per: time.Hour}
+
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			allowed := 0
+			for range 20 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					res, err := s.Take(context.Background(), "shared", limit)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if res.Allowed {
+						mu.Lock()
+						allowed++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			if allowed != limit.Requests {
+				t.Errorf("%d requests allowed, want %d", allowed, limit.Requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Package sso signs users in with external OpenID Connect providers. Each
+// provider is found through OIDC discovery on its issuer URL, and logins use
+// the authorization code flow with PKCE and a nonce.
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Config describes one provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the browser back to, with the
+	// code and state, once the user has signed in.
+	RedirectURL string
+	Scopes      []string
+}
+
+// Identity is what a provider says about the user who signed in.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Name          string
+}
+
+// Login is the state of one login between sending the browser to the
+// provider and finishing with the code it comes back with.
+type Login struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewLogin starts a login with a fresh state, nonce and PKCE verifier.
+func NewLogin() Login {
+	return Login{
+		State:    rand.Text(),
+		Nonce:    rand.Text(),
+		Verifier: oauth2.GenerateVerifier(),
+	}
+}
+
+// ErrInvalidLogin means the provider's answer couldn't be verified.
+var ErrInvalidLogin = errors.New("sso: invalid login")
+
+// Provider is an OpenID Connect provider. Discovery happens on first use and
+// is retried until it succeeds, so a provider that is down doesn't stop the
+// server from starting.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func New(cfg Config, client *http.Client) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+func (p *Provider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider != nil {
+		return p.provider, nil
+	}
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, p.client), p.cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", p.cfg.Issuer, err)
+	}
+	p.provider = provider
+	return provider, nil
+}
+
+func (p *Provider) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.cfg.Scopes,
+	}
+}
+
+// AuthCodeURL returns the provider URL to send the browser to. The login
+// must be kept until it is finished with Exchange.
+func (p *Provider) AuthCodeURL(ctx context.Context, login Login) (string, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2Config(provider).AuthCodeURL(login.State, oidc.Nonce(login.Nonce), oauth2.S256ChallengeOption(login.Verifier)), nil
+}
+
+// Exchange trades the code from the redirect for tokens and returns the
+// identity in the verified ID token. An answer that doesn't check out is
+// reported as ErrInvalidLogin; other errors mean the provider couldn't be
+// reached.
+func (p *Provider) Exchange(ctx context.Context, code string, login Login) (Identity, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	token, err := p.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			return Identity{}, fmt.Errorf("%w: %v", ErrInvalidLogin, err)
+		}
+		return Identity{}, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, fmt.Errorf("%w: no id_token in token response", ErrInvalidLogin)
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID}).Verify(oidc.ClientContext(ctx, p.client), rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidLogin, err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return Identity{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidLogin)
+	}
+
+	var claims struct {
+		Email             string          `json:"email"`
+		EmailVerified     json.RawMessage `json:"email_verified"`
+		PreferredUsername string          `json:"preferred_username"`
+		Name              string          `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidLogin, err)
+	}
+
+	return Identity{
+		Subject:  idToken.Subject,
+		Email:    claims.Email,
+		Username: claims.PreferredUsername,
+		Name:     claims.Name,
+		// Some providers send "true" as a string.
+		EmailVerified: string(claims.EmailVerified) == "true" || string(claims.EmailVerified) == `"true"`,
+	}, nil
+}
@@ -0,0 +1,14 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/artyultra/tanglr/internal/metrics"
+	_ "github.com/lib/pq"
+)
+
+// NewPostgres returns a Repository that sends the sqlc queries to Postgres
+// as generated.
+func NewPostgres(db *sql.DB) Repository {
+	return newRepository(db, metrics.WrapDBTX)
+}
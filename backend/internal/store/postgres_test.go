@@ -0,0 +1,177 @@
+//go:build postgres
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/sql/schema"
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+)
+
+// These tests run the Postgres versions of the queries that SQLite overrides
+// in sqlite_queries.sql, so the SQLite tests never reach them. Run them with
+//
+//	TEST_POSTGRES_URL=postgres://... go test -tags postgres ./internal/store
+//
+// against an empty, throwaway database. Every migration is applied and then
+// rolled back, so the Down migrations are exercised too.
+
+// newMigratedPostgres opens TEST_POSTGRES_URL with the schema applied.
+func newMigratedPostgres(t *testing.T) Repository {
+	t.Helper()
+	rawURL := os.Getenv("TEST_POSTGRES_URL")
+	if rawURL == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+	target, err := ParseURL(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.Dialect != Postgres {
+		t.Fatalf("TEST_POSTGRES_URL is a %s URL", target.Dialect)
+	}
+	db, err := sql.Open(target.Driver, target.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, schema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := provider.DownTo(context.Background(), 0); err != nil {
+			t.Errorf("rolling back migrations: %v", err)
+		}
+	})
+	return NewPostgres(db)
+}
+
+func createPostgresUser(t *testing.T, db Repository, username string) uuid.UUID {
+	t.Helper()
+	user, err := db.CreateUser(context.Background(), database.CreateUserParams{
+		Username:       username,
+		Email:          username + "@example.com",
+		HashedPassword: "unset",
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+func followAccepted(t *testing.T, db Repository, initiator, target uuid.UUID) {
+	t.Helper()
+	_, err := db.InitiateFollowRequest(context.Background(), database.InitiateFollowRequestParams{
+		InitiatorID: initiator,
+		TargetID:    target,
+		Status:      "accepted",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresQueries(t *testing.T) {
+	ctx := context.Background()
+	db := newMigratedPostgres(t)
+
+	viewer := createPostgresUser(t, db, "viewer")
+	alice := createPostgresUser(t, db, "alice")
+	carol := createPostgresUser(t, db, "carol")
+	followAccepted(t, db, viewer, alice)
+	followAccepted(t, db, alice, viewer)
+	followAccepted(t, db, alice, carol)
+
+	for _, body := range []string{"Gardening in the rain", "Nothing to see"} {
+		err := db.CreatePost(ctx, database.CreatePostParams{
+			Body:      body,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    carol,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("SearchPosts", func(t *testing.T) {
+		posts, err := db.SearchPosts(ctx, database.SearchPostsParams{
+			Query:       "garden",
+			ViewerID:    viewer,
+			ResultLimit: 10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(posts) != 1 || posts[0].Body != "Gardening in the rain" || posts[0].Highlight == "" {
+			t.Errorf("SearchPosts = %+v, want the gardening post with a highlight", posts)
+		}
+	})
+
+	t.Run("SearchUsers", func(t *testing.T) {
+		users, err := db.SearchUsers(ctx, database.SearchUsersParams{
+			Prefix:      "AL",
+			ViewerID:    viewer,
+			ResultLimit: 10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 1 || users[0].ID != alice {
+			t.Errorf("SearchUsers = %+v, want alice", users)
+		}
+	})
+
+	t.Run("GetFollowSuggestions", func(t *testing.T) {
+		suggestions, err := db.GetFollowSuggestions(ctx, database.GetFollowSuggestionsParams{
+			ViewerID:    viewer,
+			ResultLimit: 10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(suggestions) != 1 || suggestions[0].ID != carol || suggestions[0].MutualCount != 1 || !suggestions[0].LastPostedAt.Valid {
+			t.Errorf("GetFollowSuggestions = %+v, want carol with one mutual", suggestions)
+		}
+	})
+
+	t.Run("GetFriendsList", func(t *testing.T) {
+		friends, err := db.GetFriendsList(ctx, database.GetFriendsListParams{
+			UserID:   viewer,
+			ViewerID: viewer,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(friends) != 1 || friends[0].ID != alice || friends[0].FriendsSince.IsZero() {
+			t.Errorf("GetFriendsList = %+v, want alice", friends)
+		}
+	})
+
+	t.Run("TakeRateLimitToken", func(t *testing.T) {
+		params := database.TakeRateLimitTokenParams{Key: "login:ip:192.0.2.1", Burst: 2, RatePerSecond: 0.001}
+		for i, wantAllowed := range []bool{true, true, false} {
+			got, err := db.TakeRateLimitToken(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Allowed != wantAllowed {
+				t.Errorf("take %d: allowed = %v, want %v (tokens %v)", i+1, got.Allowed, wantAllowed, got.Tokens)
+			}
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/metrics"
+)
+
+// ReplicaSet spreads read-only queries over read replicas, skipping any that
+// failed their last health check.
+type ReplicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	repo    Repository
+	healthy atomic.Bool
+}
+
+// NewReplicaSet wraps already opened replica pools. Every replica starts out
+// in rotation; call CheckHealth before serving to take out any that are down.
+func NewReplicaSet(dialect Dialect, dbs []*sql.DB) *ReplicaSet {
+	set := &ReplicaSet{}
+	for i, db := range dbs {
+		r := &replica{
+			name: fmt.Sprintf("replica-%d", i+1),
+			db:   db,
+			repo: New(dialect, db),
+		}
+		r.healthy.Store(true)
+		set.replicas = append(set.replicas, r)
+	}
+	return set
+}
+
+// Reader returns the next healthy replica in round-robin order, or false when
+// none is healthy and the caller should use the primary.
+func (s *ReplicaSet) Reader() (Repository, bool) {
+	n := uint64(len(s.replicas))
+	for range n {
+		r := s.replicas[s.next.Add(1)%n]
+		if r.healthy.Load() {
+			return r.repo, true
+		}
+	}
+	return nil, false
+}
+
+// Healthy returns how many replicas passed their last health check and how
+// many there are.
+func (s *ReplicaSet) Healthy() (healthy, total int) {
+	for _, r := range s.replicas {
+		if r.healthy.Load() {
+			healthy++
+		}
+	}
+	return healthy, len(s.replicas)
+}
+
+// CheckHealth pings every replica and takes those that don't answer out of
+// rotation until they do.
+func (s *ReplicaSet) CheckHealth(ctx context.Context) {
+	for _, r := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+
+		healthy := err == nil
+		if was := r.healthy.Swap(healthy); was != healthy {
+			if healthy {
+				slog.Info("read replica is healthy", "replica", r.name)
+			} else {
+				slog.Warn("read replica is unhealthy, reads fall back to other replicas or the primary", "replica", r.name, "error", err)
+			}
+		}
+		metrics.SetReplicaHealthy(r.name, healthy)
+	}
+}
+
+// Close closes every replica pool.
+func (s *ReplicaSet) Close() error {
+	var errs []error
+	for _, r := range s.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
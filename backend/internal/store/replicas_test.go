@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/database"
+)
+
+// switchDriver connects only while its DSN is marked up, standing in for a
+// replica that goes down and comes back.
+type switchDriver struct {
+	mu sync.Mutex
+	up map[string]bool
+}
+
+var replicaSwitch = &switchDriver{up: map[string]bool{}}
+
+func init() {
+	sql.Register("switch", replicaSwitch)
+}
+
+func (d *switchDriver) set(dsn string, up bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.up[dsn] = up
+}
+
+func (d *switchDriver) isUp(dsn string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.up[dsn]
+}
+
+func (d *switchDriver) Open(dsn string) (driver.Conn, error) {
+	if !d.isUp(dsn) {
+		return nil, errors.New("connection refused")
+	}
+	return switchConn{driver: d, dsn: dsn}, nil
+}
+
+type switchConn struct {
+	driver *switchDriver
+	dsn    string
+}
+
+func (c switchConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c switchConn) Close() error                        { return nil }
+func (c switchConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+// Ping fails on pooled connections too once the replica is down.
+func (c switchConn) Ping(context.Context) error {
+	if !c.driver.isUp(c.dsn) {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// newNamedReplica returns a migrated SQLite database holding a single user
+// called name, so reads show which replica served them.
+func newNamedReplica(t *testing.T, name string) *sql.DB {
+	t.Helper()
+	db := newMigratedSQLite(t)
+	_, err := New(SQLite, db).CreateUser(context.Background(), database.CreateUserParams{
+		Username:       name,
+		Email:          name + "@example.com",
+		HashedPassword: "unset",
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// servedBy returns the name of the replica the next read goes to, or "" when
+// the caller is sent to the primary.
+func servedBy(t *testing.T, set *ReplicaSet) string {
+	t.Helper()
+	repo, ok := set.Reader()
+	if !ok {
+		return ""
+	}
+	users, err := repo.ListUsersByRole(context.Background(), "user")
+	if err != nil || len(users) != 1 {
+		t.Fatalf("ListUsersByRole() = %v, %v", users, err)
+	}
+	return users[0].Username
+}
+
+func TestReplicaSetRoundRobin(t *testing.T) {
+	one, two := newNamedReplica(t, "one"), newNamedReplica(t, "two")
+	set := NewReplicaSet(SQLite, []*sql.DB{one, two})
+
+	seen := map[string]int{}
+	for range 4 {
+		seen[servedBy(t, set)]++
+	}
+	if seen["one"] != 2 || seen["two"] != 2 {
+		t.Errorf("reads = %v, want them spread evenly", seen)
+	}
+
+	one.Close()
+	set.CheckHealth(context.Background())
+	for range 3 {
+		if got := servedBy(t, set); got != "two" {
+			t.Fatalf("read went to %q with replica one down", got)
+		}
+	}
+
+	two.Close()
+	set.CheckHealth(context.Background())
+	if got := servedBy(t, set); got != "" {
+		t.Errorf("read went to %q with every replica down, want the primary", got)
+	}
+}
+
+func TestReplicaSetRecovers(t *testing.T) {
+	dsn := t.Name()
+	replicaSwitch.set(dsn, true)
+	db, err := sql.Open("switch", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	set := NewReplicaSet(SQLite, []*sql.DB{db})
+	ctx := context.Background()
+
+	steps := []struct {
+		up          bool
+		wantHealthy int
+	}{
+		{up: true, wantHealthy: 1},
+		{up: false, wantHealthy: 0},
+		{up: true, wantHealthy: 1},
+	}
+	for _, step := range steps {
+		replicaSwitch.set(dsn, step.up)
+		set.CheckHealth(ctx)
+		if healthy, _ := set.Healthy(); healthy != step.wantHealthy {
+			t.Fatalf("replica up = %v: Healthy() = %d, want %d", step.up, healthy, step.wantHealthy)
+		}
+		if _, ok := set.Reader(); ok != step.up {
+			t.Errorf("replica up = %v: Reader() ok = %v", step.up, ok)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/internal/metrics"
+	_ "github.com/mattn/go-sqlite3"
+	_ "github.com/tursodatabase/libsql-client-go/libsql"
+)
+
+// NewSQLite returns a Repository that runs the sqlc queries on SQLite or
+// libSQL, translating them from the Postgres dialect they were written in.
+func NewSQLite(db *sql.DB) Repository {
+	return newRepository(db, func(db database.DBTX) database.DBTX {
+		return metrics.WrapDBTX(sqliteDB{db: db})
+	})
+}
+
+// sqliteNow renders the current time in the same fixed-width UTC form that
+// sqliteTime gives Go times, so timestamps compare correctly as text.
+const sqliteNow = `strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')`
+
+// uuidV4 builds a random version 4 UUID in SQL, standing in for
+// gen_random_uuid().
+const uuidV4 = `lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))`
+
+var (
+	placeholderPattern = regexp.MustCompile(`\$(\d+)`)
+	castPattern        = regexp.MustCompile(`(?i)::[a-z]+(\[\])?`)
+	nowPattern         = regexp.MustCompile(`(?i)\bnow\(\)`)
+	uuidPattern        = regexp.MustCompile(`(?i)\bgen_random_uuid\(\)`)
+	greatestPattern    = regexp.MustCompile(`(?i)\bgreatest\(`)
+)
+
+//go:embed sqlite_queries.sql
+var sqliteQueriesFile string
+
+// sqliteOverrides maps sqlc query names to hand-written SQLite versions.
+var sqliteOverrides = parseQueries(sqliteQueriesFile)
+
+// sqliteQueries caches translated statements by their Postgres text.
+var sqliteQueries sync.Map
+
+// parseQueries splits a file of "-- name: Name :kind" blocks into a map keyed
+// by name.
+func parseQueries(file string) map[string]string {
+	queries := map[string]string{}
+	blocks := strings.Split(file, "-- name: ")
+	for _, block := range blocks[1:] {
+		name, _, _ := strings.Cut(block, " ")
+		queries[name] = "-- name: " + strings.TrimSpace(block)
+	}
+	return queries
+}
+
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(query), "-- name: ")
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
+
+// translate rewrites a Postgres statement for SQLite: numbered placeholders
+// become ?N, casts are dropped and NOW(), gen_random_uuid() and GREATEST()
+// get SQLite equivalents. Queries that need more than that are replaced
+// wholesale by their entry in sqlite_queries.sql.
+func translate(query string) string {
+	if cached, ok := sqliteQueries.Load(query); ok {
+		return cached.(string)
+	}
+
+	translated := query
+	if override, ok := sqliteOverrides[queryName(query)]; ok {
+		translated = override
+	}
+	translated = placeholderPattern.ReplaceAllString(translated, "?$1")
+	translated = castPattern.ReplaceAllString(translated, "")
+	translated = nowPattern.ReplaceAllString(translated, sqliteNow)
+	translated = uuidPattern.ReplaceAllString(translated, uuidV4)
+	translated = greatestPattern.ReplaceAllString(translated, "max(")
+
+	sqliteQueries.Store(query, translated)
+	return translated
+}
+
+// sqliteTime formats t the way sqliteNow does. Both drivers parse it back
+// into a time.Time for TIMESTAMP columns.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000") + "+00:00"
+}
+
+func translateArgs(args []interface{}) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			out[i] = sqliteTime(v)
+		case sql.NullTime:
+			if v.Valid {
+				out[i] = sqliteTime(v.Time)
+			}
+		default:
+			out[i] = arg
+		}
+	}
+	return out
+}
+
+// sqliteDB translates every statement before handing it to the underlying
+// connection or transaction.
+type sqliteDB struct {
+	db database.DBTX
+}
+
+func (s sqliteDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.db.ExecContext(ctx, translate(query), translateArgs(args)...)
+}
+
+func (s sqliteDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return s.db.PrepareContext(ctx, translate(query))
+}
+
+func (s sqliteDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, translate(query), translateArgs(args)...)
+}
+
+func (s sqliteDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRowContext(ctx, translate(query), translateArgs(args)...)
+}
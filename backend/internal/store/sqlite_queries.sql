@@ -0,0 +1,165 @@
+-- SQLite versions of the queries that can't be translated mechanically.
+-- They are written like the Postgres originals: $N placeholders and NOW()
+-- are still rewritten, but each query must take the same parameters in the
+-- same order as the generated code.
+
+-- name: GetFollowSuggestions :many
+WITH my_following AS (
+    SELECT target_id
+    FROM follows
+    WHERE initiator_id = $1
+        AND status = 'accepted'
+),
+candidates AS (
+    SELECT f.target_id AS user_id, COUNT(*) AS mutual_count
+    FROM follows f
+    JOIN my_following mf ON f.initiator_id = mf.target_id
+    WHERE f.status = 'accepted'
+    GROUP BY f.target_id
+    UNION ALL
+    SELECT DISTINCT p.user_id, 0
+    FROM posts p
+    WHERE p.created_at > strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-30 days')
+        AND p.is_deleted = false
+),
+ranked AS (
+    SELECT user_id, MAX(mutual_count) AS mutual_count
+    FROM candidates
+    GROUP BY user_id
+)
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url,
+    r.mutual_count,
+    (
+      SELECT COUNT(*)
+      FROM follows f
+      WHERE f.target_id = u.id
+      AND f.status = 'accepted'
+    ) AS follower_count,
+    -- Selecting the column of the latest post, rather than MAX(created_at),
+    -- keeps its declared type so the driver returns a time.
+    lp.created_at AS last_posted_at
+FROM ranked r
+JOIN users u ON r.user_id = u.id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+LEFT JOIN posts lp ON lp.id = (
+    SELECT p.id
+    FROM posts p
+    WHERE p.user_id = u.id
+    AND p.is_deleted = false
+    ORDER BY p.created_at DESC
+    LIMIT 1
+)
+WHERE u.id != $1
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM follows f
+    WHERE f.initiator_id = $1
+        AND f.target_id = u.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $1 AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = $1)
+)
+ORDER BY r.mutual_count DESC, last_posted_at DESC NULLS LAST, follower_count DESC
+LIMIT $2;
+
+-- name: GetFriendsList :many
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url,
+    -- Join the newer of the two follow rows instead of using MAX() so
+    -- friends_since keeps its declared type.
+    latest.updated_at AS friends_since
+FROM follows f1
+JOIN follows f2
+    ON f2.initiator_id = f1.target_id
+    AND f2.target_id = f1.initiator_id
+    AND f2.status = 'accepted'
+JOIN follows latest
+    ON latest.rowid = CASE WHEN f1.updated_at >= f2.updated_at THEN f1.rowid ELSE f2.rowid END
+JOIN users u ON u.id = f1.target_id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE f1.initiator_id = $1
+AND f1.status = 'accepted'
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = $2)
+)
+ORDER BY u.username;
+
+-- name: SearchPosts :many
+-- There is no full-text search: posts containing the query are returned
+-- newest first, all with the same rank and the whole body as the highlight.
+SELECT
+    posts.*,
+    u.username,
+    u.display_name,
+    up.avatar_url,
+    1.0 AS rank,
+    posts.body AS highlight
+FROM posts
+JOIN users u ON posts.user_id = u.id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE instr(lower(posts.body), lower($1)) > 0
+AND posts.is_deleted = false
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND (
+    posts.visibility = 'public'
+    OR posts.user_id = $2
+    OR (posts.visibility = 'friends' AND EXISTS (
+        SELECT 1 FROM follows f
+        WHERE f.initiator_id = $2
+            AND f.target_id = posts.user_id
+            AND f.status = 'accepted'
+    ))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = posts.user_id)
+       OR (b.blocker_id = posts.user_id AND b.blocked_id = $2)
+)
+ORDER BY posts.created_at DESC
+LIMIT $3;
+
+-- name: SearchUsers :many
+-- SQLite's LIKE has no default escape character.
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url
+FROM users u
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE lower(u.username) LIKE lower($1) || '%' ESCAPE '\'
+  AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+  AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $2 AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = $2)
+  )
+ORDER BY length(u.username), u.username
+LIMIT $3;
+
+-- name: TakeRateLimitToken :one
+-- No EXTRACT(EPOCH FROM ...); julianday differences are in days.
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+VALUES ($1, $2 - 1, TRUE, NOW())
+ON CONFLICT (key) DO UPDATE SET (tokens, allowed, updated_at) = (
+  SELECT
+    CASE WHEN refilled >= 1 THEN refilled - 1 ELSE refilled END,
+    refilled >= 1,
+    NOW()
+  FROM (
+    SELECT min(
+      $2,
+      rate_limit_buckets.tokens + (julianday('now') - julianday(rate_limit_buckets.updated_at)) * 86400 * $3
+    ) AS refilled
+  ) AS bucket
+)
+RETURNING tokens, allowed;
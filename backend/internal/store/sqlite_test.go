@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/sql/schema/sqlite"
+	"github.com/pressly/goose/v3"
+)
+
+// generatedQuery matches the statements sqlc writes into internal/database.
+var generatedQuery = regexp.MustCompile("(?s)const \\w+ = `(-- name: .*?)`")
+
+// newMigratedSQLite opens a SQLite file with the schema applied. It runs goose
+// itself because internal/migrate depends on this package.
+func newMigratedSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	target, err := ParseURL("sqlite:" + filepath.Join(t.TempDir(), "tanglr.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(target.Driver, target.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, sqlite.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "placeholders and casts",
+			in:   "SELECT $1::uuid, $2::text[], $10",
+			want: "SELECT ?1, ?2, ?10",
+		},
+		{
+			name: "now",
+			in:   "WHERE expires_at > NOW() AND created_at < now()",
+			want: "WHERE expires_at > " + sqliteNow + " AND created_at < " + sqliteNow,
+		},
+		{
+			name: "uuid",
+			in:   "VALUES (gen_random_uuid(), $1)",
+			want: "VALUES (" + uuidV4 + ", ?1)",
+		},
+		{
+			name: "greatest",
+			in:   "SET tokens = GREATEST(tokens - 1, 0)",
+			want: "SET tokens = max(tokens - 1, 0)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := translate(tt.in); got != tt.want {
+				t.Errorf("translate(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	override := translate("-- name: SearchUsers :many\nSELECT 'postgres only' ILIKE $1")
+	if !strings.HasPrefix(override, "-- name: SearchUsers") || strings.Contains(override, "postgres only") {
+		t.Errorf("SearchUsers wasn't replaced by its SQLite override: %s", override)
+	}
+}
+
+// Every override has to stand in for a real query, or a rename would
+// silently send the Postgres text to SQLite.
+func TestOverridesNameRealQueries(t *testing.T) {
+	querier := reflect.TypeOf((*database.Querier)(nil)).Elem()
+	for name := range sqliteOverrides {
+		if _, ok := querier.MethodByName(name); !ok {
+			t.Errorf("sqlite_queries.sql overrides %s, which isn't a query", name)
+		}
+	}
+}
+
+// TestGeneratedQueriesPrepare runs every sqlc statement through the
+// translation and has SQLite prepare it against the migrated schema, which
+// catches syntax the translation misses and columns missing from the SQLite
+// migrations.
+func TestGeneratedQueriesPrepare(t *testing.T) {
+	db := newMigratedSQLite(t)
+	conn := sqliteDB{db: db}
+
+	files, err := filepath.Glob("../database/*.sql.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range generatedQuery.FindAllStringSubmatch(string(src), -1) {
+			query := match[1]
+			count++
+			stmt, err := conn.PrepareContext(context.Background(), query)
+			if err != nil {
+				t.Errorf("%s: %v\n%s", queryName(query), err, translate(query))
+				continue
+			}
+			stmt.Close()
+		}
+	}
+	if count < 100 {
+		t.Errorf("found only %d generated queries; is the pattern still right?", count)
+	}
+}
+
+// Times are written as fixed-width UTC text so they compare correctly
+// against NOW() and against each other.
+func TestSQLiteTimes(t *testing.T) {
+	db := newMigratedSQLite(t)
+	conn := sqliteDB{db: db}
+	ctx := context.Background()
+
+	_, err := db.Exec("CREATE TABLE times (id INTEGER PRIMARY KEY, at TIMESTAMP)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour).In(time.FixedZone("UTC+5", 5*60*60))
+	future := time.Now().Add(time.Hour)
+	for i, at := range []any{past, sql.NullTime{Time: future, Valid: true}, sql.NullTime{}} {
+		if _, err := conn.ExecContext(ctx, "INSERT INTO times (id, at) VALUES ($1, $2)", i, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []int
+	rows, err := conn.QueryContext(ctx, "SELECT id FROM times WHERE at > NOW() ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("rows after NOW() = %v, want only the future one", ids)
+	}
+
+	var got time.Time
+	if err := conn.QueryRowContext(ctx, "SELECT at FROM times WHERE id = $1", 0).Scan(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(past.Truncate(time.Millisecond)) {
+		t.Errorf("read back %v, want %v", got, past)
+	}
+}
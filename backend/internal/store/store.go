@@ -0,0 +1,117 @@
+// Package store puts a Repository interface in front of the sqlc queries so
+// the server can run on Postgres or on SQLite/libSQL, chosen by the scheme of
+// DATABASE_URL.
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/artyultra/tanglr/internal/database"
+)
+
+// Repository is every query the handlers run. Both backends share the
+// sqlc-generated code; they differ only in the SQL dialect the statements
+// are sent in.
+type Repository interface {
+	database.Querier
+	// WithTx returns a Repository whose queries run inside tx.
+	WithTx(tx *sql.Tx) Repository
+}
+
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// Target describes how to open the database named by DATABASE_URL.
+type Target struct {
+	Dialect Dialect
+	// Driver and DSN are the arguments for sql.Open.
+	Driver string
+	DSN    string
+	// InMemory is set for sqlite::memory:, which lives and dies with its
+	// single connection.
+	InMemory bool
+}
+
+// ParseURL picks the backend from the scheme of rawURL:
+//
+//	postgres://, postgresql://            Postgres via lib/pq
+//	sqlite:path/to.db, sqlite::memory:    a local SQLite file or in-memory database
+//	file:path/to.db                       a local SQLite file, passed to the driver as is
+//	libsql://, https://, http://, wss://, ws://
+//	                                      a remote libSQL server such as Turso
+func ParseURL(rawURL string) (Target, error) {
+	scheme, rest, ok := strings.Cut(rawURL, ":")
+	if !ok {
+		return Target{}, fmt.Errorf("DATABASE_URL has no scheme")
+	}
+
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return Target{Dialect: Postgres, Driver: "postgres", DSN: rawURL}, nil
+	case "sqlite":
+		path := strings.TrimPrefix(rest, "//")
+		if path == ":memory:" {
+			return Target{Dialect: SQLite, Driver: "sqlite3", DSN: "file::memory:?_foreign_keys=on", InMemory: true}, nil
+		}
+		if path == "" {
+			return Target{}, fmt.Errorf("DATABASE_URL %q names no SQLite file", rawURL)
+		}
+		return Target{Dialect: SQLite, Driver: "sqlite3", DSN: sqliteDSN("file:" + path)}, nil
+	case "file":
+		return Target{Dialect: SQLite, Driver: "sqlite3", DSN: sqliteDSN(rawURL)}, nil
+	case "libsql", "https", "http", "wss", "ws":
+		return Target{Dialect: SQLite, Driver: "libsql", DSN: rawURL}, nil
+	default:
+		return Target{}, fmt.Errorf("unsupported DATABASE_URL scheme %q", scheme)
+	}
+}
+
+// sqliteDSN turns on foreign keys, WAL and a busy timeout unless the URL
+// already sets them.
+func sqliteDSN(dsn string) string {
+	path, query, _ := strings.Cut(dsn, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return dsn
+	}
+	for key, value := range map[string]string{
+		"_foreign_keys": "on",
+		"_journal_mode": "WAL",
+		"_busy_timeout": "5000",
+	} {
+		if !params.Has(key) {
+			params.Set(key, value)
+		}
+	}
+	return path + "?" + params.Encode()
+}
+
+// New returns the Repository for db in the given dialect.
+func New(dialect Dialect, db *sql.DB) Repository {
+	if dialect == SQLite {
+		return NewSQLite(db)
+	}
+	return NewPostgres(db)
+}
+
+// repository runs the generated queries on a DBTX produced by wrap, which is
+// how each backend adds its dialect translation and instrumentation.
+type repository struct {
+	*database.Queries
+	wrap func(database.DBTX) database.DBTX
+}
+
+func newRepository(db database.DBTX, wrap func(database.DBTX) database.DBTX) repository {
+	return repository{Queries: database.New(wrap(db)), wrap: wrap}
+}
+
+func (r repository) WithTx(tx *sql.Tx) Repository {
+	return newRepository(tx, r.wrap)
+}
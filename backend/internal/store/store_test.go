@@ -0,0 +1,62 @@
+package store
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		dialect  Dialect
+		driver   string
+		inMemory bool
+		wantErr  bool
+	}{
+		{url: "postgres://u:p@localhost/tanglr", dialect: Postgres, driver: "postgres"},
+		{url: "postgresql://localhost/tanglr", dialect: Postgres, driver: "postgres"},
+		{url: "sqlite:tanglr.db", dialect: SQLite, driver: "sqlite3"},
+		{url: "sqlite:///var/lib/tanglr.db", dialect: SQLite, driver: "sqlite3"},
+		{url: "sqlite::memory:", dialect: SQLite, driver: "sqlite3", inMemory: true},
+		{url: "file:tanglr.db?_busy_timeout=100", dialect: SQLite, driver: "sqlite3"},
+		{url: "libsql://tanglr.turso.io?authToken=x", dialect: SQLite, driver: "libsql"},
+		{url: "https://tanglr.turso.io", dialect: SQLite, driver: "libsql"},
+		{url: "sqlite:", wantErr: true},
+		{url: "mysql://localhost/tanglr", wantErr: true},
+		{url: "tanglr.db", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ParseURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Dialect != tt.dialect || got.Driver != tt.driver || got.InMemory != tt.inMemory {
+				t.Errorf("ParseURL() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	target, err := ParseURL("file:tanglr.db?_busy_timeout=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, query, _ := strings.Cut(target.DSN, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "file:tanglr.db" {
+		t.Errorf("path = %q", path)
+	}
+	// Settings in the URL win over the defaults.
+	want := map[string]string{"_busy_timeout": "100", "_foreign_keys": "on", "_journal_mode": "WAL"}
+	for key, value := range want {
+		if got := params.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
@@ -1,96 +1,243 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/artyultra/tanglr/handlers"
-	"github.com/artyultra/tanglr/internal/database"
+	"github.com/artyultra/tanglr/internal/auth"
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/logging"
+	"github.com/artyultra/tanglr/internal/metrics"
+	"github.com/artyultra/tanglr/internal/store"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/cors"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-	db        *database.Queries
-	dbConn    *sql.DB
-	jwtSecret string
+	db     store.Repository
+	dbConn *sql.DB
+	cfg    *config.Config
 }
 
 func main() {
-	err := godotenv.Load(".env")
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = runMigrate(os.Args[2:])
+	} else {
+		err = run()
+	}
 	if err != nil {
-		log.Printf("warning: assuming default configuration. .env unreadable: %v", err)
+		log.Fatal(err)
 	}
+}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		log.Fatal("missing PORT environment variable")
+// run serves the API until it receives SIGINT or SIGTERM, then drains
+// in-flight requests, stops the background workers and closes the database
+// pool, in that order.
+func run() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
 	}
 
-	apiCfg := apiConfig{}
+	level, _ := cfg.SlogLevel()
+	slog.SetDefault(logging.New(os.Stdout, level))
 
-	dbQueries, db, cleanup, err := setupDBConn()
+	apiCfg := apiConfig{cfg: cfg}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repo, db, target, cleanup, err := setupDBConn(ctx, cfg.Database)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer cleanup()
 
-	apiCfg.db = dbQueries
-	apiCfg.dbConn = db
+	// An in-memory database starts empty every time, so it is always
+	// migrated.
+	autoMigrate := cfg.Database.AutoMigrate || target.InMemory
+	if err := ensureSchema(ctx, db, target.Dialect, autoMigrate); err != nil {
+		return err
+	}
 
-	jwtsecret := os.Getenv("JWT_SECRET")
-	if jwtsecret == "" {
-		log.Fatal("JWT_SECRET not set")
+	replicas, closeReplicas, err := setupReplicas(ctx, cfg.Database, target)
+	if err != nil {
+		return err
 	}
+	defer closeReplicas()
 
-	apiCfg.jwtSecret = jwtsecret
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	apiCfg.db = repo
+	apiCfg.dbConn = db
 
 	router := chi.NewRouter()
 
+	handlerCfg := handlers.NewConfig(apiCfg.db, apiCfg.dbConn, replicas, apiCfg.cfg)
+	if err := handlerCfg.LoadSigningKeys(ctx); err != nil {
+		stopWorkers()
+		return err
+	}
+
+	router.Use(logging.Middleware(handlerCfg.RequestUserID))
+	if cfg.Features.Metrics {
+		router.Use(metrics.Middleware)
+	}
+	if replicas != nil {
+		router.Use(handlerCfg.TrackWrites)
+	}
+
 	router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   append([]string{logging.RequestIDHeader}, handlers.RateLimitHeaders...),
 		AllowCredentials: false,
-		MaxAge:           300,
+		MaxAge:           cfg.CORS.MaxAge,
 	}))
 
 	v1Router := chi.NewRouter()
 
-	if apiCfg.db != nil {
-		handlerCfg := handlers.NewConfig(apiCfg.db, apiCfg.dbConn, apiCfg.jwtSecret)
-
-		v1Router.Post("/login", handlerCfg.HandlerLogin)
-
-		v1Router.Post("/users", handlerCfg.HandlerCreateUser)
-		v1Router.Get("/users/{username}", handlerCfg.HandlerGetUser)
-		v1Router.Put("/users/me/avatar", handlerCfg.HandlerPutAvatarUrl)
-		// v1Router.Get("/users/{username}/friends", handlerCfg.HandlerGetNonFriends)
-		// v1Router.Get("/users/{username}/friendslist", handlerCfg.HandlerGetFriends)
-		// v1Router.Post("/users/{username}/friends/{friendUsername}", handlerCfg.HandlerAddFriend)
-
-		v1Router.Post("/posts", handlerCfg.HandlerCreatePost)
-		v1Router.Get("/posts/{username}", handlerCfg.HandlerGetAllUserPosts)
-		v1Router.Get("/posts", handlerCfg.HandlerGetAllPosts)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		handlerCfg.RunWorkers(workerCtx)
+	}()
+
+	v1Router.With(handlerCfg.RateLimit("login")).Post("/login", handlerCfg.HandlerLogin)
+	v1Router.With(handlerCfg.RateLimit("login_mfa")).Post("/login/mfa", handlerCfg.HandlerLoginMFA)
+	v1Router.With(handlerCfg.RateLimit("passkey_login")).Post("/login/passkey/begin", handlerCfg.HandlerBeginPasskeyLogin)
+	v1Router.With(handlerCfg.RateLimit("passkey_login")).Post("/login/passkey/finish", handlerCfg.HandlerFinishPasskeyLogin)
+	v1Router.Get("/login/oidc", handlerCfg.HandlerGetOIDCProviders)
+	v1Router.With(handlerCfg.RateLimit("oidc_login")).Post("/login/oidc/{provider}/begin", handlerCfg.HandlerBeginOIDCLogin)
+	v1Router.With(handlerCfg.RateLimit("oidc_login")).Post("/login/oidc/{provider}/finish", handlerCfg.HandlerFinishOIDCLogin)
+
+	v1Router.With(handlerCfg.RateLimit("create_user")).Post("/users", handlerCfg.HandlerCreateUser)
+	v1Router.Get("/users/{username}", handlerCfg.HandlerGetUser)
+	v1Router.Put("/users/me/avatar", handlerCfg.HandlerPutAvatarUrl)
+	v1Router.Patch("/users/me/username", handlerCfg.HandlerUpdateUsername)
+	v1Router.Patch("/users/me/profile", handlerCfg.HandlerUpdateProfile)
+	v1Router.Post("/users/me/mfa/totp", handlerCfg.HandlerEnrollTOTP)
+	v1Router.With(handlerCfg.RateLimit("mfa")).Post("/users/me/mfa/totp/confirm", handlerCfg.HandlerConfirmTOTP)
+	v1Router.With(handlerCfg.RateLimit("mfa")).Delete("/users/me/mfa/totp", handlerCfg.HandlerDisableTOTP)
+	v1Router.Get("/users/me/passkeys", handlerCfg.HandlerGetPasskeys)
+	v1Router.With(handlerCfg.RateLimit("passkeys")).Post("/users/me/passkeys/register/begin", handlerCfg.HandlerBeginPasskeyRegistration)
+	v1Router.With(handlerCfg.RateLimit("passkeys")).Post("/users/me/passkeys/register/finish", handlerCfg.HandlerFinishPasskeyRegistration)
+	v1Router.Delete("/users/me/passkeys/{passkeyID}", handlerCfg.HandlerDeletePasskey)
+	v1Router.Get("/users/me/identities", handlerCfg.HandlerGetIdentities)
+	v1Router.With(handlerCfg.RateLimit("oidc_link")).Post("/users/me/identities/{provider}/begin", handlerCfg.HandlerBeginLinkIdentity)
+	v1Router.With(handlerCfg.RateLimit("oidc_link")).Post("/users/me/identities/{provider}/finish", handlerCfg.HandlerFinishLinkIdentity)
+	v1Router.Post("/users/{username}/follow", handlerCfg.HandlerFollowUser)
+	v1Router.Delete("/users/{username}/follow", handlerCfg.HandlerUnfollowUser)
+	v1Router.Post("/users/me/followers/{username}/accept", handlerCfg.HandlerAcceptFollower)
+	v1Router.Delete("/users/me/followers/{username}", handlerCfg.HandlerRemoveFollower)
+	v1Router.Post("/users/{username}/block", handlerCfg.HandlerBlockUser)
+	v1Router.Delete("/users/{username}/block", handlerCfg.HandlerUnblockUser)
+	v1Router.Post("/users/{username}/mute", handlerCfg.HandlerMuteUser)
+	v1Router.Delete("/users/{username}/mute", handlerCfg.HandlerUnmuteUser)
+	v1Router.Get("/users/{username}/friends", handlerCfg.HandlerGetFriends)
+	v1Router.Get("/users/{username}/mutual-friends", handlerCfg.HandlerGetMutualFriends)
+
+	v1Router.With(handlerCfg.RateLimit("create_post")).Post("/posts", handlerCfg.HandlerCreatePost)
+	v1Router.Get("/posts/{username}", handlerCfg.HandlerGetAllUserPosts)
+	v1Router.Get("/posts", handlerCfg.HandlerGetAllPosts)
+
+	if cfg.Features.Search {
+		v1Router.Get("/search", handlerCfg.HandlerSearch)
+	}
 
-		v1Router.Post("/reset", handlerCfg.HandlerResetDatabases)
+	if cfg.Features.Suggestions {
+		v1Router.Get("/me/suggestions", handlerCfg.HandlerGetSuggestions)
+	}
 
-		v1Router.Post("/refresh-token", handlerCfg.HandlerRefreshToken)
-		v1Router.Delete("/refresh-token", handlerCfg.HandlerRevokeRefreshToken)
+	v1Router.Post("/reports", handlerCfg.HandlerCreateReport)
+	v1Router.With(handlerCfg.RateLimit("appeals")).Post("/appeals", handlerCfg.HandlerCreateAppeal)
+	v1Router.Route("/moderation", func(r chi.Router) {
+		r.Use(handlerCfg.RequireRole(auth.RoleModerator, auth.RoleAdmin))
+		r.Get("/reports", handlerCfg.HandlerGetReports)
+		r.Post("/reports/{reportID}/claim", handlerCfg.HandlerClaimReport)
+		r.Post("/reports/{reportID}/resolve", handlerCfg.HandlerResolveReport)
+		r.Get("/log", handlerCfg.HandlerGetModerationLog)
+		r.Post("/users/{username}/suspension", handlerCfg.HandlerSuspendUser)
+		r.Delete("/users/{username}/suspension", handlerCfg.HandlerUnsuspendUser)
+	})
+
+	v1Router.Route("/admin", func(r chi.Router) {
+		r.Use(handlerCfg.RequireRole(auth.RoleAdmin))
+		r.Get("/users", handlerCfg.HandlerGetUsersByRole)
+		r.Put("/users/{username}/role", handlerCfg.HandlerPutUserRole)
+	})
+
+	v1Router.With(handlerCfg.RequireRole(auth.RoleAdmin)).Post("/reset", handlerCfg.HandlerResetDatabases)
+
+	v1Router.Post("/refresh-token", handlerCfg.HandlerRefreshToken)
+	v1Router.Delete("/refresh-token", handlerCfg.HandlerRevokeRefreshToken)
+
+	// Probes, build info and the token keys live outside /v1 so they never
+	// need a token.
+	router.Get("/healthz", handlerCfg.HandlerHealthz)
+	router.Get("/readyz", handlerCfg.HandlerReadyz)
+	router.Get("/version", handlerCfg.HandlerVersion)
+	router.Get("/.well-known/jwks.json", handlerCfg.HandlerJWKS)
+	if cfg.Features.Metrics {
+		router.Handle("/metrics", metrics.Handler())
 	}
 
 	router.Mount("/v1", v1Router)
 	srv := &http.Server{
-		Addr:        ":" + port,
-		Handler:     router,
-		ReadTimeout: time.Second * 15,
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("serving", "port", cfg.Port)
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
+	case <-ctx.Done():
+		slog.Info("shutting down, draining in-flight requests")
+		stop()
+
+		// Fail /readyz first and give the load balancer time to notice
+		// before the listener closes.
+		handlerCfg.SetDraining()
+		time.Sleep(cfg.Server.DrainDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+
+		err = srv.Shutdown(shutdownCtx)
+		if err != nil {
+			err = errors.Join(errors.New("graceful shutdown timed out"), err, srv.Close())
+		}
 	}
 
-	log.Printf("Serving on port: %s\n", port)
-	log.Fatal(srv.ListenAndServe())
+	stopWorkers()
+	workers.Wait()
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("server stopped")
+	return nil
 }
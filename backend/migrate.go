@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/artyultra/tanglr/internal/config"
+	"github.com/artyultra/tanglr/internal/migrate"
+	"github.com/artyultra/tanglr/internal/store"
+)
+
+const migrateUsage = "usage: tanglr migrate up|down|status|redo"
+
+// runMigrate implements the `tanglr migrate` subcommand.
+func runMigrate(args []string) error {
+	if len(args) != 1 || !slices.Contains([]string{"up", "down", "status", "redo"}, args[0]) {
+		return errors.New(migrateUsage)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, db, target, cleanup, err := setupDBConn(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	migrator, err := migrate.New(db, target.Dialect)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := migrator.Up(ctx)
+		for _, result := range results {
+			fmt.Println(result)
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Println("no migrations to apply")
+		}
+	case "down":
+		result, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Println(result)
+	case "redo":
+		results, err := migrator.Redo(ctx)
+		for _, result := range results {
+			fmt.Println(result)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		return migrator.WriteStatus(ctx, os.Stdout)
+	}
+	return nil
+}
+
+// ensureSchema makes sure the database is at the schema version the binary
+// was built with, applying pending migrations first when auto-migrate is on.
+func ensureSchema(ctx context.Context, db *sql.DB, dialect store.Dialect, autoMigrate bool) error {
+	migrator, err := migrate.New(db, dialect)
+	if err != nil {
+		return err
+	}
+
+	if autoMigrate {
+		results, err := migrator.Up(ctx)
+		for _, result := range results {
+			slog.Info("applied migration", "version", result.Source.Version, "file", result.Source.Path, "duration_ms", result.Duration.Milliseconds())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+	}
+
+	if err := migrator.Check(ctx); err != nil {
+		return err
+	}
+
+	current, _, err := migrator.Versions(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("database schema is up to date", "version", current)
+	return nil
+}
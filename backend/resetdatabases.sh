@@ -1,5 +1,7 @@
 #!/bin/bash
 
 # This script is used to reset the database for a new deployment.
-# It is not intended to be run in production.
-curl -X POST http://localhost:8082/v1/reset
+# It is not intended to be run in production: the server only accepts it when
+# ENVIRONMENT=development, and ADMIN_TOKEN must be an access token for a user
+# with the admin role.
+curl -X POST -H "Authorization: Bearer ${ADMIN_TOKEN}" http://localhost:8082/v1/reset
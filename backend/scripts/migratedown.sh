@@ -1,8 +1,3 @@
-#!/bin/zsh
-
-if [ -f .env ]; then
-  source .env
-fi
-
-cd sql/schema
-goose postgres $DATABASE_URL down
+#!/bin/sh
+# Migrations are embedded in the server; this wraps `tanglr migrate down`.
+cd "$(dirname "$0")/.." && exec go run . migrate down
@@ -0,0 +1,19 @@
+-- name: BlockUser :exec
+INSERT INTO blocks (blocker_id, blocked_id)
+VALUES ($1, $2)
+ON CONFLICT (blocker_id, blocked_id) DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1
+    AND blocked_id = $2;
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = $1 AND b.blocked_id = $2)
+       OR (b.blocker_id = $2 AND b.blocked_id = $1)
+);
+
+-- name: ResetBlocksTable :exec
+DELETE FROM blocks;
@@ -1,6 +1,11 @@
 -- name: InitiateFollowRequest :one
 INSERT INTO follows (initiator_id, target_id, status)
-VALUES ($1, $2, $3)
+SELECT @initiator_id::uuid, @target_id::uuid, @status::text
+WHERE NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = @initiator_id::uuid AND b.blocked_id = @target_id::uuid)
+       OR (b.blocker_id = @target_id::uuid AND b.blocked_id = @initiator_id::uuid)
+)
 RETURNING *;
 
 -- name: GetFollowerList :many
@@ -50,5 +55,136 @@ WHERE initiator_id = $1
     AND target_id = $2
     AND status = 'accepted';
 
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (initiator_id = $1 AND target_id = $2)
+   OR (initiator_id = $2 AND target_id = $1);
+
+-- name: GetFollowSuggestions :many
+WITH my_following AS (
+    SELECT target_id
+    FROM follows
+    WHERE initiator_id = sqlc.arg('viewer_id')
+        AND status = 'accepted'
+),
+candidates AS (
+    SELECT f.target_id AS user_id, COUNT(*) AS mutual_count
+    FROM follows f
+    JOIN my_following mf ON f.initiator_id = mf.target_id
+    WHERE f.status = 'accepted'
+    GROUP BY f.target_id
+    UNION ALL
+    SELECT DISTINCT p.user_id, 0
+    FROM posts p
+    WHERE p.created_at > NOW() - INTERVAL '30 days'
+        AND p.is_deleted = false
+),
+ranked AS (
+    SELECT user_id, MAX(mutual_count)::bigint AS mutual_count
+    FROM candidates
+    GROUP BY user_id
+)
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url,
+    r.mutual_count,
+    (
+      SELECT COUNT(*)
+      FROM follows f
+      WHERE f.target_id = u.id
+      AND f.status = 'accepted'
+    ) AS follower_count,
+    (
+      SELECT MAX(p.created_at)
+      FROM posts p
+      WHERE p.user_id = u.id
+      AND p.is_deleted = false
+    )::timestamptz AS last_posted_at
+FROM ranked r
+JOIN users u ON r.user_id = u.id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE u.id != sqlc.arg('viewer_id')
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM follows f
+    WHERE f.initiator_id = sqlc.arg('viewer_id')
+        AND f.target_id = u.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.arg('viewer_id') AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = sqlc.arg('viewer_id'))
+)
+ORDER BY r.mutual_count DESC, last_posted_at DESC NULLS LAST, follower_count DESC
+LIMIT sqlc.arg('result_limit');
+
+-- name: GetFriendsList :many
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url,
+    GREATEST(f1.updated_at, f2.updated_at)::timestamptz AS friends_since
+FROM follows f1
+JOIN follows f2
+    ON f2.initiator_id = f1.target_id
+    AND f2.target_id = f1.initiator_id
+    AND f2.status = 'accepted'
+JOIN users u ON u.id = f1.target_id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE f1.initiator_id = sqlc.arg('user_id')
+AND f1.status = 'accepted'
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.arg('viewer_id') AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = sqlc.arg('viewer_id'))
+)
+ORDER BY u.username;
+
+-- name: GetMutualFriends :many
+WITH friends AS (
+    SELECT f1.initiator_id AS user_id, f1.target_id AS friend_id
+    FROM follows f1
+    JOIN follows f2
+        ON f2.initiator_id = f1.target_id
+        AND f2.target_id = f1.initiator_id
+        AND f2.status = 'accepted'
+    WHERE f1.status = 'accepted'
+        AND f1.initiator_id IN (sqlc.arg('viewer_id'), sqlc.arg('target_id'))
+)
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url
+FROM friends vf
+JOIN friends tf ON tf.friend_id = vf.friend_id
+JOIN users u ON u.id = vf.friend_id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE vf.user_id = sqlc.arg('viewer_id')
+AND tf.user_id = sqlc.arg('target_id')
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+ORDER BY u.username;
+
+-- name: GetRelationship :one
+SELECT
+    (
+      SELECT f.status
+      FROM follows f
+      WHERE f.initiator_id = sqlc.arg('viewer_id')
+      AND f.target_id = sqlc.arg('target_id')
+    ) AS following_status,
+    (
+      SELECT f.status
+      FROM follows f
+      WHERE f.initiator_id = sqlc.arg('target_id')
+      AND f.target_id = sqlc.arg('viewer_id')
+    ) AS followed_by_status,
+    EXISTS (
+      SELECT 1 FROM blocks b
+      WHERE b.blocker_id = sqlc.arg('viewer_id')
+      AND b.blocked_id = sqlc.arg('target_id')
+    ) AS blocking;
+
 -- name: ResetFollowsTable :exec
 DELETE FROM follows;
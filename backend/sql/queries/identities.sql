@@ -0,0 +1,37 @@
+-- name: GetIdentity :one
+SELECT * FROM identities
+WHERE provider = $1 AND subject = $2;
+
+-- name: CreateIdentity :one
+INSERT INTO identities (id, user_id, provider, subject, email)
+VALUES (gen_random_uuid(), $1, $2, $3, $4)
+RETURNING *;
+
+-- name: TouchIdentity :exec
+UPDATE identities
+SET email = $2, last_login_at = NOW()
+WHERE id = $1;
+
+-- name: ListIdentities :many
+SELECT * FROM identities
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: CreateOIDCLogin :exec
+INSERT INTO oidc_logins (state, provider, nonce, code_verifier, expires_at, user_id)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: TakeOIDCLogin :one
+DELETE FROM oidc_logins
+WHERE state = $1 AND provider = $2
+RETURNING nonce, code_verifier, expires_at, user_id;
+
+-- name: DeleteExpiredOIDCLogins :exec
+DELETE FROM oidc_logins
+WHERE expires_at < $1;
+
+-- name: ResetIdentitiesTable :exec
+delete from identities;
+
+-- name: ResetOIDCLoginsTable :exec
+delete from oidc_logins;
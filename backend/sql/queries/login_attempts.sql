@@ -0,0 +1,52 @@
+-- name: RecordLoginAttempt :exec
+INSERT INTO login_attempts (id, user_id, username, succeeded, failure_reason, ip_address, user_agent, new_device)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetKnownLoginSource :one
+SELECT
+  EXISTS (
+    SELECT 1 FROM login_attempts
+    WHERE user_id = sqlc.arg('user_id') AND succeeded
+  ) AS has_history,
+  EXISTS (
+    SELECT 1 FROM login_attempts
+    WHERE user_id = sqlc.arg('user_id') AND succeeded AND ip_address = sqlc.arg('ip_address')
+  ) AS known_ip,
+  EXISTS (
+    SELECT 1 FROM login_attempts
+    WHERE user_id = sqlc.arg('user_id') AND succeeded AND user_agent = sqlc.arg('user_agent')
+  ) AS known_device;
+
+-- name: DeleteLoginAttemptsBefore :exec
+DELETE FROM login_attempts
+WHERE created_at < $1;
+
+-- name: GetLoginLockout :one
+SELECT failed_count, locked_until FROM login_lockouts
+WHERE user_id = $1;
+
+-- name: RecordFailedLogin :one
+INSERT INTO login_lockouts (user_id, failed_count, updated_at)
+VALUES (sqlc.arg('user_id'), 1, NOW())
+ON CONFLICT (user_id) DO UPDATE SET
+  failed_count = CASE
+    WHEN login_lockouts.updated_at < sqlc.arg('window_start') THEN 1
+    ELSE login_lockouts.failed_count + 1
+  END,
+  updated_at = NOW()
+RETURNING failed_count;
+
+-- name: LockLogin :exec
+UPDATE login_lockouts
+SET locked_until = $2
+WHERE user_id = $1;
+
+-- name: ClearLoginLockout :exec
+DELETE FROM login_lockouts
+WHERE user_id = $1;
+
+-- name: ResetLoginAttemptsTable :exec
+delete from login_attempts;
+
+-- name: ResetLoginLockoutsTable :exec
+delete from login_lockouts;
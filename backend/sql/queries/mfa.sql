@@ -0,0 +1,49 @@
+-- name: StartTOTPEnrollment :exec
+INSERT INTO totp_secrets (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET
+  secret = excluded.secret,
+  last_used_step = 0,
+  created_at = NOW()
+WHERE totp_secrets.confirmed_at IS NULL;
+
+-- name: GetTOTPSecret :one
+SELECT secret, confirmed_at, last_used_step FROM totp_secrets
+WHERE user_id = $1;
+
+-- name: ConfirmTOTP :execrows
+UPDATE totp_secrets
+SET confirmed_at = NOW(), last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE totp_secrets
+SET last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2;
+
+-- name: DeleteTOTPSecret :exec
+DELETE FROM totp_secrets
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (id, user_id, code_hash)
+VALUES (gen_random_uuid(), $1, $2);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: ResetTOTPSecretsTable :exec
+delete from totp_secrets;
+
+-- name: ResetRecoveryCodesTable :exec
+delete from recovery_codes;
@@ -0,0 +1,15 @@
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, moderator_id, report_id, action, target_user_id, target_post_id, note)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6);
+
+-- name: ListModerationActions :many
+SELECT
+    ma.*,
+    u.username AS moderator_username
+FROM moderation_actions ma
+LEFT JOIN users u ON ma.moderator_id = u.id
+ORDER BY ma.created_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: ResetModerationActionsTable :exec
+DELETE FROM moderation_actions;
@@ -0,0 +1,12 @@
+-- name: MuteUser :exec
+INSERT INTO mutes (muter_id, muted_id)
+VALUES ($1, $2)
+ON CONFLICT (muter_id, muted_id) DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1
+    AND muted_id = $2;
+
+-- name: ResetMutesTable :exec
+DELETE FROM mutes;
@@ -6,23 +6,94 @@ VALUES (gen_random_uuid(), $1, $2, $3, $4);
 SELECT 
     posts.*,
     u.username AS username,
+    u.display_name AS display_name,
     up.avatar_url AS avatar_url
 FROM posts
 JOIN users u ON posts.user_id = u.id
 LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE posts.is_deleted = false
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.arg('viewer_id') AND b.blocked_id = posts.user_id)
+       OR (b.blocker_id = posts.user_id AND b.blocked_id = sqlc.arg('viewer_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes m
+    WHERE m.muter_id = sqlc.arg('viewer_id') AND m.muted_id = posts.user_id
+)
 ORDER BY posts.created_at DESC;
 
 -- name: GetPostsByUsername :many
 SELECT 
     posts.*,
     u.username,
+    u.display_name,
     up.avatar_url as user_avatar_url
 FROM posts 
 JOIN users u ON posts.user_id = u.id
 LEFT JOIN user_preferences up ON up.user_id = u.id
-WHERE u.username = $1
+WHERE u.username = sqlc.arg('username')
+AND posts.is_deleted = false
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.arg('viewer_id') AND b.blocked_id = posts.user_id)
+       OR (b.blocker_id = posts.user_id AND b.blocked_id = sqlc.arg('viewer_id'))
+)
 ORDER BY posts.created_at DESC;
 
+-- name: GetPostById :one
+SELECT posts.* FROM posts
+JOIN users u ON posts.user_id = u.id
+WHERE posts.id = sqlc.arg('id')
+AND posts.is_deleted = false
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.arg('viewer_id') AND b.blocked_id = posts.user_id)
+       OR (b.blocker_id = posts.user_id AND b.blocked_id = sqlc.arg('viewer_id'))
+);
+
+-- name: SearchPosts :many
+SELECT
+    posts.*,
+    u.username,
+    u.display_name,
+    up.avatar_url,
+    ts_rank(to_tsvector('english', posts.body), websearch_to_tsquery('english', sqlc.arg('query'))) AS rank,
+    ts_headline('english', posts.body, websearch_to_tsquery('english', sqlc.arg('query')),
+        'StartSel=**, StopSel=**, MaxFragments=2') AS highlight
+FROM posts
+JOIN users u ON posts.user_id = u.id
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE to_tsvector('english', posts.body) @@ websearch_to_tsquery('english', sqlc.arg('query'))
+AND posts.is_deleted = false
+AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+AND (
+    posts.visibility = 'public'
+    OR posts.user_id = sqlc.arg('viewer_id')
+    OR (posts.visibility = 'friends' AND EXISTS (
+        SELECT 1 FROM follows f
+        WHERE f.initiator_id = sqlc.arg('viewer_id')
+            AND f.target_id = posts.user_id
+            AND f.status = 'accepted'
+    ))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.arg('viewer_id') AND b.blocked_id = posts.user_id)
+       OR (b.blocker_id = posts.user_id AND b.blocked_id = sqlc.arg('viewer_id'))
+)
+ORDER BY rank DESC, posts.created_at DESC
+LIMIT sqlc.arg('result_limit');
+
+-- name: HidePost :exec
+UPDATE posts
+SET is_deleted = true,
+    updated_at = NOW()
+WHERE id = $1;
+
 -- name: ResetPostsTable :exec
 DELETE FROM posts;
 
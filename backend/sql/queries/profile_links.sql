@@ -0,0 +1,19 @@
+-- name: CreateProfileLink :exec
+INSERT INTO profile_links (id, user_id, url, position, verified_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4);
+
+-- name: ListProfileLinks :many
+SELECT * FROM profile_links
+WHERE user_id = $1
+ORDER BY position;
+
+-- name: DeleteProfileLinks :exec
+DELETE FROM profile_links
+WHERE user_id = $1;
+
+-- name: ClearProfileLinkVerification :exec
+UPDATE profile_links SET verified_at = NULL
+WHERE user_id = $1;
+
+-- name: ResetProfileLinksTable :exec
+DELETE FROM profile_links;
@@ -0,0 +1,23 @@
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+VALUES (sqlc.arg('key'), sqlc.arg('burst')::float8 - 1, TRUE, NOW())
+ON CONFLICT (key) DO UPDATE SET (tokens, allowed, updated_at) = (
+  SELECT
+    CASE WHEN refilled >= 1 THEN refilled - 1 ELSE refilled END,
+    refilled >= 1,
+    NOW()
+  FROM (
+    SELECT LEAST(
+      sqlc.arg('burst')::float8,
+      rate_limit_buckets.tokens + EXTRACT(EPOCH FROM NOW() - rate_limit_buckets.updated_at)::float8 * sqlc.arg('rate_per_second')::float8
+    ) AS refilled
+  ) AS bucket
+)
+RETURNING tokens, allowed;
+
+-- name: DeleteIdleRateLimitBuckets :exec
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
+
+-- name: ResetRateLimitBucketsTable :exec
+delete from rate_limit_buckets;
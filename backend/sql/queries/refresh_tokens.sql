@@ -21,8 +21,15 @@ SET revoked_at = NOW(),
     updated_at = NOW()
 WHERE token = $1;
 
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL;
+
 -- name: GetUserByRefreshToken :one
-SELECT u.id, u.username, u.email, u.updated_at, u.created_at
+SELECT u.id, u.username, u.email, u.updated_at, u.created_at, u.role, u.suspended_at, u.suspended_until
 FROM users u
 JOIN refresh_tokens rt ON u.id = rt.user_id
 WHERE rt.token = $1
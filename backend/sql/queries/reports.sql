@@ -0,0 +1,47 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, reporter_id, reported_user_id, post_id, reason, details)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetReportById :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: ListReportsByStatus :many
+SELECT
+    r.*,
+    reporter.username AS reporter_username,
+    reported.username AS reported_username,
+    p.body AS post_body
+FROM reports r
+JOIN users reporter ON r.reporter_id = reporter.id
+JOIN users reported ON r.reported_user_id = reported.id
+LEFT JOIN posts p ON r.post_id = p.id
+WHERE r.status = $1
+ORDER BY r.created_at ASC
+LIMIT $2 OFFSET $3;
+
+-- name: ClaimReport :one
+UPDATE reports
+SET status = 'claimed',
+    claimed_by = $2,
+    claimed_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+    AND status = 'open'
+RETURNING *;
+
+-- name: ResolveReport :one
+UPDATE reports
+SET status = @status,
+    resolution = @resolution,
+    resolved_by = @resolved_by,
+    resolved_at = NOW(),
+    updated_at = NOW()
+WHERE id = @id
+    AND status IN ('open', 'claimed')
+    AND (claimed_by IS NULL OR claimed_by = @resolved_by)
+RETURNING *;
+
+-- name: ResetReportsTable :exec
+DELETE FROM reports;
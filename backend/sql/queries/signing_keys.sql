@@ -0,0 +1,15 @@
+-- name: CreateSigningKey :exec
+INSERT INTO signing_keys (kid, algorithm, private_key, not_before, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListSigningKeys :many
+SELECT * FROM signing_keys
+WHERE expires_at > $1
+ORDER BY not_before ASC, created_at ASC;
+
+-- name: DeleteExpiredSigningKeys :exec
+DELETE FROM signing_keys
+WHERE expires_at <= $1;
+
+-- name: ResetSigningKeysTable :exec
+DELETE FROM signing_keys;
@@ -0,0 +1,15 @@
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2,
+    updated_at = NOW()
+WHERE username = $1
+RETURNING id, username, role;
+
+-- name: ListUsersByRole :many
+SELECT id, username, role, created_at
+FROM users
+WHERE role = $1
+ORDER BY username;
+
+-- name: GetUserRole :one
+SELECT role FROM users WHERE id = $1;
@@ -0,0 +1,25 @@
+-- name: SuspendUser :exec
+UPDATE users
+SET suspended_at = NOW(),
+    suspended_until = $2,
+    suspension_reason = $3,
+    appeal_note = '',
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UnsuspendUser :exec
+UPDATE users
+SET suspended_at = NULL,
+    suspended_until = NULL,
+    suspension_reason = '',
+    appeal_note = '',
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetAppealNote :execrows
+UPDATE users
+SET appeal_note = $2,
+    updated_at = NOW()
+WHERE id = $1
+    AND suspended_at IS NOT NULL
+    AND (suspended_until IS NULL OR suspended_until > NOW());
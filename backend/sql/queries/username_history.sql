@@ -0,0 +1,40 @@
+-- name: RecordUsernameChange :exec
+INSERT INTO username_history (id, user_id, old_username, released_at)
+VALUES (gen_random_uuid(), $1, $2, $3);
+
+-- name: GetLastUsernameChange :one
+SELECT changed_at FROM username_history
+WHERE user_id = $1
+ORDER BY changed_at DESC
+LIMIT 1;
+
+-- name: GetUsernameRedirect :one
+SELECT u.username
+FROM username_history h
+JOIN users u ON u.id = h.user_id
+WHERE h.old_username = $1
+  AND h.released_at > NOW()
+ORDER BY h.changed_at DESC
+LIMIT 1;
+
+-- name: IsUsernameAvailable :one
+SELECT (
+  NOT EXISTS (
+    SELECT 1 FROM users u
+    WHERE lower(u.username) = lower(sqlc.arg('username'))
+      AND u.id <> sqlc.arg('user_id')
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM username_history h
+    WHERE lower(h.old_username) = lower(sqlc.arg('username'))
+      AND h.released_at > NOW()
+      AND h.user_id <> sqlc.arg('user_id')
+  )
+)::boolean AS available;
+
+-- name: ReleaseUsername :exec
+DELETE FROM username_history
+WHERE user_id = sqlc.arg('user_id') AND lower(old_username) = lower(sqlc.arg('old_username'));
+
+-- name: ResetUsernameHistoryTable :exec
+delete from username_history;
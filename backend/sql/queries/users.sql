@@ -11,6 +11,13 @@ SELECT
     u.hashed_password,
     u.created_at as user_created_at,
     u.updated_at as user_updated_at,
+    u.role,
+    u.suspended_at,
+    u.suspended_until,
+    u.suspension_reason,
+    u.display_name,
+    u.bio,
+    u.location,
     up.id as preferences_id,
     up.avatar_url,
     up.cover_url,
@@ -34,7 +41,12 @@ FROM
     users u
 LEFT JOIN
     user_preferences up ON up.user_id = u.id
-WHERE u.id = $1;
+WHERE u.id = sqlc.arg('id')
+  AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = sqlc.narg('viewer_id'))
+  );
 
 -- name: GetUserByUsername :one
 SELECT 
@@ -44,6 +56,13 @@ SELECT
     u.hashed_password,
     u.created_at as user_created_at,
     u.updated_at as user_updated_at,
+    u.role,
+    u.suspended_at,
+    u.suspended_until,
+    u.suspension_reason,
+    u.display_name,
+    u.bio,
+    u.location,
     up.id as preferences_id,
     up.avatar_url,
     up.cover_url,
@@ -67,7 +86,29 @@ FROM
     users u
 LEFT JOIN
     user_preferences up ON up.user_id = u.id
-WHERE u.username = $1;
+WHERE u.username = sqlc.arg('username')
+  AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.narg('viewer_id') AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = sqlc.narg('viewer_id'))
+  );
+
+-- name: SearchUsers :many
+SELECT
+    u.id,
+    u.username,
+    up.avatar_url
+FROM users u
+LEFT JOIN user_preferences up ON up.user_id = u.id
+WHERE lower(u.username) LIKE lower(sqlc.arg('prefix')) || '%'
+  AND (u.suspended_at IS NULL OR u.suspended_until <= NOW())
+  AND NOT EXISTS (
+    SELECT 1 FROM blocks b
+    WHERE (b.blocker_id = sqlc.arg('viewer_id') AND b.blocked_id = u.id)
+       OR (b.blocker_id = u.id AND b.blocked_id = sqlc.arg('viewer_id'))
+  )
+ORDER BY length(u.username), u.username
+LIMIT sqlc.arg('result_limit');
 
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
@@ -78,3 +119,19 @@ DELETE FROM users WHERE id = $1;
 -- name: ResetUsersTable :exec
 delete from users;
 
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET display_name = $2,
+    bio = $3,
+    location = $4,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING display_name, bio, location, updated_at;
+
+-- name: UpdateUsername :one
+UPDATE users
+SET username = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, username, updated_at;
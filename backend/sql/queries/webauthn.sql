@@ -0,0 +1,37 @@
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (id, user_id, credential_id, public_key, attestation_type, aaguid, sign_count, transports, backup_eligible, backup_state, name)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: ListWebAuthnCredentials :many
+SELECT * FROM webauthn_credentials
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: UpdateWebAuthnCredentialUse :exec
+UPDATE webauthn_credentials
+SET sign_count = $2, backup_state = $3, last_used_at = NOW()
+WHERE credential_id = $1;
+
+-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateWebAuthnSession :exec
+INSERT INTO webauthn_sessions (id, user_id, ceremony, data, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: TakeWebAuthnSession :one
+DELETE FROM webauthn_sessions
+WHERE id = $1 AND ceremony = $2
+RETURNING user_id, data, expires_at;
+
+-- name: DeleteExpiredWebAuthnSessions :exec
+DELETE FROM webauthn_sessions
+WHERE expires_at < $1;
+
+-- name: ResetWebAuthnCredentialsTable :exec
+delete from webauthn_credentials;
+
+-- name: ResetWebAuthnSessionsTable :exec
+delete from webauthn_sessions;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE blocks (
+  blocker_id UUID NOT NULL,
+  blocked_id UUID NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CHECK (blocker_id != blocked_id),
+
+  PRIMARY KEY (blocker_id, blocked_id),
+  FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_blocks_blocked_id ON blocks(blocked_id);
+
+-- +goose Down
+DROP TABLE blocks;
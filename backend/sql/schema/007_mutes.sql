@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE mutes (
+  muter_id UUID NOT NULL,
+  muted_id UUID NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  CHECK (muter_id != muted_id),
+
+  PRIMARY KEY (muter_id, muted_id),
+  FOREIGN KEY (muter_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (muted_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE mutes;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE reports (
+  id UUID PRIMARY KEY,
+  reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reported_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'nudity', 'other')),
+  details TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'claimed', 'resolved', 'dismissed')),
+  claimed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  claimed_at TIMESTAMPTZ,
+  resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  resolved_at TIMESTAMPTZ,
+  resolution TEXT CHECK (resolution IN ('hide_post', 'suspend_user', 'dismiss')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_reports_status_created_at ON reports(status, created_at);
+CREATE INDEX idx_reports_reported_user_id ON reports(reported_user_id);
+
+-- +goose Down
+DROP TABLE reports;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Audit log of every moderator action. Targets are stored without foreign
+-- keys so the history survives the deletion of the post or account.
+CREATE TABLE moderation_actions (
+  id UUID PRIMARY KEY,
+  moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  report_id UUID REFERENCES reports(id) ON DELETE SET NULL,
+  action TEXT NOT NULL CHECK (action IN ('claim', 'hide_post', 'suspend_user', 'dismiss')),
+  target_user_id UUID,
+  target_post_id UUID,
+  note TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_moderation_actions_created_at ON moderation_actions(created_at DESC);
+
+-- +goose Down
+DROP TABLE moderation_actions;
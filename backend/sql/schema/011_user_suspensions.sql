@@ -0,0 +1,25 @@
+-- +goose Up
+-- A user is suspended while suspended_at is set and suspended_until is either
+-- NULL (permanent) or still in the future.
+ALTER TABLE users
+  ADD COLUMN suspended_at TIMESTAMPTZ,
+  ADD COLUMN suspended_until TIMESTAMPTZ,
+  ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '',
+  ADD COLUMN appeal_note TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE moderation_actions
+  DROP CONSTRAINT moderation_actions_action_check,
+  ADD CONSTRAINT moderation_actions_action_check
+    CHECK (action IN ('claim', 'hide_post', 'suspend_user', 'unsuspend_user', 'dismiss'));
+
+-- +goose Down
+ALTER TABLE moderation_actions
+  DROP CONSTRAINT moderation_actions_action_check,
+  ADD CONSTRAINT moderation_actions_action_check
+    CHECK (action IN ('claim', 'hide_post', 'suspend_user', 'dismiss'));
+
+ALTER TABLE users
+  DROP COLUMN appeal_note,
+  DROP COLUMN suspension_reason,
+  DROP COLUMN suspended_until,
+  DROP COLUMN suspended_at;
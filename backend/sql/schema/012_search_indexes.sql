@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE INDEX idx_posts_body_search ON posts USING GIN (to_tsvector('english', body));
+CREATE INDEX idx_users_username_prefix ON users (lower(username) text_pattern_ops);
+
+-- +goose Down
+DROP INDEX idx_users_username_prefix;
+DROP INDEX idx_posts_body_search;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE username_history (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  old_username TEXT NOT NULL,
+  changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  released_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_username_history_old_username ON username_history(old_username, released_at);
+CREATE INDEX idx_username_history_user_id ON username_history(user_id, changed_at);
+
+-- +goose Down
+DROP TABLE username_history;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE users
+  ADD COLUMN display_name TEXT NOT NULL DEFAULT '' CHECK (char_length(display_name) <= 50),
+  ADD COLUMN bio TEXT NOT NULL DEFAULT '' CHECK (char_length(bio) <= 160),
+  ADD COLUMN location TEXT NOT NULL DEFAULT '' CHECK (char_length(location) <= 30);
+
+CREATE TABLE profile_links (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  verified_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, url)
+);
+
+CREATE INDEX idx_profile_links_user_id ON profile_links(user_id, position);
+
+-- +goose Down
+DROP TABLE profile_links;
+
+ALTER TABLE users
+  DROP COLUMN location,
+  DROP COLUMN bio,
+  DROP COLUMN display_name;
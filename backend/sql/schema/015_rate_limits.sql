@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE rate_limit_buckets (
+  key TEXT PRIMARY KEY,
+  tokens DOUBLE PRECISION NOT NULL,
+  allowed BOOLEAN NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
+
+-- +goose Down
+DROP TABLE rate_limit_buckets;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE totp_secrets (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret TEXT NOT NULL,
+  confirmed_at TIMESTAMPTZ,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE recovery_codes (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash TEXT NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_recovery_codes_user_id_code_hash ON recovery_codes(user_id, code_hash);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+DROP TABLE totp_secrets;
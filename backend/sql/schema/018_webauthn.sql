@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE webauthn_credentials (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  credential_id BYTEA NOT NULL UNIQUE,
+  public_key BYTEA NOT NULL,
+  attestation_type TEXT NOT NULL DEFAULT '',
+  aaguid BYTEA NOT NULL,
+  sign_count BIGINT NOT NULL DEFAULT 0,
+  transports TEXT NOT NULL DEFAULT '',
+  backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+  backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+  name TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+CREATE TABLE webauthn_sessions (
+  id UUID PRIMARY KEY,
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  ceremony TEXT NOT NULL,
+  data TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_webauthn_sessions_expires_at ON webauthn_sessions(expires_at);
+
+-- +goose Down
+DROP TABLE webauthn_sessions;
+DROP TABLE webauthn_credentials;
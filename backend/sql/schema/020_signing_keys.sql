@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE signing_keys (
+  kid TEXT PRIMARY KEY,
+  algorithm TEXT NOT NULL,
+  private_key BYTEA NOT NULL,
+  not_before TIMESTAMPTZ NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_signing_keys_expires_at ON signing_keys(expires_at);
+
+-- +goose Down
+DROP TABLE signing_keys;
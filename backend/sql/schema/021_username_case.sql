@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE UNIQUE INDEX idx_users_username_lower ON users(lower(username));
+
+DROP INDEX idx_username_history_old_username;
+CREATE INDEX idx_username_history_old_username ON username_history(lower(old_username), released_at);
+
+-- +goose Down
+DROP INDEX idx_username_history_old_username;
+CREATE INDEX idx_username_history_old_username ON username_history(old_username, released_at);
+
+DROP INDEX idx_users_username_lower;
@@ -0,0 +1,37 @@
+// Package schema embeds the goose migrations so the server can tell which
+// schema version it was built against.
+package schema
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// LatestVersion returns the highest migration number, taken from the NNN_
+// prefix of the file names.
+func LatestVersion() (int64, error) {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE user_preferences (
+    id TEXT PRIMARY KEY,
+    avatar_url TEXT NOT NULL DEFAULT 'https://68rdbf2n6t.ufs.sh/f/eFaWLjkdXdtlUmFpmNXcWR3rVUzBTD2ukjxYylC9Gm7iqA4o',
+    cover_url TEXT NOT NULL DEFAULT '#',
+    dark_mode BOOLEAN NOT NULL DEFAULT true,
+    private_mode BOOLEAN NOT NULL DEFAULT false,
+    user_id TEXT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_user_preferences_user_id ON user_preferences(user_id);
+
+-- +goose Down
+DROP TABLE user_preferences;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE posts (
+    id TEXT PRIMARY KEY,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'friends', 'private'))
+);
+
+CREATE INDEX idx_posts_user_id ON posts(user_id);
+CREATE INDEX idx_posts_created_at ON posts(created_at DESC);
+
+-- +goose Down
+DROP TABLE posts;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE blocks (
+  blocker_id TEXT NOT NULL,
+  blocked_id TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+
+  CHECK (blocker_id != blocked_id),
+
+  PRIMARY KEY (blocker_id, blocked_id),
+  FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_blocks_blocked_id ON blocks(blocked_id);
+
+-- +goose Down
+DROP TABLE blocks;
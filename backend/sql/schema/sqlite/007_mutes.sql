@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE mutes (
+  muter_id TEXT NOT NULL,
+  muted_id TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+
+  CHECK (muter_id != muted_id),
+
+  PRIMARY KEY (muter_id, muted_id),
+  FOREIGN KEY (muter_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (muted_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE mutes;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE reports (
+  id TEXT PRIMARY KEY,
+  reporter_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reported_user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  post_id TEXT REFERENCES posts(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'nudity', 'other')),
+  details TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'claimed', 'resolved', 'dismissed')),
+  claimed_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+  claimed_at TIMESTAMP,
+  resolved_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+  resolved_at TIMESTAMP,
+  resolution TEXT CHECK (resolution IN ('hide_post', 'suspend_user', 'dismiss')),
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_reports_status_created_at ON reports(status, created_at);
+CREATE INDEX idx_reports_reported_user_id ON reports(reported_user_id);
+
+-- +goose Down
+DROP TABLE reports;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Audit log of every moderator action. Targets are stored without foreign
+-- keys so the history survives the deletion of the post or account.
+-- SQLite can't alter a CHECK constraint, so the list already includes the
+-- unsuspend_user action that 011 adds on Postgres.
+CREATE TABLE moderation_actions (
+  id TEXT PRIMARY KEY,
+  moderator_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+  report_id TEXT REFERENCES reports(id) ON DELETE SET NULL,
+  action TEXT NOT NULL CHECK (action IN ('claim', 'hide_post', 'suspend_user', 'unsuspend_user', 'dismiss')),
+  target_user_id TEXT,
+  target_post_id TEXT,
+  note TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_moderation_actions_created_at ON moderation_actions(created_at DESC);
+
+-- +goose Down
+DROP TABLE moderation_actions;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- A user is suspended while suspended_at is set and suspended_until is either
+-- NULL (permanent) or still in the future.
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP;
+ALTER TABLE users ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN appeal_note TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN appeal_note;
+ALTER TABLE users DROP COLUMN suspension_reason;
+ALTER TABLE users DROP COLUMN suspended_until;
+ALTER TABLE users DROP COLUMN suspended_at;
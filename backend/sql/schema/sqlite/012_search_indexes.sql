@@ -0,0 +1,7 @@
+-- +goose Up
+-- SQLite has no full-text index here; post search falls back to a substring
+-- match, so only the username prefix index is created.
+CREATE INDEX idx_users_username_prefix ON users (lower(username));
+
+-- +goose Down
+DROP INDEX idx_users_username_prefix;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE username_history (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  old_username TEXT NOT NULL,
+  changed_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  released_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_username_history_old_username ON username_history(old_username, released_at);
+CREATE INDEX idx_username_history_user_id ON username_history(user_id, changed_at);
+
+-- +goose Down
+DROP TABLE username_history;
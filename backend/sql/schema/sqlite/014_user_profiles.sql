@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '' CHECK (length(display_name) <= 50);
+ALTER TABLE users ADD COLUMN bio TEXT NOT NULL DEFAULT '' CHECK (length(bio) <= 160);
+ALTER TABLE users ADD COLUMN location TEXT NOT NULL DEFAULT '' CHECK (length(location) <= 30);
+
+CREATE TABLE profile_links (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  verified_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  UNIQUE (user_id, url)
+);
+
+CREATE INDEX idx_profile_links_user_id ON profile_links(user_id, position);
+
+-- +goose Down
+DROP TABLE profile_links;
+
+ALTER TABLE users DROP COLUMN location;
+ALTER TABLE users DROP COLUMN bio;
+ALTER TABLE users DROP COLUMN display_name;
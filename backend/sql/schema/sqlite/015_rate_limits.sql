@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE rate_limit_buckets (
+  key TEXT PRIMARY KEY,
+  tokens REAL NOT NULL,
+  allowed BOOLEAN NOT NULL,
+  updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
+
+-- +goose Down
+DROP TABLE rate_limit_buckets;
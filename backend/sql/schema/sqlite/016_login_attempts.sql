@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE login_attempts (
+  id TEXT PRIMARY KEY,
+  user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+  username TEXT NOT NULL,
+  succeeded BOOLEAN NOT NULL,
+  failure_reason TEXT NOT NULL DEFAULT '',
+  ip_address TEXT NOT NULL,
+  user_agent TEXT NOT NULL,
+  new_device BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_login_attempts_user_id ON login_attempts(user_id, created_at);
+CREATE INDEX idx_login_attempts_created_at ON login_attempts(created_at);
+
+CREATE TABLE login_lockouts (
+  user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  failed_count INTEGER NOT NULL,
+  locked_until TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+-- +goose Down
+DROP TABLE login_lockouts;
+DROP TABLE login_attempts;
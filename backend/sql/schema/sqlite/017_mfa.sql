@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE totp_secrets (
+  user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret TEXT NOT NULL,
+  confirmed_at TIMESTAMP,
+  last_used_step INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE TABLE recovery_codes (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash TEXT NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE UNIQUE INDEX idx_recovery_codes_user_id_code_hash ON recovery_codes(user_id, code_hash);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+DROP TABLE totp_secrets;
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE webauthn_credentials (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  credential_id BLOB NOT NULL UNIQUE,
+  public_key BLOB NOT NULL,
+  attestation_type TEXT NOT NULL DEFAULT '',
+  aaguid BLOB NOT NULL,
+  sign_count INTEGER NOT NULL DEFAULT 0,
+  transports TEXT NOT NULL DEFAULT '',
+  backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+  backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+  name TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  last_used_at TIMESTAMP
+);
+
+CREATE INDEX idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+CREATE TABLE webauthn_sessions (
+  id TEXT PRIMARY KEY,
+  user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+  ceremony TEXT NOT NULL,
+  data TEXT NOT NULL,
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_webauthn_sessions_expires_at ON webauthn_sessions(expires_at);
+
+-- +goose Down
+DROP TABLE webauthn_sessions;
+DROP TABLE webauthn_credentials;
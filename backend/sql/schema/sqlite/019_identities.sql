@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE identities (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  email TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  last_login_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+  UNIQUE (provider, subject)
+);
+
+CREATE INDEX idx_identities_user_id ON identities(user_id);
+
+CREATE TABLE oidc_logins (
+  state TEXT PRIMARY KEY,
+  provider TEXT NOT NULL,
+  nonce TEXT NOT NULL,
+  code_verifier TEXT NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  -- Set when a signed-in user is linking an identity rather than logging in.
+  user_id TEXT REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_oidc_logins_expires_at ON oidc_logins(expires_at);
+
+-- +goose Down
+DROP TABLE oidc_logins;
+DROP TABLE identities;
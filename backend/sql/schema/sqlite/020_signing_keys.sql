@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE signing_keys (
+  kid TEXT PRIMARY KEY,
+  algorithm TEXT NOT NULL,
+  private_key BLOB NOT NULL,
+  not_before TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_signing_keys_expires_at ON signing_keys(expires_at);
+
+-- +goose Down
+DROP TABLE signing_keys;
@@ -0,0 +1,9 @@
+// Package sqlite embeds the SQLite versions of the goose migrations. Every
+// migration in sql/schema has a twin here with the same version number, so
+// both backends report the same schema version.
+package sqlite
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS